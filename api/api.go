@@ -30,6 +30,18 @@ import (
 const (
 	// The API version.
 	Version = "0.1"
+
+	// The content type of streamed responses.
+	// Successful stream requests are answered with newline separated JSON values.
+	StreamContentType = "application/x-ndjson"
+
+	// The content type of exported backup archives.
+	// Imported archives are sent directly after the JSON request body.
+	ArchiveContentType = "application/x-turtle-backup"
+
+	// The content type of sent backup replicas.
+	// The replica is sent as btrfs send stream.
+	ReplicaContentType = "application/x-btrfs-stream"
 )
 
 //####################//
@@ -43,6 +55,14 @@ const (
 	StatusError   Status = 1 << iota
 )
 
+type ErrorCode int
+
+const (
+	ErrorCodeGeneric          ErrorCode = iota // Default error code for all failed requests.
+	ErrorCodeAuthFailed                        // The request credential is missing or invalid.
+	ErrorCodePermissionDenied                  // The user is not allowed to perform the request.
+)
+
 // Request is the request body of each turtle request.
 // The specific request is stored in the data value.
 type Request struct {
@@ -52,6 +72,10 @@ type Request struct {
 	// The requested action.
 	Type Type
 
+	// The API token to authenticate the request.
+	// Optional if a client certificate is used.
+	Token string
+
 	// The specific request type.
 	Data interface{}
 }
@@ -71,9 +95,30 @@ type Response struct {
 
 // ResponseError is always set to the response data, if the status value is set to error.
 type ResponseError struct {
+	ErrorCode    ErrorCode
 	ErrorMessage string
 }
 
+// Error is an error with an API error code.
+// It implements the error interface.
+type Error struct {
+	Code    ErrorCode
+	Message string
+}
+
+// NewError creates a new API error with the given code.
+func NewError(code ErrorCode, format string, a ...interface{}) *Error {
+	return &Error{
+		Code:    code,
+		Message: fmt.Sprintf(format, a...),
+	}
+}
+
+// Error returns the error message.
+func (e *Error) Error() string {
+	return e.Message
+}
+
 //##############//
 //### Public ###//
 //##############//
@@ -113,6 +158,21 @@ func NewRequestFromJSON(jsonData interface{}) (*Request, error) {
 	return &r, nil
 }
 
+// NewRequestFromStream constructs a new request from the JSON value at the
+// beginning of the reader. A reader for the remaining data is returned.
+func NewRequestFromStream(r io.Reader) (*Request, io.Reader, error) {
+	// Decode the JSON to the request value.
+	decoder := json.NewDecoder(r)
+
+	var req Request
+	err := decoder.Decode(&req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode JSON: %v", err)
+	}
+
+	return &req, io.MultiReader(decoder.Buffered(), r), nil
+}
+
 // MapTo maps the data values to a struct.
 // val must be a pointer to a struct.
 func (r *Request) MapTo(val interface{}) error {
@@ -37,12 +37,42 @@ const (
 	TypeSetupSet            Type = "setup-set"
 	TypeErrorMsg            Type = "error-msg"
 	TypeLogs                Type = "logs"
+	TypeStreamLogs          Type = "stream-logs"
 	TypeUpdate              Type = "update"
 	TypeBackup              Type = "backup"
 	TypeRemoveBackup        Type = "remove-backup"
 	TypeRestoreBackup       Type = "restore-backup"
 	TypeAddHostFingerprint  Type = "add-host-fingerprint"
 	TypeHostFingerprintInfo Type = "host-fingerprint-info"
+	TypeConfig              Type = "config"
+	TypePermissions         Type = "permissions"
+	TypeMetrics             Type = "metrics"
+	TypeListRevisions       Type = "list-revisions"
+	TypeRollbackSource      Type = "rollback-source"
+	TypeUpdatePreview       Type = "update-preview"
+	TypeSetAutoUpdate       Type = "set-auto-update"
+	TypeAddRegistry         Type = "add-registry"
+	TypeListRegistries      Type = "list-registries"
+	TypeRemoveRegistry      Type = "remove-registry"
+	TypeExportBackup        Type = "export-backup"
+	TypeImportBackup        Type = "import-backup"
+	TypeReplicate           Type = "replicate"
+	TypeListReplicas        Type = "list-replicas"
+	TypeRestoreReplica      Type = "restore-replica"
+	TypeSetRetention        Type = "set-retention"
+	TypePinBackup           Type = "pin-backup"
+	TypePruneBackups        Type = "prune-backups"
+	TypeSetBackupSchedule   Type = "set-backup-schedule"
+	TypeBackupHookLog       Type = "backup-hook-log"
+
+	// Requests sent by a replicating daemon to its remote replication target.
+	// The replicas are stored separately for each authenticated user.
+	TypeReplicaList    Type = "replica-list"
+	TypeReplicaReceive Type = "replica-receive"
+	TypeReplicaSend    Type = "replica-send"
+
+	// TypePrometheus is the permission to scrape the /metrics endpoint.
+	TypePrometheus Type = "prometheus"
 )
 
 //####################//
@@ -95,10 +125,61 @@ type RequestLogs struct {
 	Stream    string // Optional: stderr or stdout. Otherwise both.
 }
 
-type RequestUpdate struct {
+type RequestStreamLogs struct {
+	Name       string   // App name
+	Containers []string // Optional: Container names. Otherwise all app containers.
+	Stream     string   // Optional: stderr or stdout. Otherwise both.
+	Follow     bool     // Keep the stream open and send new log lines.
+	Tail       int      // Optional: Only send the last x lines of each container.
+	Since      int64    // Optional: Unix timestamp. Only send lines since this time.
+	Until      int64    // Optional: Unix timestamp. Only send lines until this time.
+	Timestamps bool     // Set the timestamp of each log line.
+	History    bool     // Read the captured log files instead of the container logs. Follow is ignored.
+}
+
+type RequestMetrics struct {
 	Name string // App name
 }
 
+type RequestUpdate struct {
+	Name     string // App name
+	Safe     bool   // Verify the update with a temporary clone and roll back on failure.
+	Revision string // Optional git tag or commit. Pins the app to this revision.
+	Unpin    bool   // Follow the app branch again.
+}
+
+type RequestListRevisions struct {
+	Name  string // App name
+	Limit int    // Maximum number of commits and tags. A default is used if zero.
+}
+
+type RequestRollbackSource struct {
+	Name     string // App name
+	Revision string // The git tag or commit. The previously deployed commit is used if empty.
+	Safe     bool   // Verify the rollback with a temporary clone.
+}
+
+type RequestUpdatePreview struct {
+	Name     string // App name
+	Revision string // Optional git tag or commit. The pinned revision or the branch is used if empty.
+}
+
+type RequestSetAutoUpdate struct {
+	Name      string // App name
+	Mode      string // disabled, poll or webhook.
+	Interval  string // Optional poll interval duration. The daemon default is used if empty.
+	Safe      bool   // Verify the updates with a temporary clone.
+	NewSecret bool   // Replace the webhook secret.
+}
+
+type RequestSetBackupSchedule struct {
+	Name     string // App name
+	Mode     string // default, interval, cron or disabled.
+	Interval string // The backup interval duration of the interval mode.
+	Cron     string // The cron expression of the cron mode, e.g. 0 3 * * *
+	Stopped  bool   // Also backup the app if it is stopped.
+}
+
 type RequestBackup struct {
 	Name string // App name
 }
@@ -117,6 +198,69 @@ type RequestRestoreBackup struct {
 	Unix string // Backup unix timestamp
 }
 
+type RequestExportBackup struct {
+	Name     string // App name
+	Unix     string // Backup unix timestamp
+	Password string // The archive encryption password.
+}
+
+// RequestImportBackup is followed by the archive data in the request body.
+type RequestImportBackup struct {
+	Name     string // App name
+	Password string // The archive encryption password.
+	NewApp   bool   // Create a new app instead of a backup of an existing app.
+}
+
+type RequestSetRetention struct {
+	Name      string // App name
+	Retention string // The retention policy, e.g. all=24h,daily=7. Empty to use the daemon default.
+}
+
+type RequestPinBackup struct {
+	Name  string // App name
+	Unix  string // Backup unix timestamp
+	Unpin bool   // Remove the pin instead.
+}
+
+type RequestBackupHookLog struct {
+	Name string // App name
+	Unix string // Backup unix timestamp
+}
+
+type RequestPruneBackups struct {
+	Name   string // App name
+	DryRun bool   // Only list the backups which would be removed.
+}
+
+type RequestReplicate struct {
+	Name string // App name
+}
+
+type RequestListReplicas struct {
+	Name string // App name
+}
+
+type RequestRestoreReplica struct {
+	Name string // App name
+	Unix string // Backup unix timestamp
+}
+
+type RequestReplicaList struct {
+	Name string // App name
+}
+
+// RequestReplicaReceive is followed by the btrfs send stream in the request body.
+type RequestReplicaReceive struct {
+	Name   string // App name
+	Unix   string // Backup unix timestamp
+	Parent string // The parent backup of an incremental stream. Empty for a full stream.
+}
+
+type RequestReplicaSend struct {
+	Name string // App name
+	Unix string // Backup unix timestamp
+}
+
 type RequestAddHostFingerprint struct {
 	Fingerprint string
 }
@@ -124,3 +268,14 @@ type RequestAddHostFingerprint struct {
 type RequestHostFingerprintInfo struct {
 	Host string
 }
+
+type RequestAddRegistry struct {
+	Host     string // The registry host, e.g. registry.example.com:5000 or docker.io.
+	Username string
+	Password string
+	Email    string // Optional
+}
+
+type RequestRemoveRegistry struct {
+	Host string
+}
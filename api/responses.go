@@ -29,8 +29,32 @@ type ResponseInfo struct {
 	State      string
 	SourceURL  string
 	Branch     string
+	Pin        string // The pinned git tag or commit. Empty if the app follows its branch.
+	Commit     string // The deployed source commit.
 
-	Setup *Setup
+	Setup   *Setup
+	Metrics *ResponseMetricsSample // The latest resource usage sample. Nil if not sampled yet.
+
+	RestartPolicy   RestartPolicy // The effective restart policy.
+	RestartAttempts int           // Automatic restarts within the restart policy window.
+	NextRestart     int64         // Unix timestamp of the next pending restart. 0 if none.
+
+	UpdateState string // The state of the current or last safe update. Empty if none.
+	UpdateError string // The error of the last safe update.
+
+	AutoUpdate      string // The automatic update mode: disabled, poll or webhook.
+	AutoUpdateState string // The state of the current or last automatic update check. Empty if none.
+	AutoUpdateError string // The error of the last automatic update.
+
+	BackupSchedule string // The automatic backup schedule.
+	NextBackup     int64  // Unix timestamp of the next scheduled backup. 0 if none.
+
+	Retention        string // The effective backup retention policy.
+	DefaultRetention bool   // The app uses the daemon default retention policy.
+
+	ReplicatedBackup string // The last replicated backup. Empty if none.
+	LastReplication  int64  // Unix timestamp of the last replication. 0 if never replicated.
+	ReplicationError string // The error of the last replication.
 }
 
 type ResponseList struct {
@@ -48,8 +72,112 @@ type ResponseListBackups struct {
 }
 
 type ResponseListBackup struct {
-	Date string
-	Unix string
+	Date   string
+	Unix   string
+	Pinned bool // Pinned backups are never removed by the retention policy.
+}
+
+type ResponseBackupHookLog struct {
+	Log string // The recorded backup hooks output. Empty if no hooks were executed.
+}
+
+type ResponseSetBackupSchedule struct {
+	Schedule   string // The automatic backup schedule.
+	NextBackup int64  // Unix timestamp of the next scheduled backup. 0 if none.
+}
+
+type ResponseSetRetention struct {
+	Retention string // The effective retention policy.
+}
+
+type ResponsePruneBackups struct {
+	Retention string // The effective retention policy.
+	DryRun    bool
+	Removed   []ResponseListBackup // The removed backups or the backups which would be removed.
+}
+
+type ResponseImportBackup struct {
+	Name       string // App name
+	Unix       string // The timestamp of the imported backup. Empty if a new app was created.
+	SourceApp  string // The name of the exported app.
+	SourceUnix string // The timestamp of the exported backup.
+}
+
+type ResponseReplicate struct {
+	Replicated []string // The timestamps of the replicated backups.
+}
+
+type ResponseListReplicas struct {
+	Target   string // The replication target.
+	Parent   string // The last replicated backup used as parent for incremental streams.
+	Replicas []ResponseListBackup
+}
+
+type ResponseRestoreReplica struct {
+	Name   string // App name
+	Unix   string // The timestamp of the restored backup.
+	NewApp bool   // The app was created from the replica.
+}
+
+type ResponseReplicaList struct {
+	Replicas []string // The replicated backup timestamps.
+}
+
+type ResponseListRevisions struct {
+	Branch  string
+	Pin     string
+	Commit  string // The deployed source commit.
+	Commits []ResponseRevision
+	Tags    []ResponseTag
+}
+
+type ResponseRevision struct {
+	Commit  string
+	Time    int64 // Unix timestamp of the commit.
+	Author  string
+	Subject string
+}
+
+type ResponseTag struct {
+	Name   string
+	Commit string
+}
+
+type ResponseSetAutoUpdate struct {
+	Mode        string
+	Interval    string // The effective poll interval.
+	Safe        bool
+	WebhookPath string // The URL path of the webhook. Empty if disabled.
+	Secret      string // The webhook HMAC secret. Empty if disabled.
+}
+
+type ResponseUpdatePreview struct {
+	Commit   string             // The deployed source commit.
+	Target   string             // The commit the update would check out.
+	Commits  []ResponseRevision // Commits which would be applied.
+	Reverted []ResponseRevision // Deployed commits which are not part of the target.
+
+	// Changes of the Turtlefile. Nil if the new Turtlefile is invalid.
+	Turtlefile *ResponseTurtlefileChanges
+
+	Warnings []string
+}
+
+type ResponseTurtlefileChanges struct {
+	AddedContainers   []string
+	RemovedContainers []string
+	ImageChanges      []ResponseImageChange
+	AddedEnv          []string
+	RemovedEnv        []string
+	RequiredEnv       []string // Previously optional environment variables which became required.
+	AddedPorts        []string // In the form of CONTAINER:PORT/PROTOCOL.
+	RemovedPorts      []string
+}
+
+type ResponseImageChange struct {
+	Container string
+	OldImage  string
+	NewImage  string
 }
 
 type ResponseErrorMsg struct {
@@ -57,6 +185,16 @@ type ResponseErrorMsg struct {
 	ErrorMessage string
 }
 
+type ResponseListRegistries struct {
+	Registries []ResponseRegistry
+}
+
+type ResponseRegistry struct {
+	Host     string
+	Username string
+	Email    string
+}
+
 type ResponseHostFingerprintInfo struct {
 	Host        string
 	Trusted     bool
@@ -67,3 +205,64 @@ type ResponseLogs struct {
 	Containers  []string // Empty if a specific continer is passed. Otherwise a list of available containers is set.
 	LogMessages string
 }
+
+type ResponseMetrics struct {
+	Name       string
+	Interval   string                     // The sample interval.
+	Containers []ResponseContainerMetrics // The current resource usage of the running containers.
+	History    []ResponseMetricsSample    // Sorted by time. The last sample is the latest one.
+}
+
+type ResponseMetricsSample struct {
+	Time int64 // Unix timestamp
+
+	CPUPercent  float64 // Summed CPU usage of all containers. 100 percent equals one CPU.
+	MemoryUsage uint64  // In bytes
+	MemoryLimit uint64  // In bytes
+
+	NetworkRxRate float64 // Received bytes per second.
+	NetworkTxRate float64 // Transmitted bytes per second.
+
+	Storage          uint64 // Bytes referenced by the app.
+	StorageExclusive uint64 // Bytes only used by the app.
+	BackupsStorage   uint64 // Bytes only used by the app backups.
+}
+
+type ResponseContainerMetrics struct {
+	Name        string
+	CPUPercent  float64
+	MemoryUsage uint64
+	MemoryLimit uint64
+	NetworkRx   uint64 // Received bytes since the container started.
+	NetworkTx   uint64 // Transmitted bytes since the container started.
+}
+
+// ResponseLogLine is a single line of a log stream.
+// Log streams are sent as newline separated JSON values with the StreamContentType.
+type ResponseLogLine struct {
+	Container string
+	Stream    string // stdout or stderr
+	Timestamp string // RFC3339 timestamp. Only set if requested.
+	Message   string
+	Error     string // Set if the stream failed. No further lines are sent.
+}
+
+type ResponseConfig struct {
+	FilePath string // The path of the daemon config file.
+	Options  []ResponseConfigOption
+}
+
+type ResponseConfigOption struct {
+	Name   string
+	Value  string
+	Source string // default, file, env or flag.
+}
+
+type ResponsePermissions struct {
+	User     string
+	Groups   []string
+	AllTypes bool   // All request types are allowed.
+	Types    []Type // The allowed request types.
+	AllApps  bool   // All apps can be accessed.
+	Apps     []string
+}
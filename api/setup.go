@@ -23,8 +23,10 @@ package api
 //###################//
 
 type Setup struct {
-	Env   Env
-	Ports Ports
+	Env           Env
+	Ports         Ports
+	Resources     Resources
+	RestartPolicy RestartPolicy
 }
 
 type Env []*EnvValue
@@ -50,3 +52,31 @@ type Port struct {
 	HostPort    int
 	Description string
 }
+
+type Resources []*ResourceLimits
+
+// ResourceLimits overwrites the Turtlefile resource limits of a container.
+// Empty values keep the Turtlefile limits.
+type ResourceLimits struct {
+	Container string // The container name.
+
+	// Optional
+	Memory      string // Memory limit with an optional unit suffix (b, k, m or g), e.g. 512m.
+	MemorySwap  string // Total limit of memory and swap. -1 enables unlimited swap.
+	CPUShares   int64  // Relative CPU weight compared to other containers.
+	CPUSet      string // CPUs in which the execution is allowed, e.g. 0-3 or 0,1.
+	CPUQuota    int64  // CPU time in microseconds per CPUPeriod.
+	CPUPeriod   int64  // CPU CFS period in microseconds.
+	PidsLimit   int64  // Maximum number of processes.
+	BlkioWeight int64  // Relative block IO weight between 10 and 1000.
+}
+
+// RestartPolicy overwrites the Turtlefile restart policy.
+// Empty values keep the Turtlefile values.
+type RestartPolicy struct {
+	Policy     string // never, on-failure or always.
+	MaxRetries int    // Maximum restarts within the window. -1 for unlimited restarts.
+	Window     string // The time window of the restart budget, e.g. 10m.
+	Backoff    string // The delay before the first restart, e.g. 1s.
+	MaxBackoff string // The maximum delay between restarts, e.g. 5m.
+}
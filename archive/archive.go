@@ -0,0 +1,433 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+// Package archive implements the encrypted and compressed turtle backup archive.
+//
+// The archive starts with a plaintext header containing the magic, the key salt
+// and the nonce prefix. It is followed by AES-256-GCM encrypted frames.
+// The key is derived from the password with scrypt. Each frame consists of a flag
+// byte marking the final frame, the ciphertext length and the ciphertext. The frame
+// counter and the flag are part of the nonce, so reordered, removed or truncated
+// frames are detected. The decrypted stream contains the JSON manifest prefixed
+// with its length and the gzip compressed backup data.
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// Version is the current archive format version.
+	Version = 1
+
+	magic = "TURTLEB1"
+
+	saltLength        = 16
+	noncePrefixLength = 7
+	keyLength         = 32 // AES-256
+	frameSize         = 64 * 1024
+	maxManifestSize   = 16 * 1024 * 1024
+
+	flagFinal byte = 1
+
+	// scrypt parameters.
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+//#####################//
+//### Manifest type ###//
+//#####################//
+
+// Manifest describes the archived backup.
+type Manifest struct {
+	Version    int
+	App        string // The name of the exported app.
+	Timestamp  string // The backup unix timestamp.
+	Created    int64  // Unix timestamp of the export.
+	Turtlefile string // The Turtlefile content of the backup.
+	Settings   string // The app settings file content of the backup.
+}
+
+//##############//
+//### Public ###//
+//##############//
+
+// NewWriter writes the archive header and the manifest to w and returns
+// a writer for the backup data. The data is compressed and encrypted.
+// Close has to be called to write the final frame. w is not closed.
+func NewWriter(w io.Writer, password string, m *Manifest) (io.WriteCloser, error) {
+	if len(password) == 0 {
+		return nil, fmt.Errorf("the archive password is missing!")
+	}
+
+	// Create the random salt and nonce prefix.
+	header := make([]byte, len(magic)+saltLength+noncePrefixLength)
+	copy(header, magic)
+	if _, err := rand.Read(header[len(magic):]); err != nil {
+		return nil, fmt.Errorf("failed to create random archive salt: %v", err)
+	}
+
+	salt := header[len(magic) : len(magic)+saltLength]
+	prefix := header[len(magic)+saltLength:]
+
+	gcm, err := newCipher(password, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Write the plaintext header.
+	if _, err = w.Write(header); err != nil {
+		return nil, err
+	}
+
+	ew := &encryptWriter{
+		w:      w,
+		gcm:    gcm,
+		prefix: prefix,
+		buf:    make([]byte, 0, frameSize),
+	}
+
+	// Write the manifest prefixed with its length.
+	m.Version = Version
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode archive manifest: %v", err)
+	}
+
+	size := make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(len(data)))
+	if _, err = ew.Write(size); err != nil {
+		return nil, err
+	}
+	if _, err = ew.Write(data); err != nil {
+		return nil, err
+	}
+
+	return &archiveWriter{
+		gz: gzip.NewWriter(ew),
+		ew: ew,
+	}, nil
+}
+
+// NewReader reads the archive header and the manifest from r and returns
+// a reader for the decompressed backup data. The reader returns an error
+// if the archive was modified or is incomplete.
+func NewReader(r io.Reader, password string) (*Manifest, io.Reader, error) {
+	// Read the plaintext header.
+	header := make([]byte, len(magic)+saltLength+noncePrefixLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, nil, fmt.Errorf("failed to read archive header: %v", err)
+	} else if string(header[:len(magic)]) != magic {
+		return nil, nil, fmt.Errorf("invalid or unsupported archive format!")
+	}
+
+	gcm, err := newCipher(password, header[len(magic):len(magic)+saltLength])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dr := &decryptReader{
+		r:      r,
+		gcm:    gcm,
+		prefix: header[len(magic)+saltLength:],
+	}
+
+	// Read the manifest.
+	size := make([]byte, 4)
+	if _, err = io.ReadFull(dr, size); err != nil {
+		return nil, nil, fmt.Errorf("failed to read archive manifest: %v", err)
+	}
+
+	n := binary.BigEndian.Uint32(size)
+	if n > maxManifestSize {
+		return nil, nil, fmt.Errorf("failed to read archive manifest: invalid size")
+	}
+
+	data := make([]byte, n)
+	if _, err = io.ReadFull(dr, data); err != nil {
+		return nil, nil, fmt.Errorf("failed to read archive manifest: %v", err)
+	}
+
+	var m Manifest
+	if err = json.Unmarshal(data, &m); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode archive manifest: %v", err)
+	} else if m.Version != Version {
+		return nil, nil, fmt.Errorf("unsupported archive version '%v'!", m.Version)
+	}
+
+	return &m, &archiveReader{dr: dr}, nil
+}
+
+// Copy copies the raw archive from src to dst without decrypting it.
+// An error is returned if the final frame is missing.
+func Copy(dst io.Writer, src io.Reader) (int64, error) {
+	var written int64
+
+	copyN := func(n int64) error {
+		c, err := io.CopyN(dst, src, n)
+		written += c
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	// Copy the header.
+	if err := copyN(int64(len(magic) + saltLength + noncePrefixLength)); err != nil {
+		return written, fmt.Errorf("incomplete archive: %v", err)
+	}
+
+	// Copy all frames.
+	frameHeader := make([]byte, 5)
+	for {
+		if _, err := io.ReadFull(src, frameHeader); err != nil {
+			return written, fmt.Errorf("incomplete archive: %v", err)
+		}
+
+		c, err := dst.Write(frameHeader)
+		written += int64(c)
+		if err != nil {
+			return written, err
+		}
+
+		if err = copyN(int64(binary.BigEndian.Uint32(frameHeader[1:]))); err != nil {
+			return written, fmt.Errorf("incomplete archive: %v", err)
+		}
+
+		if frameHeader[0]&flagFinal != 0 {
+			return written, nil
+		}
+	}
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// newCipher derives the key from the password and returns the AES-GCM cipher.
+func newCipher(password string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(password), salt, scryptN, scryptR, scryptP, keyLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive archive key: %v", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// nonce returns the nonce of the frame. It consists of the
+// random prefix, the frame counter and the frame flag.
+func nonce(prefix []byte, counter uint32, flag byte) []byte {
+	n := make([]byte, noncePrefixLength+5)
+	copy(n, prefix)
+	binary.BigEndian.PutUint32(n[noncePrefixLength:], counter)
+	n[noncePrefixLength+4] = flag
+	return n
+}
+
+//##########################//
+//### encryptWriter type ###//
+//##########################//
+
+type encryptWriter struct {
+	w       io.Writer
+	gcm     cipher.AEAD
+	prefix  []byte
+	counter uint32
+	buf     []byte
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		// Write the frame if the buffer is full.
+		if len(e.buf) == frameSize {
+			if err := e.writeFrame(0); err != nil {
+				return written, err
+			}
+		}
+
+		n := frameSize - len(e.buf)
+		if n > len(p) {
+			n = len(p)
+		}
+
+		e.buf = append(e.buf, p[:n]...)
+		p = p[n:]
+		written += n
+	}
+
+	return written, nil
+}
+
+// Close writes the final frame.
+func (e *encryptWriter) Close() error {
+	return e.writeFrame(flagFinal)
+}
+
+func (e *encryptWriter) writeFrame(flag byte) error {
+	if e.counter == ^uint32(0) {
+		return fmt.Errorf("archive is too large!")
+	}
+
+	ciphertext := e.gcm.Seal(nil, nonce(e.prefix, e.counter, flag), e.buf, []byte{flag})
+	e.counter++
+	e.buf = e.buf[:0]
+
+	header := make([]byte, 5)
+	header[0] = flag
+	binary.BigEndian.PutUint32(header[1:], uint32(len(ciphertext)))
+
+	if _, err := e.w.Write(header); err != nil {
+		return err
+	}
+	_, err := e.w.Write(ciphertext)
+	return err
+}
+
+//##########################//
+//### decryptReader type ###//
+//##########################//
+
+type decryptReader struct {
+	r       io.Reader
+	gcm     cipher.AEAD
+	prefix  []byte
+	counter uint32
+	buf     []byte
+	final   bool
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.final {
+			return 0, io.EOF
+		}
+
+		if err := d.readFrame(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+
+	return n, nil
+}
+
+func (d *decryptReader) readFrame() error {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(d.r, header); err != nil {
+		return fmt.Errorf("incomplete archive: %v", err)
+	}
+
+	flag := header[0]
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > frameSize+uint32(d.gcm.Overhead()) {
+		return fmt.Errorf("invalid archive frame size")
+	}
+
+	ciphertext := make([]byte, size)
+	if _, err := io.ReadFull(d.r, ciphertext); err != nil {
+		return fmt.Errorf("incomplete archive: %v", err)
+	}
+
+	plaintext, err := d.gcm.Open(nil, nonce(d.prefix, d.counter, flag), ciphertext, []byte{flag})
+	if err != nil {
+		return fmt.Errorf("failed to decrypt archive: wrong password or modified archive")
+	}
+
+	d.counter++
+	d.buf = plaintext
+	d.final = flag&flagFinal != 0
+
+	return nil
+}
+
+//##########################//
+//### archiveWriter type ###//
+//##########################//
+
+// archiveWriter compresses the data before it is encrypted.
+type archiveWriter struct {
+	gz *gzip.Writer
+	ew *encryptWriter
+}
+
+func (a *archiveWriter) Write(p []byte) (int, error) {
+	return a.gz.Write(p)
+}
+
+func (a *archiveWriter) Close() error {
+	if err := a.gz.Close(); err != nil {
+		return err
+	}
+	return a.ew.Close()
+}
+
+//##########################//
+//### archiveReader type ###//
+//##########################//
+
+// archiveReader decompresses the decrypted data.
+// The gzip reader is created lazily to return its errors on read.
+type archiveReader struct {
+	dr *decryptReader
+	br *bufio.Reader
+	gz *gzip.Reader
+}
+
+func (a *archiveReader) Read(p []byte) (int, error) {
+	if a.gz == nil {
+		a.br = bufio.NewReader(a.dr)
+		gz, err := gzip.NewReader(a.br)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decompress archive: %v", err)
+		}
+		a.gz = gz
+	}
+
+	n, err := a.gz.Read(p)
+	if err == io.EOF {
+		// Check if the final frame was reached.
+		if _, errD := a.br.ReadByte(); errD != io.EOF {
+			if errD == nil {
+				errD = fmt.Errorf("unexpected data after the compressed archive data")
+			}
+			return n, errD
+		}
+	}
+
+	return n, err
+}
@@ -0,0 +1,196 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package archive
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+)
+
+const testPassword = "secret"
+
+// newTestArchive creates an archive of random data spanning multiple frames.
+func newTestArchive(t *testing.T) ([]byte, []byte) {
+	data := make([]byte, 3*frameSize+100)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, testPassword, &Manifest{
+		App:       "myapp",
+		Timestamp: "1445254208",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes(), data
+}
+
+// readTestArchive decrypts the complete archive.
+func readTestArchive(b []byte, password string) (*Manifest, []byte, error) {
+	m, r, err := NewReader(bytes.NewReader(b), password)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := ioutil.ReadAll(r)
+	return m, data, err
+}
+
+// splitFrames splits the archive into its plaintext header and its frames.
+func splitFrames(t *testing.T, b []byte) ([]byte, [][]byte) {
+	headerSize := len(magic) + saltLength + noncePrefixLength
+	header, b := b[:headerSize], b[headerSize:]
+
+	var frames [][]byte
+	for len(b) > 0 {
+		if len(b) < 5 {
+			t.Fatal("invalid frame header")
+		}
+
+		size := 5 + int(binary.BigEndian.Uint32(b[1:5]))
+		frames = append(frames, b[:size])
+		b = b[size:]
+	}
+
+	if len(frames) < 3 {
+		t.Fatalf("expected at least 3 frames, got %v", len(frames))
+	}
+
+	return header, frames
+}
+
+func joinFrames(header []byte, frames ...[]byte) []byte {
+	return bytes.Join(append([][]byte{header}, frames...), nil)
+}
+
+func TestRoundTrip(t *testing.T) {
+	b, data := newTestArchive(t)
+
+	m, out, err := readTestArchive(b, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Version != Version || m.App != "myapp" || m.Timestamp != "1445254208" {
+		t.Errorf("invalid manifest: %+v", m)
+	}
+	if !bytes.Equal(out, data) {
+		t.Error("decrypted data does not match")
+	}
+
+	// The raw copy has to be identical.
+	var buf bytes.Buffer
+	n, err := Copy(&buf, bytes.NewReader(append(b, "trailing"...)))
+	if err != nil {
+		t.Fatal(err)
+	} else if n != int64(len(b)) || !bytes.Equal(buf.Bytes(), b) {
+		t.Error("copied archive does not match")
+	}
+}
+
+func TestWrongPassword(t *testing.T) {
+	b, _ := newTestArchive(t)
+
+	if _, _, err := readTestArchive(b, "wrong"); err == nil {
+		t.Error("expected an error for a wrong password")
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	b, _ := newTestArchive(t)
+
+	for _, size := range []int{0, 10, len(magic) + saltLength + noncePrefixLength + 3, len(b) / 2, len(b) - 1} {
+		if _, _, err := readTestArchive(b[:size], testPassword); err == nil {
+			t.Errorf("expected an error for an archive truncated to %v bytes", size)
+		}
+		if _, err := Copy(ioutil.Discard, bytes.NewReader(b[:size])); err == nil {
+			t.Errorf("expected a copy error for an archive truncated to %v bytes", size)
+		}
+	}
+}
+
+func TestModifiedFrames(t *testing.T) {
+	b, _ := newTestArchive(t)
+	header, f := splitFrames(t, b)
+	last := len(f) - 1
+
+	tests := []struct {
+		name    string
+		archive []byte
+	}{
+		{"reordered", joinFrames(header, append([][]byte{f[0], f[2], f[1]}, f[3:]...)...)},
+		{"duplicated", joinFrames(header, append([][]byte{f[0], f[1], f[1]}, f[2:]...)...)},
+		{"removed", joinFrames(header, append([][]byte{f[0]}, f[2:]...)...)},
+		{"modified", func() []byte {
+			m := joinFrames(header, f...)
+			m[len(header)+10] ^= 1
+			return m
+		}()},
+	}
+
+	for _, test := range tests {
+		if _, _, err := readTestArchive(test.archive, testPassword); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+
+	// Check that the unmodified frames are valid.
+	if _, _, err := readTestArchive(joinFrames(header, f...), testPassword); err != nil {
+		t.Fatal(err)
+	}
+	if f[last][0]&flagFinal == 0 {
+		t.Fatal("last frame is not marked as final")
+	}
+}
+
+func TestMissingFinalFrame(t *testing.T) {
+	b, _ := newTestArchive(t)
+	header, f := splitFrames(t, b)
+	last := len(f) - 1
+
+	// The final frame is removed.
+	if _, _, err := readTestArchive(joinFrames(header, f[:last]...), testPassword); err == nil {
+		t.Error("expected an error for a missing final frame")
+	}
+	if _, err := Copy(ioutil.Discard, bytes.NewReader(joinFrames(header, f[:last]...))); err == nil {
+		t.Error("expected a copy error for a missing final frame")
+	}
+
+	// The final frame is removed and the previous frame is marked as final.
+	forged := make([]byte, len(f[last-1]))
+	copy(forged, f[last-1])
+	forged[0] |= flagFinal
+
+	frames := append(append([][]byte{}, f[:last-1]...), forged)
+	if _, _, err := readTestArchive(joinFrames(header, frames...), testPassword); err == nil {
+		t.Error("expected an error for a forged final frame")
+	}
+}
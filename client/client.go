@@ -46,16 +46,64 @@ const (
 //### Access Groups ###//
 //#####################//
 
-type Group int
-type Groups []Group
+var (
+	// The permissions of the current user.
+	// They are obtained from the daemon on startup and are nil if unknown.
+	permissions *api.ResponsePermissions
+
+	// The request types required by each command.
+	commandTypes = make(map[string][]api.Type)
+)
+
+// loadPermissions obtains the permissions of the current user from the daemon.
+func loadPermissions() error {
+	response, err := sendRequest(api.TypePermissions, nil)
+	if err != nil {
+		return err
+	}
+
+	var p api.ResponsePermissions
+	if err = response.MapTo(&p); err != nil {
+		return err
+	}
+
+	permissions = &p
+
+	return nil
+}
+
+// isCommandAllowed returns a boolean whenever the user is allowed
+// to perform all requests of the command.
+// All commands are allowed if the permissions are unknown.
+func isCommandAllowed(key string) bool {
+	if permissions == nil || permissions.AllTypes {
+		return true
+	}
+
+	for _, t := range commandTypes[key] {
+		allowed := false
+		for _, pt := range permissions.Types {
+			if pt == t {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+
+	return true
+}
 
 //####################//
 //### Command type ###//
 //####################//
 
 var (
-	commands        = make(map[string]Command)
-	errInvalidUsage = errors.New("invalid usage.")
+	commands          = make(map[string]Command)
+	errInvalidUsage   = errors.New("invalid usage.")
+	errInvalidCommand = errors.New("invalid command")
 )
 
 type Command interface {
@@ -64,8 +112,12 @@ type Command interface {
 	PrintUsage()
 }
 
-func AddCommand(key string, command Command) {
+// AddCommand registers a command.
+// Pass the request types which are sent by the command.
+// The command is hidden if the user is not allowed to perform them.
+func AddCommand(key string, command Command, types ...api.Type) {
 	commands[key] = command
+	commandTypes[key] = types
 }
 
 //###############//
@@ -84,10 +136,6 @@ var (
 	// Create the reader from stdin.
 	reader = bufio.NewReader(os.Stdin)
 
-	// Network
-	host string = "127.0.0.1"
-	port string = "28239"
-
 	// Prompt
 	prompt string = colorPrompt + "[turtle]$ " + colorReset + colorInput
 )
@@ -96,9 +144,25 @@ func main() {
 	// Set the maximum number of CPUs that can be executing simultaneously.
 	runtime.GOMAXPROCS(runtime.NumCPU())
 
+	// Load the connection settings.
+	if err := initConnection(); err != nil {
+		fmt.Printf("%serror: %v%s\n", colorError, err, colorReset)
+		os.Exit(exitCodeError)
+	}
+
+	// Run a single command if passed as arguments and exit.
+	if len(os.Args) > 1 {
+		os.Exit(runScript(os.Args[1:]))
+	}
+
 	// Catch interrupts.
 	go onInterrupt()
 
+	// Obtain the user permissions to hide forbidden commands.
+	if err := loadPermissions(); err != nil {
+		fmt.Printf("%swarning: failed to obtain user permissions: %v%s\n", colorError, err, colorReset)
+	}
+
 	// Print our cute turtle.
 	printTurtle()
 
@@ -176,18 +240,16 @@ func readCommand() {
 	// Remove the first command key from the slice.
 	args = args[1:]
 
-	// Try to find the command in the commands map.
-	cmd, ok := commands[key]
-	if !ok {
-		fmt.Printf("%serror: invalid command\n", colorError)
+	// Run the command.
+	cmd, err := runCommand(key, args)
+	if cmd == nil {
+		fmt.Printf("%serror: %v\n", colorError, err)
 		return
 	}
 
 	// Add the command to the history.
 	gnureadline.AddHistory(line)
 
-	// Run the command.
-	err = cmd.Run(args)
 	if err != nil {
 		fmt.Printf("%serror: %v\n", colorError, err)
 
@@ -203,6 +265,48 @@ func readCommand() {
 	}
 }
 
+// runCommand runs the command with the given key.
+// The global yes flag is removed from the arguments.
+// The returned command is nil if no command with the key exists.
+func runCommand(key string, args []string) (Command, error) {
+	// Try to find the command in the commands map.
+	cmd, ok := commands[key]
+	if !ok {
+		return nil, errInvalidCommand
+	} else if !isCommandAllowed(key) {
+		return cmd, api.NewError(api.ErrorCodePermissionDenied, "permission denied")
+	}
+
+	// Obtain the output format for this command only.
+	args, format, err := extractOutputFlag(args)
+	if err != nil {
+		return cmd, err
+	}
+
+	// Write all other output to stderr, if the output is machine-readable.
+	// Only the printed response data is written to stdout.
+	outputFormat = format
+	if outputFormat != outputTable {
+		stdout := os.Stdout
+		outputWriter, os.Stdout = stdout, os.Stderr
+		defer func() {
+			os.Stdout = stdout
+		}()
+	}
+	defer func() {
+		outputFormat = outputTable
+	}()
+
+	// Enable the yes flag for this command only.
+	args, assumeYes = extractYesFlag(args)
+	defer func() {
+		assumeYes = false
+	}()
+
+	// Run the command.
+	return cmd, cmd.Run(args)
+}
+
 func printTurtle() {
 	fmt.Println(`      ___
  ,,  // \\
@@ -215,8 +319,62 @@ func printTurtle() {
 // sendRequest sends a request to the daemon server.
 // If a remote error occurres, the error value will be extracted and returned as error.
 func sendRequest(requestType api.Type, data interface{}) (*api.Response, error) {
+	return sendUploadRequest(requestType, data, nil)
+}
+
+// sendUploadRequest sends a request to the daemon server.
+// The upload data is sent after the request in the request body.
+func sendUploadRequest(requestType api.Type, data interface{}, upload io.Reader) (*api.Response, error) {
+	// Perform the request.
+	httpResponse, err := postRequest(requestType, data, upload)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+
+	return readResponse(httpResponse)
+}
+
+// sendStreamRequest sends a stream request to the daemon and returns the
+// stream body. The body has to be closed by the caller.
+func sendStreamRequest(requestType api.Type, data interface{}) (io.ReadCloser, error) {
+	return openStream(requestType, data, api.StreamContentType)
+}
+
+// sendArchiveRequest sends a request to the daemon and returns the
+// archive body. The body has to be closed by the caller.
+func sendArchiveRequest(requestType api.Type, data interface{}) (io.ReadCloser, error) {
+	return openStream(requestType, data, api.ArchiveContentType)
+}
+
+// openStream sends a request to the daemon and returns the response
+// body if the response has the expected content type.
+func openStream(requestType api.Type, data interface{}, contentType string) (io.ReadCloser, error) {
+	// Perform the request.
+	httpResponse, err := postRequest(requestType, data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The daemon sends a normal response if the stream was not started.
+	if httpResponse.Header.Get("Content-Type") != contentType {
+		defer httpResponse.Body.Close()
+
+		if _, err = readResponse(httpResponse); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("the daemon did not start the stream!")
+	}
+
+	return httpResponse.Body, nil
+}
+
+// postRequest posts a new request to the daemon.
+// The optional upload data is appended to the request body.
+func postRequest(requestType api.Type, data interface{}, upload io.Reader) (*http.Response, error) {
 	// Create a new request value.
 	request := api.NewRequest(requestType, data)
+	request.Token = token
 
 	// Marshal the request to JSON.
 	json, err := request.ToJSON()
@@ -225,19 +383,21 @@ func sendRequest(requestType api.Type, data interface{}) (*api.Response, error)
 	}
 
 	// Create a new io.Reader from the JSON.
-	jsonReader := bytes.NewReader(json)
+	var body io.Reader = bytes.NewReader(json)
+	if upload != nil {
+		body = io.MultiReader(body, upload)
+	}
 
 	// Create a new HTTP request.
-	req, _ := http.NewRequest("POST", "http://"+host+":"+port, jsonReader)
+	req, _ := http.NewRequest("POST", daemonURL, body)
 	req.Header.Set("Content-Type", "application/json")
 
-	// Get the HTTP client and perform the request.
-	client := &http.Client{}
-	httpResponse, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
+	// Perform the request.
+	return httpClient.Do(req)
+}
 
+// readResponse reads the response value from the HTTP response body.
+func readResponse(httpResponse *http.Response) (*api.Response, error) {
 	// Marshal the received JSON response to a response value.
 	response, err := api.NewResponseFromJSON(httpResponse.Body)
 	if err != nil {
@@ -245,8 +405,8 @@ func sendRequest(requestType api.Type, data interface{}) (*api.Response, error)
 	}
 
 	// The API versions have to match.
-	if request.Version != api.Version {
-		return nil, fmt.Errorf("API Versions don't match: client=%s server=%s", request.Version, api.Version)
+	if response.Version != api.Version {
+		return nil, fmt.Errorf("API Versions don't match: client=%s server=%s", api.Version, response.Version)
 	}
 
 	// Check if an error occurred.
@@ -257,8 +417,11 @@ func sendRequest(requestType api.Type, data interface{}) (*api.Response, error)
 			return nil, err
 		}
 
-		// Create an error from the error message string.
-		return nil, fmt.Errorf(rErr.ErrorMessage)
+		// Create an error from the error code and message.
+		return nil, &api.Error{
+			Code:    rErr.ErrorCode,
+			Message: rErr.ErrorMessage,
+		}
 	}
 
 	return response, nil
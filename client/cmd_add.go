@@ -27,7 +27,7 @@ import (
 
 func init() {
 	// Add this command.
-	AddCommand("add", new(CmdAdd))
+	AddCommand("add", new(CmdAdd), api.TypeAdd, api.TypeHostFingerprintInfo)
 }
 
 type CmdAdd struct{}
@@ -37,41 +37,37 @@ func (c CmdAdd) Help() string {
 }
 
 func (c CmdAdd) PrintUsage() {
-	fmt.Println("Usage: add")
+	fmt.Println("Usage: add [--name NAME --url SOURCE_URL [--branch BRANCH]] [--yes]")
 	fmt.Printf("\n%s\n", c.Help())
+	fmt.Println("The values are requested interactively if no flags are passed.")
 }
 
 func (c CmdAdd) Run(args []string) error {
-	// Check if any arguments are passed.
-	if len(args) > 0 {
-		return errInvalidUsage
-	}
+	// Parse the flags.
+	f := newFlagSet("add")
+	name := f.String("name", "", "")
+	sourceURL := f.String("url", "", "")
+	branch := f.String("branch", "master", "")
 
-	fmt.Println("Add a new app")
-
-	// Get the app name.
-	fmt.Print("Name: ")
-	name, err := readline()
+	args, err := parseFlags(f, args)
 	if err != nil {
 		return err
 	}
 
-	// Get the app source url.
-	fmt.Print("Source URL: ")
-	sourceURL, err := readline()
-	if err != nil {
-		return err
+	// Check if any arguments are passed.
+	if len(args) > 0 {
+		return errInvalidUsage
 	}
 
-	// Get the app branch.
-	fmt.Print("Branch [master]: ")
-	branch, err := readline("master")
-	if err != nil {
-		return err
+	// Ask for the values if no flags are passed.
+	if f.NFlag() == 0 {
+		if *name, *sourceURL, *branch, err = c.readValues(); err != nil {
+			return err
+		}
 	}
 
 	// Valdiate.
-	if len(name) == 0 || len(sourceURL) == 0 || len(branch) == 0 {
+	if len(*name) == 0 || len(*sourceURL) == 0 || len(*branch) == 0 {
 		return fmt.Errorf("invalid or emtpy option(s)!")
 	}
 
@@ -81,15 +77,15 @@ func (c CmdAdd) Run(args []string) error {
 	}
 
 	// Add the host fingerprint if not trusted yet.
-	if err = c.HandleHostFingerprint(sourceURL); err != nil {
+	if err = c.HandleHostFingerprint(*sourceURL); err != nil {
 		return err
 	}
 
 	// Create a new add request.
 	request := api.RequestAdd{
-		Name:      name,
-		SourceURL: sourceURL,
-		Branch:    branch,
+		Name:      *name,
+		SourceURL: *sourceURL,
+		Branch:    *branch,
 	}
 
 	// Send the add request to the daemon.
@@ -103,6 +99,30 @@ func (c CmdAdd) Run(args []string) error {
 	return nil
 }
 
+// readValues requests the app name, source URL and branch from the user.
+func (c CmdAdd) readValues() (name, sourceURL, branch string, err error) {
+	fmt.Println("Add a new app")
+
+	// Get the app name.
+	fmt.Print("Name: ")
+	name, err = readline()
+	if err != nil {
+		return
+	}
+
+	// Get the app source url.
+	fmt.Print("Source URL: ")
+	sourceURL, err = readline()
+	if err != nil {
+		return
+	}
+
+	// Get the app branch.
+	fmt.Print("Branch [master]: ")
+	branch, err = readline("master")
+	return
+}
+
 func (c CmdAdd) HandleHostFingerprint(sourceURL string) error {
 	host := utils.GetHostFromUrl(sourceURL)
 
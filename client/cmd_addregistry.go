@@ -0,0 +1,93 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/desertbit/turtle/api"
+)
+
+func init() {
+	// Add this command.
+	AddCommand("addregistry", new(CmdAddRegistry), api.TypeAddRegistry)
+}
+
+type CmdAddRegistry struct{}
+
+func (c CmdAddRegistry) Help() string {
+	return "Store the credentials of a private docker registry."
+}
+
+func (c CmdAddRegistry) PrintUsage() {
+	fmt.Println("Usage: addregistry HOST USERNAME [--email EMAIL]")
+	fmt.Printf("\n%s\n", c.Help())
+	fmt.Println("The password is read from stdin without echo. Use docker.io as host for the Docker Hub.")
+	fmt.Println("\nAvailable flags:")
+	printc(cmdIndent+"--email EMAIL", "The optional account email.")
+	flush()
+}
+
+func (c CmdAddRegistry) Run(args []string) error {
+	// Parse the flags.
+	var email string
+	f := newFlagSet("addregistry")
+	f.StringVar(&email, "email", "", "")
+
+	args, err := parseFlags(f, args)
+	if err != nil {
+		return err
+	}
+
+	// Check if the arguments are passed.
+	if len(args) != 2 {
+		return errInvalidUsage
+	}
+
+	host := strings.TrimSpace(args[0])
+	username := strings.TrimSpace(args[1])
+	if len(host) == 0 || len(username) == 0 {
+		return fmt.Errorf("invalid host or username passed.")
+	}
+
+	// Read the password.
+	password, err := readPassword(false)
+	if err != nil {
+		return err
+	}
+
+	// Create a new request.
+	request := api.RequestAddRegistry{
+		Host:     host,
+		Username: username,
+		Password: password,
+		Email:    strings.TrimSpace(email),
+	}
+
+	// Send the request to the daemon.
+	_, err = sendRequest(api.TypeAddRegistry, request)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Successfully added registry credentials.")
+
+	return nil
+}
@@ -0,0 +1,119 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/desertbit/turtle/api"
+)
+
+func init() {
+	// Add this command.
+	AddCommand("autoupdate", new(CmdAutoUpdate), api.TypeSetAutoUpdate)
+}
+
+type CmdAutoUpdate struct{}
+
+func (c CmdAutoUpdate) Help() string {
+	return "Enable or disable automatic updates of an app."
+}
+
+func (c CmdAutoUpdate) PrintUsage() {
+	fmt.Println("Usage: autoupdate APP disabled|poll|webhook [--interval DURATION] [--safe] [--new-secret]")
+	fmt.Printf("\n%s\n", c.Help())
+	fmt.Println("poll:    Poll the app source for new commits.")
+	fmt.Println("webhook: Update the app on signed push events of GitHub, Gitea or GitLab.")
+	fmt.Println("\nAvailable flags:")
+	printc(cmdIndent+"--interval DURATION", "The poll interval. The daemon default is used if not set.")
+	printc(cmdIndent+"--safe", "Verify the updates with a temporary clone.")
+	printc(cmdIndent+"--new-secret", "Replace the webhook secret.")
+	flush()
+}
+
+func (c CmdAutoUpdate) Run(args []string) error {
+	// Parse the flags.
+	var safe, newSecret bool
+	var interval string
+	f := newFlagSet("autoupdate")
+	f.StringVar(&interval, "interval", "", "")
+	f.BoolVar(&safe, "safe", false, "")
+	f.BoolVar(&newSecret, "new-secret", false, "")
+
+	args, err := parseFlags(f, args)
+	if err != nil {
+		return err
+	}
+
+	// Check if the arguments are passed.
+	if len(args) != 2 {
+		return errInvalidUsage
+	}
+
+	// Obtain the app name.
+	appName := strings.TrimSpace(args[0])
+	if len(appName) == 0 {
+		return fmt.Errorf("invalid app name passed.")
+	}
+
+	// Create a new request.
+	request := api.RequestSetAutoUpdate{
+		Name:      appName,
+		Mode:      strings.TrimSpace(args[1]),
+		Interval:  strings.TrimSpace(interval),
+		Safe:      safe,
+		NewSecret: newSecret,
+	}
+
+	// Send the request to the daemon.
+	response, err := sendRequest(api.TypeSetAutoUpdate, request)
+	if err != nil {
+		return err
+	}
+
+	// Map the response data to the custom type.
+	var res api.ResponseSetAutoUpdate
+	if err = response.MapTo(&res); err != nil {
+		return err
+	}
+
+	// Print the data in the requested output format.
+	return printOutput(res, func() {
+		fmt.Println()
+		printc("Auto Update", res.Mode)
+		if res.Mode == "poll" {
+			printc("Interval", res.Interval)
+		}
+		printc("Safe", res.Safe)
+		if len(res.WebhookPath) > 0 {
+			printc("Webhook Path", res.WebhookPath)
+			printc("Webhook Secret", res.Secret)
+		}
+		flush()
+
+		if len(res.WebhookPath) > 0 {
+			fmt.Print(colorHint)
+			fmt.Println("\nAdd the webhook with the secret to the push events of the app repository.")
+			fmt.Print(colorOutput)
+		}
+
+		fmt.Println()
+	})
+}
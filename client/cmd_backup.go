@@ -27,7 +27,7 @@ import (
 
 func init() {
 	// Add this command.
-	AddCommand("backup", new(CmdBackup))
+	AddCommand("backup", new(CmdBackup), api.TypeBackup)
 }
 
 type CmdBackup struct{}
@@ -0,0 +1,84 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/desertbit/turtle/api"
+)
+
+func init() {
+	// Add this command.
+	AddCommand("config", new(CmdConfig), api.TypeConfig)
+}
+
+type CmdConfig struct{}
+
+func (c CmdConfig) Help() string {
+	return "Show the effective daemon configuration."
+}
+
+func (c CmdConfig) PrintUsage() {
+	fmt.Println("Usage: config")
+	fmt.Printf("\n%s\n", c.Help())
+}
+
+func (c CmdConfig) Run(args []string) error {
+	// Check if any arguments are passed.
+	if len(args) > 0 {
+		return errInvalidUsage
+	}
+
+	// Send the config request to the daemon.
+	response, err := sendRequest(api.TypeConfig, nil)
+	if err != nil {
+		return err
+	}
+
+	// Map the response data to the config value.
+	var data api.ResponseConfig
+	if err = response.MapTo(&data); err != nil {
+		return err
+	}
+
+	// Print the data in the requested output format.
+	return printOutput(data, func() {
+		// Print a new empty line.
+		fmt.Println()
+
+		// Print the config file path.
+		printc("Config file:", data.FilePath)
+		println()
+
+		// Print the column header.
+		println("OPTION\tVALUE\tSOURCE")
+
+		// Print all the options.
+		for _, o := range data.Options {
+			printc(o.Name, o.Value, o.Source)
+		}
+
+		// Flush the output.
+		flush()
+
+		// Print a new empty line.
+		fmt.Println()
+	})
+}
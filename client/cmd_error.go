@@ -27,7 +27,7 @@ import (
 
 func init() {
 	// Add this command.
-	AddCommand("error", new(CmdError))
+	AddCommand("error", new(CmdError), api.TypeErrorMsg)
 }
 
 type CmdError struct{}
@@ -70,11 +70,12 @@ func (c CmdError) Run(args []string) error {
 		return err
 	}
 
-	if len(data.ErrorMessage) == 0 {
-		fmt.Println("No errors occurred :)")
-	} else {
-		fmt.Printf("Error message:\n%s\n", data.ErrorMessage)
-	}
-
-	return nil
+	// Print the data in the requested output format.
+	return printOutput(data, func() {
+		if len(data.ErrorMessage) == 0 {
+			fmt.Println("No errors occurred :)")
+		} else {
+			fmt.Printf("Error message:\n%s\n", data.ErrorMessage)
+		}
+	})
 }
@@ -0,0 +1,117 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/desertbit/turtle/api"
+	"github.com/desertbit/turtle/archive"
+)
+
+func init() {
+	// Add this command.
+	AddCommand("export", new(CmdExport), api.TypeExportBackup)
+}
+
+type CmdExport struct{}
+
+func (c CmdExport) Help() string {
+	return "Download an app's backup as encrypted and compressed archive."
+}
+
+func (c CmdExport) PrintUsage() {
+	fmt.Println("Usage: export APP BACKUP_TIMESTAMP FILE")
+	fmt.Printf("\n%s\n", c.Help())
+	fmt.Println("The archive password is read from stdin without echo.")
+}
+
+func (c CmdExport) Run(args []string) (err error) {
+	// Check if the arguments are passed.
+	if len(args) != 3 {
+		return errInvalidUsage
+	}
+
+	// Obtain the app name.
+	name := strings.TrimSpace(args[0])
+	if len(name) == 0 {
+		return fmt.Errorf("invalid app name passed.")
+	}
+
+	// Obtain the timestamp.
+	unix := strings.TrimSpace(args[1])
+	if len(unix) == 0 {
+		return fmt.Errorf("invalid backup timestamp passed.")
+	}
+
+	// Don't overwrite existing files.
+	path := strings.TrimSpace(args[2])
+	if _, err = os.Stat(path); err == nil {
+		return fmt.Errorf("the file '%s' already exists.", path)
+	}
+
+	// Read the archive password.
+	password, err := readPassword(true)
+	if err != nil {
+		return err
+	}
+
+	// Create a new request.
+	request := api.RequestExportBackup{
+		Name:     name,
+		Unix:     unix,
+		Password: password,
+	}
+
+	// Send the request to the daemon.
+	body, err := sendArchiveRequest(api.TypeExportBackup, request)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	// Create the archive file.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create file '%s': %v", path, err)
+	}
+
+	// Remove the incomplete file on error.
+	defer func() {
+		if err != nil {
+			os.Remove(path)
+		}
+	}()
+
+	// Download the archive.
+	// The daemon closes the stream if the export failed.
+	n, err := archive.Copy(f, body)
+	if errC := f.Close(); err == nil && errC != nil {
+		err = errC
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download backup: %v", err)
+	}
+
+	fmt.Printf("Successfully exported backup to '%s' (%s).\n", path, formatBytes(uint64(n)))
+
+	return nil
+}
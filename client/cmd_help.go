@@ -64,7 +64,11 @@ func (c CmdHelp) Run(args []string) error {
 	fmt.Println("Available commands:\n")
 
 	// Print all available commands with a description.
+	// Skip commands which the user is not allowed to run.
 	for _, key := range keys {
+		if !isCommandAllowed(key) {
+			continue
+		}
 		printc(cmdIndent+key, commands[key].Help())
 	}
 
@@ -0,0 +1,89 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/desertbit/turtle/api"
+)
+
+func init() {
+	// Add this command.
+	AddCommand("hooklog", new(CmdHookLog), api.TypeBackupHookLog)
+}
+
+type CmdHookLog struct{}
+
+func (c CmdHookLog) Help() string {
+	return "Print the backup hooks output recorded with an app's backup."
+}
+
+func (c CmdHookLog) PrintUsage() {
+	fmt.Println("Usage: hooklog APP BACKUP_TIMESTAMP")
+	fmt.Printf("\n%s\n", c.Help())
+}
+
+func (c CmdHookLog) Run(args []string) error {
+	// Check if an argument is passed.
+	if len(args) != 2 {
+		return errInvalidUsage
+	}
+
+	// Obtain the app name.
+	name := strings.TrimSpace(args[0])
+	if len(name) == 0 {
+		return fmt.Errorf("invalid app name passed.")
+	}
+
+	// Obtain the timestamp.
+	unix := strings.TrimSpace(args[1])
+	if len(unix) == 0 {
+		return fmt.Errorf("invalid backup timestamp passed.")
+	}
+
+	// Create a new request.
+	request := api.RequestBackupHookLog{
+		Name: name,
+		Unix: unix,
+	}
+
+	// Send the request to the daemon.
+	response, err := sendRequest(api.TypeBackupHookLog, request)
+	if err != nil {
+		return err
+	}
+
+	// Map the response data to the custom type.
+	var res api.ResponseBackupHookLog
+	if err = response.MapTo(&res); err != nil {
+		return err
+	}
+
+	// Print the data in the requested output format.
+	return printOutput(res, func() {
+		if len(res.Log) == 0 {
+			fmt.Println("No backup hooks were executed for this backup.")
+			return
+		}
+
+		fmt.Print(res.Log)
+	})
+}
@@ -0,0 +1,113 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/desertbit/turtle/api"
+)
+
+func init() {
+	// Add this command.
+	AddCommand("import", new(CmdImport), api.TypeImportBackup)
+}
+
+type CmdImport struct{}
+
+func (c CmdImport) Help() string {
+	return "Upload an exported backup archive as new backup or as new app."
+}
+
+func (c CmdImport) PrintUsage() {
+	fmt.Println("Usage: import FILE APP [--new]")
+	fmt.Printf("\n%s\n", c.Help())
+	fmt.Println("The archive password is read from stdin without echo.")
+	fmt.Println("\nAvailable flags:")
+	printc(cmdIndent+"--new", "Create the new app APP instead of a backup of the existing app.")
+	flush()
+}
+
+func (c CmdImport) Run(args []string) error {
+	// Parse the flags.
+	var newApp bool
+	f := newFlagSet("import")
+	f.BoolVar(&newApp, "new", false, "")
+
+	args, err := parseFlags(f, args)
+	if err != nil {
+		return err
+	}
+
+	// Check if the arguments are passed.
+	if len(args) != 2 {
+		return errInvalidUsage
+	}
+
+	// Obtain the app name.
+	name := strings.TrimSpace(args[1])
+	if len(name) == 0 {
+		return fmt.Errorf("invalid app name passed.")
+	}
+
+	// Open the archive.
+	path := strings.TrimSpace(args[0])
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %v", err)
+	}
+	defer file.Close()
+
+	// Read the archive password.
+	password, err := readPassword(false)
+	if err != nil {
+		return err
+	}
+
+	// Create a new request.
+	request := api.RequestImportBackup{
+		Name:     name,
+		Password: password,
+		NewApp:   newApp,
+	}
+
+	fmt.Println("Uploading archive...")
+
+	// Send the request with the archive to the daemon.
+	response, err := sendUploadRequest(api.TypeImportBackup, request, file)
+	if err != nil {
+		return err
+	}
+
+	// Map the response data to the custom type.
+	var res api.ResponseImportBackup
+	if err = response.MapTo(&res); err != nil {
+		return err
+	}
+
+	if newApp {
+		fmt.Printf("Successfully imported backup '%s' of app '%s' as new app '%s'.\n", res.SourceUnix, res.SourceApp, res.Name)
+	} else {
+		fmt.Printf("Successfully imported backup '%s' of app '%s' as backup '%s'.\n", res.SourceUnix, res.SourceApp, res.Unix)
+	}
+
+	return nil
+}
@@ -22,13 +22,14 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/desertbit/turtle/api"
 )
 
 func init() {
 	// Add this command.
-	AddCommand("info", new(CmdInfo))
+	AddCommand("info", new(CmdInfo), api.TypeInfo)
 }
 
 type CmdInfo struct{}
@@ -71,49 +72,154 @@ func (c CmdInfo) Run(args []string) error {
 		return err
 	}
 
-	// Print new lines and a header.
-	println("\nGeneral:\n========")
-
-	// Print the general information.
-	printc("Name", d.Name)
-	printc("State", d.State)
-	printc("Turtlefile", d.Turtlefile)
-	printc("Maintainer", d.Maintainer)
-	printc("SourceURL", d.SourceURL)
-	printc("Branch", d.Branch)
-
-	// Print new lines and a header.
-	println("\nExposed Ports:\n==============")
-
-	// Print the ports.
-	var hp string
-	for _, p := range d.Setup.Ports {
-		if p.HostPort > 0 {
-			hp = strconv.Itoa(p.HostPort)
-		} else {
-			hp = "DISABLED"
+	// Print the data in the requested output format.
+	return printOutput(d, func() {
+		// Print new lines and a header.
+		println("\nGeneral:\n========")
+
+		// Print the general information.
+		printc("Name", d.Name)
+		printc("State", d.State)
+		printc("Turtlefile", d.Turtlefile)
+		printc("Maintainer", d.Maintainer)
+		printc("SourceURL", d.SourceURL)
+		printc("Branch", d.Branch)
+		if len(d.Pin) > 0 {
+			printc("Pinned", d.Pin)
 		}
+		printc("Commit", shortCommit(d.Commit))
 
-		printf("%v/%s => %s\t%s\n", p.Port, p.Protocol, hp, p.Description)
-	}
+		// Print the restart policy and state.
+		p := d.RestartPolicy
+		maxRetries := strconv.Itoa(p.MaxRetries)
+		if p.MaxRetries < 0 {
+			maxRetries = "unlimited"
+		}
+		printc("Restart Policy", fmt.Sprintf("%s (max %s retries within %s, backoff %s up to %s)",
+			p.Policy, maxRetries, p.Window, p.Backoff, p.MaxBackoff))
+		printc("Restarts", d.RestartAttempts)
+		if d.NextRestart > 0 {
+			printc("Next Restart", time.Unix(d.NextRestart, 0).Format(time.Stamp))
+		}
 
-	// Print new lines and a header.
-	println("\nEnvironment variables:\n=====================")
+		// Print the safe update state.
+		if len(d.UpdateState) > 0 {
+			printc("Update", d.UpdateState)
+		}
+		if len(d.UpdateError) > 0 {
+			printc("Update Error", d.UpdateError)
+		}
+
+		// Print the automatic update state.
+		printc("Auto Update", d.AutoUpdate)
+		if len(d.AutoUpdateState) > 0 {
+			printc("Auto Update State", d.AutoUpdateState)
+		}
+		if len(d.AutoUpdateError) > 0 {
+			printc("Auto Update Error", d.AutoUpdateError)
+		}
 
-	// Print the environement variables.
-	for _, env := range d.Setup.Env {
-		if len(env.Description) > 0 {
-			printf("%s = %s\t(%s)\n", env.Name, env.Value, env.Description)
+		// Print the automatic backup schedule.
+		printc("Backup Schedule", d.BackupSchedule)
+		if d.NextBackup > 0 {
+			printc("Next Backup", time.Unix(d.NextBackup, 0).Format(time.Stamp))
+		}
+
+		// Print the backup retention policy.
+		if d.DefaultRetention {
+			printc("Backup Retention", d.Retention+" (default)")
 		} else {
-			printf("%s = %s\t\n", env.Name, env.Value)
+			printc("Backup Retention", d.Retention)
 		}
-	}
 
-	// Flush the output.
-	flush()
+		// Print the replication state.
+		if d.LastReplication > 0 {
+			printc("Replicated Backup", d.ReplicatedBackup)
+			printc("Last Replication", time.Unix(d.LastReplication, 0).Format(time.Stamp))
+		}
+		if len(d.ReplicationError) > 0 {
+			printc("Replication Error", d.ReplicationError)
+		}
+
+		// Print new lines and a header.
+		println("\nExposed Ports:\n==============")
+
+		// Print the ports.
+		var hp string
+		for _, p := range d.Setup.Ports {
+			if p.HostPort > 0 {
+				hp = strconv.Itoa(p.HostPort)
+			} else {
+				hp = "DISABLED"
+			}
+
+			printf("%v/%s => %s\t%s\n", p.Port, p.Protocol, hp, p.Description)
+		}
+
+		// Print new lines and a header.
+		println("\nEnvironment variables:\n=====================")
+
+		// Print the environement variables.
+		for _, env := range d.Setup.Env {
+			if len(env.Description) > 0 {
+				printf("%s = %s\t(%s)\n", env.Name, env.Value, env.Description)
+			} else {
+				printf("%s = %s\t\n", env.Name, env.Value)
+			}
+		}
+
+		// Print the latest resource usage sample.
+		if d.Metrics != nil {
+			// Print new lines and a header.
+			println("\nResource usage:\n===============")
+
+			printc("CPU", formatPercent(d.Metrics.CPUPercent))
+			printc("Memory", formatMemory(d.Metrics.MemoryUsage, d.Metrics.MemoryLimit))
+			printc("Network", formatRate(d.Metrics.NetworkRxRate)+" received, "+formatRate(d.Metrics.NetworkTxRate)+" transmitted")
+			printc("Storage", formatBytes(d.Metrics.Storage)+" ("+formatBytes(d.Metrics.StorageExclusive)+" exclusive)")
+			printc("Backups", formatBytes(d.Metrics.BackupsStorage))
+			printc("Sampled", time.Unix(d.Metrics.Time, 0).Format(time.Stamp))
+		}
+
+		// Print new lines and a header.
+		println("\nResource limits (app settings):\n===============================")
+
+		// Print the resource limits which overwrite the turtlefile limits.
+		for _, r := range d.Setup.Resources {
+			limits := formatResourceLimits(r)
+			if len(limits) == 0 {
+				limits = "Turtlefile limits"
+			}
+
+			printf("%s\t%s\n", r.Container, limits)
+		}
+
+		// Flush the output.
+		flush()
+
+		// Print a new empty line.
+		fmt.Println()
+	})
+}
+
+// formatResourceLimits returns a list of all set resource limits.
+func formatResourceLimits(r *api.ResourceLimits) string {
+	var limits []string
+
+	add := func(key string, value interface{}, set bool) {
+		if set {
+			limits = append(limits, fmt.Sprintf("%s=%v", key, value))
+		}
+	}
 
-	// Print a new empty line.
-	fmt.Println()
+	add("Memory", r.Memory, len(r.Memory) > 0)
+	add("MemorySwap", r.MemorySwap, len(r.MemorySwap) > 0)
+	add("CPUShares", r.CPUShares, r.CPUShares != 0)
+	add("CPUSet", r.CPUSet, len(r.CPUSet) > 0)
+	add("CPUQuota", r.CPUQuota, r.CPUQuota != 0)
+	add("CPUPeriod", r.CPUPeriod, r.CPUPeriod != 0)
+	add("PidsLimit", r.PidsLimit, r.PidsLimit != 0)
+	add("BlkioWeight", r.BlkioWeight, r.BlkioWeight != 0)
 
-	return nil
+	return strings.Join(limits, " ")
 }
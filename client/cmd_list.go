@@ -26,7 +26,7 @@ import (
 
 func init() {
 	// Add this command.
-	AddCommand("list", new(CmdList))
+	AddCommand("list", new(CmdList), api.TypeList)
 }
 
 type CmdList struct{}
@@ -58,22 +58,23 @@ func (c CmdList) Run(args []string) error {
 		return err
 	}
 
-	// Print a new empty line.
-	fmt.Println()
+	// Print the data in the requested output format.
+	return printOutput(list, func() {
+		// Print a new empty line.
+		fmt.Println()
 
-	// Print the column header.
-	println("NAME\tTURTLEFILE\tSTATE")
+		// Print the column header.
+		println("NAME\tTURTLEFILE\tSTATE")
 
-	// Print all the apps.
-	for _, app := range list.Apps {
-		printc(app.Name, app.Turtlefile, app.State)
-	}
-
-	// Flush the output.
-	flush()
+		// Print all the apps.
+		for _, app := range list.Apps {
+			printc(app.Name, app.Turtlefile, app.State)
+		}
 
-	// Print a new empty line.
-	fmt.Println()
+		// Flush the output.
+		flush()
 
-	return nil
+		// Print a new empty line.
+		fmt.Println()
+	})
 }
@@ -27,7 +27,7 @@ import (
 
 func init() {
 	// Add this command.
-	AddCommand("listb", new(CmdListBackups))
+	AddCommand("listb", new(CmdListBackups), api.TypeListBackups)
 }
 
 type CmdListBackups struct{}
@@ -70,28 +70,33 @@ func (c CmdListBackups) Run(args []string) error {
 		return err
 	}
 
-	// Check if no backups are present.
-	if len(list.Backups) == 0 {
-		fmt.Println("There are no backups.")
-		return nil
-	}
-
-	// Print a new empty line.
-	fmt.Println()
-
-	// Print the column header.
-	println("DATE\tUNIX TIMESTAMP")
-
-	// Print all the backups.
-	for _, b := range list.Backups {
-		printc(b.Date, b.Unix)
-	}
-
-	// Flush the output.
-	flush()
-
-	// Print a new empty line.
-	fmt.Println()
-
-	return nil
+	// Print the data in the requested output format.
+	return printOutput(list, func() {
+		// Check if no backups are present.
+		if len(list.Backups) == 0 {
+			fmt.Println("There are no backups.")
+			return
+		}
+
+		// Print a new empty line.
+		fmt.Println()
+
+		// Print the column header.
+		println("DATE\tUNIX TIMESTAMP\tPINNED")
+
+		// Print all the backups.
+		for _, b := range list.Backups {
+			pinned := ""
+			if b.Pinned {
+				pinned = "yes"
+			}
+			printc(b.Date, b.Unix, pinned)
+		}
+
+		// Flush the output.
+		flush()
+
+		// Print a new empty line.
+		fmt.Println()
+	})
 }
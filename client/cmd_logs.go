@@ -19,16 +19,20 @@
 package main
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/desertbit/turtle/api"
 )
 
 func init() {
 	// Add this command.
-	AddCommand("logs", new(CmdLogs))
+	AddCommand("logs", new(CmdLogs), api.TypeLogs, api.TypeStreamLogs)
 }
 
 type CmdLogs struct{}
@@ -38,16 +42,45 @@ func (c CmdLogs) Help() string {
 }
 
 func (c CmdLogs) PrintUsage() {
-	fmt.Println("Usage: logs APP [CONTAINER] [STREAM]")
-	fmt.Printf("\n%s\n\n", c.Help())
+	fmt.Println("Usage: logs APP [CONTAINER] [STREAM] [-f] [--tail LINES] [--since TIME] [--until TIME] [-t] [--history]")
+	fmt.Printf("\n%s\n", c.Help())
+	fmt.Println("The logs of all app containers are shown if no container is passed together with a flag.")
+	fmt.Println("TIME is a unix timestamp, a RFC3339 date or a duration relative to now (e.g. 10m).\n")
 	fmt.Println("Available streams:")
 	printc(cmdIndent+"combined", "Combined standard streams. Default option.")
 	printc(cmdIndent+"stdout", "Fetch only standard output messages.")
 	printc(cmdIndent+"stderr", "Fetch only standard error messages.")
 	flush()
+	fmt.Println("\nAvailable flags:")
+	printc(cmdIndent+"-f, --follow", "Follow the log output.")
+	printc(cmdIndent+"--tail LINES", "Show only the last lines of each container.")
+	printc(cmdIndent+"--since TIME", "Show only logs since the time.")
+	printc(cmdIndent+"--until TIME", "Show only logs until the time.")
+	printc(cmdIndent+"-t, --timestamps", "Show the timestamps.")
+	printc(cmdIndent+"--history", "Show the captured logs of all previous containers.")
+	flush()
 }
 
 func (c CmdLogs) Run(args []string) error {
+	// Parse the flags.
+	var follow, timestamps, history bool
+	var tail int
+	var since, until string
+	f := newFlagSet("logs")
+	f.BoolVar(&follow, "f", false, "")
+	f.BoolVar(&follow, "follow", false, "")
+	f.BoolVar(&timestamps, "t", false, "")
+	f.BoolVar(&timestamps, "timestamps", false, "")
+	f.BoolVar(&history, "history", false, "")
+	f.IntVar(&tail, "tail", 0, "")
+	f.StringVar(&since, "since", "", "")
+	f.StringVar(&until, "until", "", "")
+
+	args, err := parseFlags(f, args)
+	if err != nil {
+		return err
+	}
+
 	// Check if an argument is passed.
 	if len(args) < 1 || len(args) > 3 {
 		return errInvalidUsage
@@ -76,11 +109,105 @@ func (c CmdLogs) Run(args []string) error {
 		}
 	}
 
+	// Print the available containers if no container and no flag is passed.
+	if len(container) == 0 && f.NFlag() == 0 {
+		return c.printContainers(appName)
+	}
+
+	// Create a new stream request.
+	request := api.RequestStreamLogs{
+		Name:       appName,
+		Stream:     stream,
+		Follow:     follow,
+		Tail:       tail,
+		Timestamps: timestamps,
+		History:    history,
+	}
+
+	if len(container) > 0 {
+		request.Containers = []string{container}
+	}
+
+	if tail < 0 {
+		return fmt.Errorf("invalid tail value passed: %v", tail)
+	}
+	if len(since) > 0 {
+		if request.Since, err = parseTimeFlag(since); err != nil {
+			return err
+		}
+	}
+	if len(until) > 0 {
+		if request.Until, err = parseTimeFlag(until); err != nil {
+			return err
+		}
+	}
+
+	// Send the request to the daemon.
+	body, err := sendStreamRequest(api.TypeStreamLogs, request)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	// Prefix the lines with the container name if multiple containers are streamed.
+	prefix := len(container) == 0
+
+	// Print the log lines as soon as they are received.
+	decoder := json.NewDecoder(bufio.NewReader(body))
+	for {
+		var line api.ResponseLogLine
+		if err = decoder.Decode(&line); err == io.EOF {
+			// The stream ended.
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read the log stream: %v", err)
+		}
+
+		if len(line.Error) > 0 {
+			return fmt.Errorf("%s: %s", line.Container, line.Error)
+		}
+
+		if err = c.printLine(line, prefix); err != nil {
+			return err
+		}
+	}
+}
+
+// printLine prints a single log line in the current output format.
+func (c CmdLogs) printLine(line api.ResponseLogLine, prefix bool) error {
+	// Print a single JSON value per line.
+	if outputFormat == outputJSON {
+		b, err := json.Marshal(line)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(outputWriter, string(b))
+		return nil
+	}
+
+	// Print a YAML document for each line.
+	if outputFormat == outputYAML {
+		fmt.Fprintln(outputWriter, "---")
+	}
+
+	return printOutput(line, func() {
+		l := line.Message
+		if len(line.Timestamp) > 0 {
+			l = line.Timestamp + " " + l
+		}
+		if prefix {
+			l = colorHint + line.Container + " | " + colorOutput + l
+		}
+
+		fmt.Println(cmdIndent + l)
+	})
+}
+
+// printContainers prints the available app containers.
+func (c CmdLogs) printContainers(appName string) error {
 	// Create a new request.
 	request := api.RequestLogs{
-		Name:      appName,
-		Container: container,
-		Stream:    stream,
+		Name: appName,
 	}
 
 	// Send the request to the daemon.
@@ -95,7 +222,8 @@ func (c CmdLogs) Run(args []string) error {
 		return err
 	}
 
-	if len(data.Containers) > 0 {
+	// Print the data in the requested output format.
+	return printOutput(data, func() {
 		fmt.Println("Available app containers:\n")
 
 		for i, c := range data.Containers {
@@ -105,19 +233,23 @@ func (c CmdLogs) Run(args []string) error {
 
 		fmt.Println()
 		c.PrintUsage()
+	})
+}
 
-		return nil
+// parseTimeFlag parses a unix timestamp, a RFC3339 date or
+// a duration relative to now and returns the unix timestamp.
+func parseTimeFlag(s string) (int64, error) {
+	if unix, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return unix, nil
 	}
 
-	if len(data.LogMessages) == 0 {
-		fmt.Println("No log messages available.")
-	} else {
-		lines := strings.Split(data.LogMessages, "\n")
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.Unix(), nil
+	}
 
-		for _, l := range lines {
-			fmt.Println(cmdIndent + l)
-		}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d).Unix(), nil
 	}
 
-	return nil
+	return 0, fmt.Errorf("invalid time value passed: %s", s)
 }
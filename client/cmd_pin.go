@@ -0,0 +1,92 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/desertbit/turtle/api"
+)
+
+func init() {
+	// Add the commands.
+	AddCommand("pin", &CmdPin{pin: true}, api.TypePinBackup)
+	AddCommand("unpin", &CmdPin{pin: false}, api.TypePinBackup)
+}
+
+type CmdPin struct {
+	pin bool
+}
+
+func (c CmdPin) Help() string {
+	if c.pin {
+		return "Pin an app's backup. Pinned backups are never removed by the retention policy."
+	}
+	return "Remove the pin of an app's backup."
+}
+
+func (c CmdPin) PrintUsage() {
+	if c.pin {
+		fmt.Println("Usage: pin APP BACKUP_TIMESTAMP")
+	} else {
+		fmt.Println("Usage: unpin APP BACKUP_TIMESTAMP")
+	}
+	fmt.Printf("\n%s\n", c.Help())
+}
+
+func (c CmdPin) Run(args []string) error {
+	// Check if an argument is passed.
+	if len(args) != 2 {
+		return errInvalidUsage
+	}
+
+	// Obtain the app name.
+	name := strings.TrimSpace(args[0])
+	if len(name) == 0 {
+		return fmt.Errorf("invalid app name passed.")
+	}
+
+	// Obtain the timestamp.
+	unix := strings.TrimSpace(args[1])
+	if len(unix) == 0 {
+		return fmt.Errorf("invalid backup timestamp passed.")
+	}
+
+	// Create a new request.
+	request := api.RequestPinBackup{
+		Name:  name,
+		Unix:  unix,
+		Unpin: !c.pin,
+	}
+
+	// Send the request to the daemon.
+	_, err := sendRequest(api.TypePinBackup, request)
+	if err != nil {
+		return err
+	}
+
+	if c.pin {
+		fmt.Printf("Successfully pinned backup '%s'.\n", unix)
+	} else {
+		fmt.Printf("Successfully unpinned backup '%s'.\n", unix)
+	}
+
+	return nil
+}
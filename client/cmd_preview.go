@@ -0,0 +1,158 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/desertbit/turtle/api"
+)
+
+func init() {
+	// Add this command.
+	AddCommand("preview", new(CmdPreview), api.TypeUpdatePreview)
+}
+
+type CmdPreview struct{}
+
+func (c CmdPreview) Help() string {
+	return "Preview the source and Turtlefile changes of an update without applying them."
+}
+
+func (c CmdPreview) PrintUsage() {
+	fmt.Println("Usage: preview APP [--revision REVISION]")
+	fmt.Printf("\n%s\n", c.Help())
+	fmt.Println("\nAvailable flags:")
+	printc(cmdIndent+"--revision REVISION", "Preview an update to a git tag or commit. The pinned revision or the branch is used by default.")
+	flush()
+}
+
+func (c CmdPreview) Run(args []string) error {
+	// Parse the flags.
+	var revision string
+	f := newFlagSet("preview")
+	f.StringVar(&revision, "revision", "", "")
+
+	args, err := parseFlags(f, args)
+	if err != nil {
+		return err
+	}
+
+	// Check if an argument is passed.
+	if len(args) != 1 {
+		return errInvalidUsage
+	}
+
+	// Obtain the app name.
+	appName := strings.TrimSpace(args[0])
+	if len(appName) == 0 {
+		return fmt.Errorf("invalid app name passed.")
+	}
+
+	// Create a new request.
+	request := api.RequestUpdatePreview{
+		Name:     appName,
+		Revision: strings.TrimSpace(revision),
+	}
+
+	// Send the request to the daemon.
+	response, err := sendRequest(api.TypeUpdatePreview, request)
+	if err != nil {
+		return err
+	}
+
+	// Map the response data to the custom type.
+	var p api.ResponseUpdatePreview
+	if err = response.MapTo(&p); err != nil {
+		return err
+	}
+
+	// Print the data in the requested output format.
+	return printOutput(p, func() {
+		fmt.Println()
+		printc("Deployed:", shortCommit(p.Commit))
+		printc("Target:", shortCommit(p.Target))
+		flush()
+
+		// Print the commits.
+		c.printRevisions("Incoming commits:", p.Commits)
+		c.printRevisions("Reverted commits:", p.Reverted)
+
+		if p.Commit == p.Target {
+			fmt.Println("\nThe app is up to date.")
+		}
+
+		// Print the Turtlefile changes.
+		if t := p.Turtlefile; t != nil {
+			fmt.Println("\nTurtlefile changes:")
+			for _, name := range t.AddedContainers {
+				printc(cmdIndent+"+ container", name)
+			}
+			for _, name := range t.RemovedContainers {
+				printc(cmdIndent+"- container", name)
+			}
+			for _, ic := range t.ImageChanges {
+				printc(cmdIndent+"~ image", ic.Container, ic.OldImage+" -> "+ic.NewImage)
+			}
+			for _, name := range t.AddedEnv {
+				printc(cmdIndent+"+ env", name)
+			}
+			for _, name := range t.RemovedEnv {
+				printc(cmdIndent+"- env", name)
+			}
+			for _, name := range t.RequiredEnv {
+				printc(cmdIndent+"~ env", name, "now required")
+			}
+			for _, port := range t.AddedPorts {
+				printc(cmdIndent+"+ port", port)
+			}
+			for _, port := range t.RemovedPorts {
+				printc(cmdIndent+"- port", port)
+			}
+			flush()
+		}
+
+		// Print the warnings.
+		if len(p.Warnings) > 0 {
+			fmt.Print(colorHint)
+			fmt.Println("\nWarnings:")
+			for _, w := range p.Warnings {
+				fmt.Println(cmdIndent + w)
+			}
+			fmt.Print(colorOutput)
+		}
+
+		fmt.Println()
+	})
+}
+
+// printRevisions prints the commits with a title if present.
+func (c CmdPreview) printRevisions(title string, revisions []api.ResponseRevision) {
+	if len(revisions) == 0 {
+		return
+	}
+
+	fmt.Printf("\n%s\n", title)
+	for _, r := range revisions {
+		printc(cmdIndent+shortCommit(r.Commit), time.Unix(r.Time, 0).Format("2006-01-02 15:04"), r.Author, r.Subject)
+	}
+	flush()
+}
@@ -0,0 +1,124 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/desertbit/turtle/api"
+)
+
+func init() {
+	// Add this command.
+	AddCommand("prune", new(CmdPrune), api.TypePruneBackups)
+}
+
+type CmdPrune struct{}
+
+func (c CmdPrune) Help() string {
+	return "Remove the backups of an app expired by its retention policy."
+}
+
+func (c CmdPrune) PrintUsage() {
+	fmt.Println("Usage: prune APP [--dry-run]")
+	fmt.Printf("\n%s\n", c.Help())
+	fmt.Println("Pinned backups and the last replicated backup are kept.")
+	fmt.Println("\nAvailable flags:")
+	printc(cmdIndent+"--dry-run", "Only list the backups which would be removed.")
+	flush()
+}
+
+func (c CmdPrune) Run(args []string) error {
+	// Parse the flags.
+	var dryRun bool
+	f := newFlagSet("prune")
+	f.BoolVar(&dryRun, "dry-run", false, "")
+
+	args, err := parseFlags(f, args)
+	if err != nil {
+		return err
+	}
+
+	// Check if the arguments are passed.
+	if len(args) != 1 {
+		return errInvalidUsage
+	}
+
+	// Obtain the app name.
+	name := strings.TrimSpace(args[0])
+	if len(name) == 0 {
+		return fmt.Errorf("invalid app name passed.")
+	}
+
+	// Confirm the request.
+	if !dryRun {
+		fmt.Printf("Remove all expired backups of app '%s'?\n", name)
+		if !confirmCommit() {
+			return nil
+		}
+	}
+
+	// Create a new request.
+	request := api.RequestPruneBackups{
+		Name:   name,
+		DryRun: dryRun,
+	}
+
+	// Send the request to the daemon.
+	response, err := sendRequest(api.TypePruneBackups, request)
+	if err != nil {
+		return err
+	}
+
+	// Map the response data to the custom type.
+	var res api.ResponsePruneBackups
+	if err = response.MapTo(&res); err != nil {
+		return err
+	}
+
+	// Print the data in the requested output format.
+	return printOutput(res, func() {
+		fmt.Printf("Retention policy: %s\n", res.Retention)
+
+		if len(res.Removed) == 0 {
+			fmt.Println("There are no expired backups.")
+			return
+		}
+
+		if res.DryRun {
+			fmt.Println("\nThe following backups would be removed:")
+		} else {
+			fmt.Println("\nRemoved backups:")
+		}
+
+		// Print the column header.
+		println("DATE\tUNIX TIMESTAMP")
+
+		for _, b := range res.Removed {
+			printc(b.Date, b.Unix)
+		}
+
+		// Flush the output.
+		flush()
+
+		// Print a new empty line.
+		fmt.Println()
+	})
+}
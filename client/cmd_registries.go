@@ -0,0 +1,70 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/desertbit/turtle/api"
+)
+
+func init() {
+	// Add this command.
+	AddCommand("registries", new(CmdRegistries), api.TypeListRegistries)
+}
+
+type CmdRegistries struct{}
+
+func (c CmdRegistries) Help() string {
+	return "List the docker registries with stored credentials."
+}
+
+func (c CmdRegistries) PrintUsage() {
+	fmt.Println("Usage: registries")
+	fmt.Printf("\n%s\n", c.Help())
+}
+
+func (c CmdRegistries) Run(args []string) error {
+	if len(args) != 0 {
+		return errInvalidUsage
+	}
+
+	// Send the request to the daemon.
+	response, err := sendRequest(api.TypeListRegistries, nil)
+	if err != nil {
+		return err
+	}
+
+	// Map the response data to the custom type.
+	var list api.ResponseListRegistries
+	if err = response.MapTo(&list); err != nil {
+		return err
+	}
+
+	// Print the data in the requested output format.
+	return printOutput(list, func() {
+		fmt.Println()
+		println("HOST\tUSERNAME\tEMAIL")
+		for _, r := range list.Registries {
+			printc(r.Host, r.Username, r.Email)
+		}
+		flush()
+		fmt.Println()
+	})
+}
@@ -0,0 +1,104 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/desertbit/turtle/api"
+)
+
+func init() {
+	// Add this command.
+	AddCommand("replicas", new(CmdReplicas), api.TypeListReplicas)
+}
+
+type CmdReplicas struct{}
+
+func (c CmdReplicas) Help() string {
+	return "Print a list of all replicated backups of an app on the replication target."
+}
+
+func (c CmdReplicas) PrintUsage() {
+	fmt.Println("Usage: replicas APP")
+	fmt.Printf("\n%s\n", c.Help())
+}
+
+func (c CmdReplicas) Run(args []string) error {
+	// Check if an argument is passed.
+	if len(args) != 1 {
+		return errInvalidUsage
+	}
+
+	// Obtain the app name.
+	name := strings.TrimSpace(args[0])
+	if len(name) == 0 {
+		return fmt.Errorf("invalid app name passed.")
+	}
+
+	// Create a new request.
+	request := api.RequestListReplicas{
+		Name: name,
+	}
+
+	// Send the list request to the daemon.
+	response, err := sendRequest(api.TypeListReplicas, request)
+	if err != nil {
+		return err
+	}
+
+	// Map the response data to the list value.
+	var list api.ResponseListReplicas
+	if err = response.MapTo(&list); err != nil {
+		return err
+	}
+
+	// Print the data in the requested output format.
+	return printOutput(list, func() {
+		fmt.Printf("Replication target: %s\n", list.Target)
+
+		// Check if no replicas are present.
+		if len(list.Replicas) == 0 {
+			fmt.Println("There are no replicas.")
+			return
+		}
+
+		// Print a new empty line.
+		fmt.Println()
+
+		// Print the column header.
+		println("DATE\tUNIX TIMESTAMP\tPARENT")
+
+		// Print all the replicas and mark the parent of the next replication.
+		for _, r := range list.Replicas {
+			parent := ""
+			if r.Unix == list.Parent {
+				parent = "*"
+			}
+			printc(r.Date, r.Unix, parent)
+		}
+
+		// Flush the output.
+		flush()
+
+		// Print a new empty line.
+		fmt.Println()
+	})
+}
@@ -0,0 +1,83 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/desertbit/turtle/api"
+)
+
+func init() {
+	// Add this command.
+	AddCommand("replicate", new(CmdReplicate), api.TypeReplicate)
+}
+
+type CmdReplicate struct{}
+
+func (c CmdReplicate) Help() string {
+	return "Replicate the new backups of an app to the replication target now."
+}
+
+func (c CmdReplicate) PrintUsage() {
+	fmt.Println("Usage: replicate APP")
+	fmt.Printf("\n%s\n", c.Help())
+}
+
+func (c CmdReplicate) Run(args []string) error {
+	// Check if an argument is passed.
+	if len(args) != 1 {
+		return errInvalidUsage
+	}
+
+	// Obtain the app name.
+	name := strings.TrimSpace(args[0])
+	if len(name) == 0 {
+		return fmt.Errorf("invalid app name passed.")
+	}
+
+	// Create a new request.
+	request := api.RequestReplicate{
+		Name: name,
+	}
+
+	fmt.Println("Replicating backups...")
+
+	// Send the request to the daemon.
+	response, err := sendRequest(api.TypeReplicate, request)
+	if err != nil {
+		return err
+	}
+
+	// Map the response data to the custom type.
+	var res api.ResponseReplicate
+	if err = response.MapTo(&res); err != nil {
+		return err
+	}
+
+	if len(res.Replicated) == 0 {
+		fmt.Println("All backups are already replicated.")
+		return nil
+	}
+
+	fmt.Printf("Successfully replicated backups: %s\n", strings.Join(res.Replicated, ", "))
+
+	return nil
+}
@@ -27,7 +27,7 @@ import (
 
 func init() {
 	// Add this command.
-	AddCommand("restart", new(CmdRestart))
+	AddCommand("restart", new(CmdRestart), api.TypeRestart)
 }
 
 type CmdRestart struct{}
@@ -27,7 +27,7 @@ import (
 
 func init() {
 	// Add this command.
-	AddCommand("restore", new(CmdRestore))
+	AddCommand("restore", new(CmdRestore), api.TypeRestoreBackup)
 }
 
 type CmdRestore struct{}
@@ -0,0 +1,91 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/desertbit/turtle/api"
+)
+
+func init() {
+	// Add this command.
+	AddCommand("restorereplica", new(CmdRestoreReplica), api.TypeRestoreReplica)
+}
+
+type CmdRestoreReplica struct{}
+
+func (c CmdRestoreReplica) Help() string {
+	return "Copy a replicated backup from the replication target to the app's backups."
+}
+
+func (c CmdRestoreReplica) PrintUsage() {
+	fmt.Println("Usage: restorereplica APP BACKUP_TIMESTAMP")
+	fmt.Printf("\n%s\n", c.Help())
+	fmt.Println("The app is created from the replica if it does not exist.")
+	fmt.Println("Otherwise restore the app data with 'restore APP BACKUP_TIMESTAMP'.")
+}
+
+func (c CmdRestoreReplica) Run(args []string) error {
+	// Check if an argument is passed.
+	if len(args) != 2 {
+		return errInvalidUsage
+	}
+
+	// Obtain the app name.
+	name := strings.TrimSpace(args[0])
+	if len(name) == 0 {
+		return fmt.Errorf("invalid app name passed.")
+	}
+
+	// Obtain the timestamp.
+	unix := strings.TrimSpace(args[1])
+	if len(unix) == 0 {
+		return fmt.Errorf("invalid backup timestamp passed.")
+	}
+
+	// Create a new request.
+	request := api.RequestRestoreReplica{
+		Name: name,
+		Unix: unix,
+	}
+
+	fmt.Println("Receiving replica...")
+
+	// Send the request to the daemon.
+	response, err := sendRequest(api.TypeRestoreReplica, request)
+	if err != nil {
+		return err
+	}
+
+	// Map the response data to the custom type.
+	var res api.ResponseRestoreReplica
+	if err = response.MapTo(&res); err != nil {
+		return err
+	}
+
+	if res.NewApp {
+		fmt.Printf("Successfully created app '%s' from replica '%s'.\n", res.Name, res.Unix)
+	} else {
+		fmt.Printf("Successfully restored replica '%s' as backup. Restore the app data with 'restore %s %s'.\n", res.Unix, res.Name, res.Unix)
+	}
+
+	return nil
+}
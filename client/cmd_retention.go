@@ -0,0 +1,96 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/desertbit/turtle/api"
+)
+
+func init() {
+	// Add this command.
+	AddCommand("retention", new(CmdRetention), api.TypeSetRetention)
+}
+
+type CmdRetention struct{}
+
+func (c CmdRetention) Help() string {
+	return "Set the backup retention policy of an app."
+}
+
+func (c CmdRetention) PrintUsage() {
+	fmt.Println("Usage: retention APP POLICY|default")
+	fmt.Printf("\n%s\n", c.Help())
+	fmt.Println("The policy consists of comma separated rules, e.g. all=24h,daily=7,weekly=4,monthly=12")
+	fmt.Println("\nAvailable rules:")
+	printc(cmdIndent+"all=DURATION", "Keep all backups within the duration.")
+	printc(cmdIndent+"hourly=N", "Keep the latest backup of the last N hours with backups.")
+	printc(cmdIndent+"daily=N", "Keep the latest backup of the last N days with backups.")
+	printc(cmdIndent+"weekly=N", "Keep the latest backup of the last N weeks with backups.")
+	printc(cmdIndent+"monthly=N", "Keep the latest backup of the last N months with backups.")
+	printc(cmdIndent+"yearly=N", "Keep the latest backup of the last N years with backups.")
+	flush()
+	fmt.Println("\nPass 'default' to use the policy of the daemon.")
+}
+
+func (c CmdRetention) Run(args []string) error {
+	// Check if the arguments are passed.
+	if len(args) != 2 {
+		return errInvalidUsage
+	}
+
+	// Obtain the app name.
+	name := strings.TrimSpace(args[0])
+	if len(name) == 0 {
+		return fmt.Errorf("invalid app name passed.")
+	}
+
+	// Obtain the policy.
+	policy := strings.TrimSpace(args[1])
+	if len(policy) == 0 {
+		return fmt.Errorf("invalid retention policy passed.")
+	} else if policy == "default" {
+		policy = ""
+	}
+
+	// Create a new request.
+	request := api.RequestSetRetention{
+		Name:      name,
+		Retention: policy,
+	}
+
+	// Send the request to the daemon.
+	response, err := sendRequest(api.TypeSetRetention, request)
+	if err != nil {
+		return err
+	}
+
+	// Map the response data to the custom type.
+	var res api.ResponseSetRetention
+	if err = response.MapTo(&res); err != nil {
+		return err
+	}
+
+	fmt.Printf("Successfully set the retention policy: %s\n", res.Retention)
+	fmt.Printf("Run 'prune %s --dry-run' to list the backups which will be removed.\n", name)
+
+	return nil
+}
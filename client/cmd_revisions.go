@@ -0,0 +1,127 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/desertbit/turtle/api"
+)
+
+func init() {
+	// Add this command.
+	AddCommand("revisions", new(CmdRevisions), api.TypeListRevisions)
+}
+
+type CmdRevisions struct{}
+
+func (c CmdRevisions) Help() string {
+	return "List the latest upstream commits and tags of an app."
+}
+
+func (c CmdRevisions) PrintUsage() {
+	fmt.Println("Usage: revisions APP [--limit COUNT]")
+	fmt.Printf("\n%s\n", c.Help())
+	fmt.Println("The deployed commit is marked with a star.")
+	fmt.Println("\nAvailable flags:")
+	printc(cmdIndent+"--limit COUNT", "Show at most COUNT commits and tags.")
+	flush()
+}
+
+func (c CmdRevisions) Run(args []string) error {
+	// Parse the flags.
+	var limit int
+	f := newFlagSet("revisions")
+	f.IntVar(&limit, "limit", 0, "")
+
+	args, err := parseFlags(f, args)
+	if err != nil {
+		return err
+	}
+
+	// Check if an argument is passed.
+	if len(args) != 1 || limit < 0 {
+		return errInvalidUsage
+	}
+
+	// Obtain the app name.
+	appName := strings.TrimSpace(args[0])
+	if len(appName) == 0 {
+		return fmt.Errorf("invalid app name passed.")
+	}
+
+	// Create a new request.
+	request := api.RequestListRevisions{
+		Name:  appName,
+		Limit: limit,
+	}
+
+	// Send the request to the daemon.
+	response, err := sendRequest(api.TypeListRevisions, request)
+	if err != nil {
+		return err
+	}
+
+	// Map the response data to the custom type.
+	var list api.ResponseListRevisions
+	if err = response.MapTo(&list); err != nil {
+		return err
+	}
+
+	// Print the data in the requested output format.
+	return printOutput(list, func() {
+		fmt.Println()
+
+		if len(list.Pin) > 0 {
+			fmt.Printf("Pinned to %s. Branch: %s\n\n", list.Pin, list.Branch)
+		} else {
+			fmt.Printf("Following branch %s.\n\n", list.Branch)
+		}
+
+		// Print the commits.
+		println("\tCOMMIT\tDATE\tAUTHOR\tSUBJECT")
+		for _, r := range list.Commits {
+			printc(deployedMark(r.Commit, list.Commit), shortCommit(r.Commit),
+				time.Unix(r.Time, 0).Format("2006-01-02 15:04"), r.Author, r.Subject)
+		}
+		flush()
+
+		// Print the tags.
+		if len(list.Tags) > 0 {
+			fmt.Println()
+			println("\tTAG\tCOMMIT")
+			for _, t := range list.Tags {
+				printc(deployedMark(t.Commit, list.Commit), t.Name, shortCommit(t.Commit))
+			}
+			flush()
+		}
+
+		fmt.Println()
+	})
+}
+
+// deployedMark returns a star if the commit is deployed.
+func deployedMark(commit, deployed string) string {
+	if commit == deployed {
+		return "*"
+	}
+	return ""
+}
@@ -27,7 +27,7 @@ import (
 
 func init() {
 	// Add this command.
-	AddCommand("rm", new(CmdRm))
+	AddCommand("rm", new(CmdRm), api.TypeRemove)
 }
 
 type CmdRm struct{}
@@ -37,11 +37,20 @@ func (c CmdRm) Help() string {
 }
 
 func (c CmdRm) PrintUsage() {
-	fmt.Println("Usage: rm APP")
+	fmt.Println("Usage: rm APP [--remove-backups] [--yes]")
 	fmt.Printf("\n%s\n", c.Help())
 }
 
 func (c CmdRm) Run(args []string) error {
+	// Parse the flags.
+	f := newFlagSet("rm")
+	removeBackups := f.Bool("remove-backups", false, "")
+
+	args, err := parseFlags(f, args)
+	if err != nil {
+		return err
+	}
+
 	// Check if an argument is passed.
 	if len(args) != 1 {
 		return errInvalidUsage
@@ -54,27 +63,14 @@ func (c CmdRm) Run(args []string) error {
 	}
 
 	// Check whenever to remove all backups also.
-	var removeBackups bool
-	for {
-		fmt.Print("Remove all backups also? (y/N) ")
-		input, err := readline("n")
-		if err != nil {
+	// Skip the question if the flag is passed or all requests are confirmed.
+	if f.NFlag() == 0 && !assumeYes {
+		if *removeBackups, err = c.readRemoveBackups(); err != nil {
 			return err
 		}
-		input = strings.ToLower(input)
-
-		if input == "y" {
-			removeBackups = true
-			break
-		} else if input == "n" {
-			removeBackups = false
-			break
-		}
-
-		continue
 	}
 
-	if removeBackups {
+	if *removeBackups {
 		fmt.Println("All backups will be deleted!")
 	} else {
 		fmt.Println("A new backup will be created before deletion.")
@@ -90,11 +86,11 @@ func (c CmdRm) Run(args []string) error {
 	// Create a new remove request.
 	request := api.RequestRemove{
 		Name:          appName,
-		RemoveBackups: removeBackups,
+		RemoveBackups: *removeBackups,
 	}
 
 	// Send the remove request to the daemon.
-	_, err := sendRequest(api.TypeRemove, request)
+	_, err = sendRequest(api.TypeRemove, request)
 	if err != nil {
 		return err
 	}
@@ -103,3 +99,21 @@ func (c CmdRm) Run(args []string) error {
 
 	return nil
 }
+
+// readRemoveBackups asks the user whenever to remove all backups also.
+func (c CmdRm) readRemoveBackups() (bool, error) {
+	for {
+		fmt.Print("Remove all backups also? (y/N) ")
+		input, err := readline("n")
+		if err != nil {
+			return false, err
+		}
+
+		input = strings.ToLower(input)
+		if input == "y" {
+			return true, nil
+		} else if input == "n" {
+			return false, nil
+		}
+	}
+}
@@ -27,7 +27,7 @@ import (
 
 func init() {
 	// Add this command.
-	AddCommand("rmb", new(CmdRmb))
+	AddCommand("rmb", new(CmdRmb), api.TypeRemoveBackup)
 }
 
 type CmdRmb struct{}
@@ -0,0 +1,76 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/desertbit/turtle/api"
+)
+
+func init() {
+	// Add this command.
+	AddCommand("rmregistry", new(CmdRmRegistry), api.TypeRemoveRegistry)
+}
+
+type CmdRmRegistry struct{}
+
+func (c CmdRmRegistry) Help() string {
+	return "Remove the credentials of a docker registry."
+}
+
+func (c CmdRmRegistry) PrintUsage() {
+	fmt.Println("Usage: rmregistry HOST")
+	fmt.Printf("\n%s\n", c.Help())
+}
+
+func (c CmdRmRegistry) Run(args []string) error {
+	// Check if an argument is passed.
+	if len(args) != 1 {
+		return errInvalidUsage
+	}
+
+	host := strings.TrimSpace(args[0])
+	if len(host) == 0 {
+		return fmt.Errorf("invalid host passed.")
+	}
+
+	fmt.Printf("Remove credentials of registry '%s'?\n", host)
+
+	// Confirm the request.
+	if !confirmCommit() {
+		return nil
+	}
+
+	// Create a new request.
+	request := api.RequestRemoveRegistry{
+		Host: host,
+	}
+
+	// Send the request to the daemon.
+	_, err := sendRequest(api.TypeRemoveRegistry, request)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Successfully removed registry credentials.")
+
+	return nil
+}
@@ -0,0 +1,102 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/desertbit/turtle/api"
+)
+
+func init() {
+	// Add this command.
+	AddCommand("rollback", new(CmdRollback), api.TypeRollbackSource)
+}
+
+type CmdRollback struct{}
+
+func (c CmdRollback) Help() string {
+	return "Roll back the source of an app. The volume data is not restored."
+}
+
+func (c CmdRollback) PrintUsage() {
+	fmt.Println("Usage: rollback APP [REVISION] [--safe]")
+	fmt.Printf("\n%s\n", c.Help())
+	fmt.Println("The app is pinned to the git tag or commit REVISION. The previously deployed commit is used by default.")
+	fmt.Println("Use 'update APP --unpin' to follow the app branch again.")
+	fmt.Println("\nAvailable flags:")
+	printc(cmdIndent+"--safe", "Verify the rollback with a temporary clone before the app is replaced.")
+	flush()
+}
+
+func (c CmdRollback) Run(args []string) error {
+	// Parse the flags.
+	var safe bool
+	f := newFlagSet("rollback")
+	f.BoolVar(&safe, "safe", false, "")
+
+	args, err := parseFlags(f, args)
+	if err != nil {
+		return err
+	}
+
+	// Check if an argument is passed.
+	if len(args) < 1 || len(args) > 2 {
+		return errInvalidUsage
+	}
+
+	// Obtain the app name.
+	appName := strings.TrimSpace(args[0])
+	if len(appName) == 0 {
+		return fmt.Errorf("invalid app name passed.")
+	}
+
+	// Obtain the optional revision.
+	var revision string
+	if len(args) == 2 {
+		revision = strings.TrimSpace(args[1])
+	}
+
+	if len(revision) > 0 {
+		fmt.Printf("Roll back source of app '%s' to '%s'?\n", appName, revision)
+	} else {
+		fmt.Printf("Roll back source of app '%s' to the previously deployed commit?\n", appName)
+	}
+
+	// Confirm the request.
+	if !confirmCommit() {
+		return nil
+	}
+
+	// Create a new request.
+	request := api.RequestRollbackSource{
+		Name:     appName,
+		Revision: revision,
+		Safe:     safe,
+	}
+
+	// Send the request to the daemon.
+	_, err = sendRequest(api.TypeRollbackSource, request)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
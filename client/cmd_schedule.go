@@ -0,0 +1,123 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/desertbit/turtle/api"
+)
+
+func init() {
+	// Add this command.
+	AddCommand("schedule", new(CmdSchedule), api.TypeSetBackupSchedule)
+}
+
+type CmdSchedule struct{}
+
+func (c CmdSchedule) Help() string {
+	return "Set the automatic backup schedule of an app."
+}
+
+func (c CmdSchedule) PrintUsage() {
+	fmt.Println("Usage: schedule APP default|disabled|interval DURATION|cron MINUTE HOUR DAY MONTH WEEKDAY [--stopped]")
+	fmt.Printf("\n%s\n", c.Help())
+	fmt.Println("default:  Backup the running app in the daemon backup interval.")
+	fmt.Println("interval: Backup the running app in the passed interval, e.g. 2h.")
+	fmt.Println("cron:     Backup the running app at the times of the cron expression, e.g. 0 3 * * *")
+	fmt.Println("disabled: Don't backup the app automatically.")
+	fmt.Println("\nAvailable flags:")
+	printc(cmdIndent+"--stopped", "Also backup the app if it is stopped.")
+	flush()
+}
+
+func (c CmdSchedule) Run(args []string) error {
+	// Parse the flags.
+	var stopped bool
+	f := newFlagSet("schedule")
+	f.BoolVar(&stopped, "stopped", false, "")
+
+	args, err := parseFlags(f, args)
+	if err != nil {
+		return err
+	}
+
+	// Check if the arguments are passed.
+	if len(args) < 2 {
+		return errInvalidUsage
+	}
+
+	// Obtain the app name.
+	appName := strings.TrimSpace(args[0])
+	if len(appName) == 0 {
+		return fmt.Errorf("invalid app name passed.")
+	}
+
+	// Create a new request.
+	request := api.RequestSetBackupSchedule{
+		Name:    appName,
+		Mode:    strings.TrimSpace(args[1]),
+		Stopped: stopped,
+	}
+
+	// Obtain the mode values.
+	values := args[2:]
+	switch request.Mode {
+	case "interval":
+		if len(values) != 1 {
+			return errInvalidUsage
+		}
+		request.Interval = values[0]
+	case "cron":
+		// The cron expression might be passed quoted as single argument.
+		request.Cron = strings.Join(values, " ")
+		if len(strings.Fields(request.Cron)) != 5 {
+			return errInvalidUsage
+		}
+	default:
+		if len(values) != 0 {
+			return errInvalidUsage
+		}
+	}
+
+	// Send the request to the daemon.
+	response, err := sendRequest(api.TypeSetBackupSchedule, request)
+	if err != nil {
+		return err
+	}
+
+	// Map the response data to the custom type.
+	var res api.ResponseSetBackupSchedule
+	if err = response.MapTo(&res); err != nil {
+		return err
+	}
+
+	// Print the data in the requested output format.
+	return printOutput(res, func() {
+		fmt.Println()
+		printc("Backup Schedule", res.Schedule)
+		if res.NextBackup > 0 {
+			printc("Next Backup", time.Unix(res.NextBackup, 0).Format(time.Stamp))
+		}
+		flush()
+		fmt.Println()
+	})
+}
@@ -28,7 +28,7 @@ import (
 
 func init() {
 	// Add this command.
-	AddCommand("setup", new(CmdSetup))
+	AddCommand("setup", new(CmdSetup), api.TypeSetupGet, api.TypeSetupSet)
 }
 
 type CmdSetup struct{}
@@ -38,11 +38,30 @@ func (c CmdSetup) Help() string {
 }
 
 func (c CmdSetup) PrintUsage() {
-	fmt.Println("Usage: setup APP")
+	fmt.Println("Usage: setup APP [--env NAME=VALUE]... [--port CONTAINER:PORT[/PROTOCOL]=HOST_PORT]... [--limit CONTAINER:KEY=VALUE]... [--restart KEY=VALUE]... [--yes]")
 	fmt.Printf("\n%s\n", c.Help())
+	fmt.Println("The values are requested interactively if no flags are passed.")
+	fmt.Println("Pass ! or 0 as host port to disable a port.")
+	fmt.Println("Resource limits overwrite the Turtlefile limits and are only set with the limit flag. Pass an empty value to reset a limit.")
+	fmt.Println("Available limit keys: " + strings.Join(limitKeys, ", "))
+	fmt.Println("The restart policy overwrites the Turtlefile policy and is only set with the restart flag.")
+	fmt.Println("Available restart keys: Policy (never, on-failure or always), MaxRetries, Window, Backoff, MaxBackoff")
 }
 
 func (c CmdSetup) Run(args []string) error {
+	// Parse the flags.
+	var envFlags, portFlags, limitFlags, restartFlags stringList
+	f := newFlagSet("setup")
+	f.Var(&envFlags, "env", "")
+	f.Var(&portFlags, "port", "")
+	f.Var(&limitFlags, "limit", "")
+	f.Var(&restartFlags, "restart", "")
+
+	args, err := parseFlags(f, args)
+	if err != nil {
+		return err
+	}
+
 	// Check if an argument is passed.
 	if len(args) != 1 {
 		return errInvalidUsage
@@ -71,6 +90,40 @@ func (c CmdSetup) Run(args []string) error {
 		return err
 	}
 
+	// Set the values from the flags or ask the user.
+	if f.NFlag() > 0 {
+		err = c.applyFlags(&setup, envFlags, portFlags, limitFlags, restartFlags)
+	} else {
+		err = c.readValues(&setup)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Confirm the request.
+	if !confirmCommit() {
+		return nil
+	}
+
+	// Create a new setup request.
+	sRequest := api.RequestSetupSet{
+		Name:  appName,
+		Setup: setup,
+	}
+
+	// Send the setup request to the daemon.
+	_, err = sendRequest(api.TypeSetupSet, sRequest)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Successfully configured app.")
+
+	return nil
+}
+
+// readValues requests the setup values from the user.
+func (c CmdSetup) readValues(setup *api.Setup) error {
 	// Get the environement values from the user.
 	for _, env := range setup.Env {
 		var defaultValue string
@@ -175,24 +228,220 @@ func (c CmdSetup) Run(args []string) error {
 		}
 	}
 
-	// Confirm the request.
-	if !confirmCommit() {
-		return nil
+	return nil
+}
+
+// applyFlags sets the setup values passed as flags.
+func (c CmdSetup) applyFlags(setup *api.Setup, envFlags, portFlags, limitFlags, restartFlags []string) error {
+	// Set the environment values.
+	for _, e := range envFlags {
+		pos := strings.Index(e, "=")
+		if pos <= 0 {
+			return fmt.Errorf("invalid environment value '%s': expected NAME=VALUE", e)
+		}
+		name, value := e[:pos], e[pos+1:]
+
+		found := false
+		for _, env := range setup.Env {
+			if env.Name == name {
+				env.Value = value
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown environment variable '%s'", name)
+		}
 	}
 
-	// Create a new setup request.
-	sRequest := api.RequestSetupSet{
-		Name:  appName,
-		Setup: setup,
+	// Set the host ports.
+	for _, p := range portFlags {
+		container, port, protocol, hostPort, err := parsePortFlag(p)
+		if err != nil {
+			return err
+		}
+
+		found := false
+		for _, sp := range setup.Ports {
+			if sp.Container == container && sp.Port == port && sp.Protocol == protocol {
+				sp.HostPort = hostPort
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown port '%s'", p)
+		}
 	}
 
-	// Send the setup request to the daemon.
-	_, err = sendRequest(api.TypeSetupSet, sRequest)
+	// Set the resource limits.
+	for _, l := range limitFlags {
+		if err := applyLimitFlag(setup, l); err != nil {
+			return err
+		}
+	}
+
+	// Set the restart policy.
+	for _, r := range restartFlags {
+		if err := applyRestartFlag(setup, r); err != nil {
+			return err
+		}
+	}
+
+	// Check if all required values are set.
+	for _, env := range setup.Env {
+		if env.Required && len(env.Value) == 0 {
+			return fmt.Errorf("the environment variable '%s' is required!", env.Name)
+		}
+	}
+
+	return nil
+}
+
+// parsePortFlag parses a port value in the form of CONTAINER:PORT[/PROTOCOL]=HOST_PORT.
+func parsePortFlag(v string) (container string, port int, protocol string, hostPort int, err error) {
+	invalid := fmt.Errorf("invalid port value '%s': expected CONTAINER:PORT[/PROTOCOL]=HOST_PORT", v)
+
+	pos := strings.Index(v, "=")
+	if pos < 0 {
+		return "", 0, "", 0, invalid
+	}
+	target, host := v[:pos], v[pos+1:]
+
+	// Parse the host port. A disabled port is set to 0.
+	if host != "!" {
+		hostPort, err = strconv.Atoi(host)
+		if err != nil || hostPort < 0 {
+			return "", 0, "", 0, invalid
+		}
+	}
+
+	pos = strings.Index(target, ":")
+	if pos <= 0 {
+		return "", 0, "", 0, invalid
+	}
+	container, target = target[:pos], target[pos+1:]
+
+	// The protocol is optional.
+	protocol = "tcp"
+	if pos = strings.Index(target, "/"); pos >= 0 {
+		protocol, target = target[pos+1:], target[:pos]
+	}
+
+	port, err = strconv.Atoi(target)
 	if err != nil {
-		return err
+		return "", 0, "", 0, invalid
 	}
 
-	fmt.Println("Successfully configured app.")
+	return container, port, protocol, hostPort, nil
+}
+
+// limitKeys are the available resource limit keys of the limit flag.
+var limitKeys = []string{"Memory", "MemorySwap", "CPUShares", "CPUSet", "CPUQuota", "CPUPeriod", "PidsLimit", "BlkioWeight"}
+
+// applyLimitFlag sets a resource limit in the form of CONTAINER:KEY=VALUE.
+func applyLimitFlag(setup *api.Setup, v string) error {
+	invalid := fmt.Errorf("invalid limit value '%s': expected CONTAINER:KEY=VALUE", v)
+
+	pos := strings.Index(v, "=")
+	if pos < 0 {
+		return invalid
+	}
+	target, value := v[:pos], strings.TrimSpace(v[pos+1:])
+
+	pos = strings.Index(target, ":")
+	if pos <= 0 {
+		return invalid
+	}
+	container, key := target[:pos], target[pos+1:]
+
+	// Obtain the container resource limits.
+	var r *api.ResourceLimits
+	for _, sr := range setup.Resources {
+		if sr.Container == container {
+			r = sr
+			break
+		}
+	}
+	if r == nil {
+		return fmt.Errorf("unknown container '%s'", container)
+	}
+
+	// Parse the integer value. An empty value resets the limit.
+	var i int64
+	parseInt := func() (err error) {
+		if len(value) == 0 {
+			return nil
+		}
+		i, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s value '%s'", key, value)
+		}
+		return nil
+	}
+
+	var err error
+	switch strings.ToLower(key) {
+	case "memory":
+		r.Memory = value
+	case "memoryswap":
+		r.MemorySwap = value
+	case "cpuset":
+		r.CPUSet = value
+	case "cpushares":
+		err = parseInt()
+		r.CPUShares = i
+	case "cpuquota":
+		err = parseInt()
+		r.CPUQuota = i
+	case "cpuperiod":
+		err = parseInt()
+		r.CPUPeriod = i
+	case "pidslimit":
+		err = parseInt()
+		r.PidsLimit = i
+	case "blkioweight":
+		err = parseInt()
+		r.BlkioWeight = i
+	default:
+		return fmt.Errorf("unknown limit key '%s'", key)
+	}
+
+	return err
+}
+
+// applyRestartFlag sets a restart policy value in the form of KEY=VALUE.
+// An empty value resets the value to the Turtlefile value.
+func applyRestartFlag(setup *api.Setup, v string) error {
+	pos := strings.Index(v, "=")
+	if pos <= 0 {
+		return fmt.Errorf("invalid restart value '%s': expected KEY=VALUE", v)
+	}
+	key, value := v[:pos], strings.TrimSpace(v[pos+1:])
+
+	p := &setup.RestartPolicy
+
+	switch strings.ToLower(key) {
+	case "policy":
+		p.Policy = value
+	case "maxretries":
+		p.MaxRetries = 0
+		if len(value) > 0 {
+			i, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid MaxRetries value '%s'", value)
+			}
+			p.MaxRetries = i
+		}
+	case "window":
+		p.Window = value
+	case "backoff":
+		p.Backoff = value
+	case "maxbackoff":
+		p.MaxBackoff = value
+	default:
+		return fmt.Errorf("unknown restart key '%s'", key)
+	}
 
 	return nil
 }
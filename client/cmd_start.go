@@ -27,7 +27,7 @@ import (
 
 func init() {
 	// Add this command.
-	AddCommand("start", new(CmdStart))
+	AddCommand("start", new(CmdStart), api.TypeStart)
 }
 
 type CmdStart struct{}
@@ -0,0 +1,183 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/desertbit/turtle/api"
+)
+
+const (
+	sparklineWidth = 40
+)
+
+var (
+	sparklineChars = []rune("▁▂▃▄▅▆▇█")
+)
+
+func init() {
+	// Add this command.
+	AddCommand("stats", new(CmdStats), api.TypeMetrics)
+}
+
+type CmdStats struct{}
+
+func (c CmdStats) Help() string {
+	return "Show the resource usage of an app."
+}
+
+func (c CmdStats) PrintUsage() {
+	fmt.Println("Usage: stats APP")
+	fmt.Printf("\n%s\n", c.Help())
+	fmt.Println("The trends show the average, the maximum and the history of each value.")
+}
+
+func (c CmdStats) Run(args []string) error {
+	// Check if an argument is passed.
+	if len(args) != 1 {
+		return errInvalidUsage
+	}
+
+	// Obtain the app name.
+	appName := strings.TrimSpace(args[0])
+	if len(appName) == 0 {
+		return fmt.Errorf("invalid app name passed.")
+	}
+
+	// Create a new request.
+	request := api.RequestMetrics{
+		Name: appName,
+	}
+
+	// Send the request to the daemon.
+	response, err := sendRequest(api.TypeMetrics, request)
+	if err != nil {
+		return err
+	}
+
+	// Map the response data to the metrics value.
+	var d api.ResponseMetrics
+	if err = response.MapTo(&d); err != nil {
+		return err
+	}
+
+	// Print the data in the requested output format.
+	return printOutput(d, func() {
+		if len(d.History) == 0 {
+			fmt.Println("No resource usage samples available yet.")
+			return
+		}
+
+		// Print new lines and a header.
+		println("\nContainers:\n===========")
+
+		if len(d.Containers) == 0 {
+			println("No running containers.")
+		} else {
+			printc("NAME", "CPU", "MEMORY", "NET RX", "NET TX")
+			for _, cm := range d.Containers {
+				printc(cm.Name, formatPercent(cm.CPUPercent), formatMemory(cm.MemoryUsage, cm.MemoryLimit),
+					formatBytes(cm.NetworkRx), formatBytes(cm.NetworkTx))
+			}
+		}
+
+		// Print new lines and a header.
+		first := time.Unix(d.History[0].Time, 0)
+		println(fmt.Sprintf("\nTrends since %s (every %s):\n=============", first.Format(time.Stamp), d.Interval))
+
+		printc("", "CURRENT", "AVERAGE", "MAXIMUM", "HISTORY")
+		printTrend(d.History, "CPU", formatPercent, func(s api.ResponseMetricsSample) float64 { return s.CPUPercent })
+		printTrend(d.History, "Memory", formatFloatBytes, func(s api.ResponseMetricsSample) float64 { return float64(s.MemoryUsage) })
+		printTrend(d.History, "Net RX", formatRate, func(s api.ResponseMetricsSample) float64 { return s.NetworkRxRate })
+		printTrend(d.History, "Net TX", formatRate, func(s api.ResponseMetricsSample) float64 { return s.NetworkTxRate })
+		printTrend(d.History, "Storage", formatFloatBytes, func(s api.ResponseMetricsSample) float64 { return float64(s.Storage) })
+		printTrend(d.History, "Backups", formatFloatBytes, func(s api.ResponseMetricsSample) float64 { return float64(s.BackupsStorage) })
+
+		// Flush the output.
+		flush()
+
+		// Print a new empty line.
+		fmt.Println()
+	})
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// printTrend prints the current, average and maximum value and a sparkline of the history.
+func printTrend(history []api.ResponseMetricsSample, name string, format func(float64) string, value func(api.ResponseMetricsSample) float64) {
+	var sum, max float64
+	values := make([]float64, len(history))
+
+	for i, s := range history {
+		v := value(s)
+		values[i] = v
+		sum += v
+		if v > max {
+			max = v
+		}
+	}
+
+	current := values[len(values)-1]
+	avg := sum / float64(len(values))
+
+	printc(name, format(current), format(avg), format(max), sparkline(values, max))
+}
+
+// sparkline creates a sparkline of the last values.
+func sparkline(values []float64, max float64) string {
+	if len(values) > sparklineWidth {
+		values = values[len(values)-sparklineWidth:]
+	}
+
+	line := make([]rune, len(values))
+	for i, v := range values {
+		index := 0
+		if max > 0 {
+			index = int(v / max * float64(len(sparklineChars)-1))
+		}
+		line[i] = sparklineChars[index]
+	}
+
+	return string(line)
+}
+
+func formatPercent(v float64) string {
+	return fmt.Sprintf("%.1f%%", v)
+}
+
+func formatFloatBytes(v float64) string {
+	return formatBytes(uint64(v))
+}
+
+func formatRate(v float64) string {
+	return formatBytes(uint64(v)) + "/s"
+}
+
+// formatMemory formats the memory usage and the limit if present.
+func formatMemory(usage, limit uint64) string {
+	if limit == 0 {
+		return formatBytes(usage)
+	}
+
+	return formatBytes(usage) + " / " + formatBytes(limit)
+}
@@ -27,7 +27,7 @@ import (
 
 func init() {
 	// Add this command.
-	AddCommand("stop", new(CmdStop))
+	AddCommand("stop", new(CmdStop), api.TypeStop)
 }
 
 type CmdStop struct{}
@@ -27,7 +27,7 @@ import (
 
 func init() {
 	// Add this command.
-	AddCommand("update", new(CmdUpdate))
+	AddCommand("update", new(CmdUpdate), api.TypeUpdate)
 }
 
 type CmdUpdate struct{}
@@ -37,11 +37,29 @@ func (c CmdUpdate) Help() string {
 }
 
 func (c CmdUpdate) PrintUsage() {
-	fmt.Println("Usage: update APP")
+	fmt.Println("Usage: update APP [--safe] [--revision REVISION] [--unpin]")
 	fmt.Printf("\n%s\n", c.Help())
+	fmt.Println("\nAvailable flags:")
+	printc(cmdIndent+"--safe", "Verify the update with a temporary clone before the app is replaced. Roll back if the updated app fails.")
+	printc(cmdIndent+"--revision REVISION", "Pin the app to a git tag or commit.")
+	printc(cmdIndent+"--unpin", "Follow the app branch again.")
+	flush()
 }
 
 func (c CmdUpdate) Run(args []string) error {
+	// Parse the flags.
+	var safe, unpin bool
+	var revision string
+	f := newFlagSet("update")
+	f.BoolVar(&safe, "safe", false, "")
+	f.BoolVar(&unpin, "unpin", false, "")
+	f.StringVar(&revision, "revision", "", "")
+
+	args, err := parseFlags(f, args)
+	if err != nil {
+		return err
+	}
+
 	// Check if an argument is passed.
 	if len(args) != 1 {
 		return errInvalidUsage
@@ -51,17 +69,26 @@ func (c CmdUpdate) Run(args []string) error {
 	appName := strings.TrimSpace(args[0])
 	if len(appName) == 0 {
 		return fmt.Errorf("invalid app name passed.")
+	} else if unpin && len(revision) > 0 {
+		return fmt.Errorf("the revision and unpin flags can't be combined.")
 	}
 
-	fmt.Printf("Started process: update app %s.\n", appName)
+	if safe {
+		fmt.Printf("Started process: safe update app %s. Check the progress with info.\n", appName)
+	} else {
+		fmt.Printf("Started process: update app %s.\n", appName)
+	}
 
 	// Create a new request.
 	request := api.RequestUpdate{
-		Name: appName,
+		Name:     appName,
+		Safe:     safe,
+		Revision: strings.TrimSpace(revision),
+		Unpin:    unpin,
 	}
 
 	// Send the request to the daemon.
-	_, err := sendRequest(api.TypeUpdate, request)
+	_, err = sendRequest(api.TypeUpdate, request)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,160 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Environment variables to configure the daemon connection.
+	envSocket    = "TURTLE_SOCKET"
+	envHost      = "TURTLE_HOST"
+	envPort      = "TURTLE_PORT"
+	envToken     = "TURTLE_TOKEN"
+	envTokenFile = "TURTLE_TOKEN_FILE"
+	envTLS       = "TURTLE_TLS"
+	envCAFile    = "TURTLE_CA_FILE"
+	envCertFile  = "TURTLE_CERT_FILE"
+	envKeyFile   = "TURTLE_KEY_FILE"
+
+	defaultTokenFilename = ".turtle-token"
+	defaultSocketPath    = "/turtle/turtle/turtle.sock"
+)
+
+var (
+	// Network
+	host string = "127.0.0.1"
+	port string = "28239"
+
+	// The API token which is sent with each request.
+	token string
+
+	// The URL of the daemon.
+	daemonURL string
+
+	// The HTTP client used for all requests.
+	httpClient = &http.Client{}
+)
+
+// initConnection loads the connection settings from the environment.
+// The local unix socket is used by default if present and no host is set.
+func initConnection() error {
+	// Obtain the unix socket path.
+	socketPath := os.Getenv(envSocket)
+	if len(socketPath) == 0 {
+		socketPath = defaultSocketPath
+	}
+
+	// Connect to the unix socket if no remote host is set.
+	if len(os.Getenv(envHost)) == 0 {
+		if _, err := os.Stat(socketPath); err == nil {
+			daemonURL = "http://unix"
+			httpClient = &http.Client{
+				Transport: &http.Transport{
+					Dial: func(network, addr string) (net.Conn, error) {
+						return net.Dial("unix", socketPath)
+					},
+				},
+			}
+
+			// Local requests don't require a token.
+			return nil
+		}
+	}
+
+	// Set the remote host and port if defined.
+	if h := os.Getenv(envHost); len(h) > 0 {
+		host = h
+	}
+	if p := os.Getenv(envPort); len(p) > 0 {
+		port = p
+	}
+
+	return initTCPConnection()
+}
+
+// initTCPConnection loads the token and TLS settings for a TCP connection.
+func initTCPConnection() error {
+	daemonURL = "http://" + host + ":" + port
+
+	// Obtain the API token.
+	// The environment variable is preferred over the token file.
+	token = os.Getenv(envToken)
+	if len(token) == 0 {
+		path := os.Getenv(envTokenFile)
+		if len(path) == 0 {
+			path = filepath.Join(os.Getenv("HOME"), defaultTokenFilename)
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read token file: %v", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+
+	// Skip if TLS is disabled.
+	if os.Getenv(envTLS) != "1" && os.Getenv(envTLS) != "true" {
+		return nil
+	}
+
+	daemonURL = "https://" + host + ":" + port
+	tlsConfig := &tls.Config{}
+
+	// Load the CA certificates to verify the daemon.
+	if caFile := os.Getenv(envCAFile); len(caFile) > 0 {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read CA file: %v", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("failed to load CA file: no valid certificates found")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	// Load the client certificate if set.
+	certFile, keyFile := os.Getenv(envCertFile), os.Getenv(envKeyFile)
+	if len(certFile) > 0 || len(keyFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	httpClient = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsConfig,
+		},
+	}
+
+	return nil
+}
@@ -0,0 +1,259 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+var (
+	// The output format of the current command.
+	// Set by the global --output flag.
+	outputFormat = outputTable
+
+	// The writer for machine-readable output.
+	outputWriter io.Writer = os.Stdout
+)
+
+//##############//
+//### Output ###//
+//##############//
+
+// printOutput prints the response data in the current output format.
+// The table function is called to print the human-readable table format.
+func printOutput(data interface{}, table func()) error {
+	switch outputFormat {
+	case outputJSON:
+		b, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(outputWriter, string(b))
+
+	case outputYAML:
+		b, err := toYAML(data)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(outputWriter, string(b))
+
+	default:
+		table()
+	}
+
+	return nil
+}
+
+// extractOutputFlag removes the global output flag from the arguments
+// and returns the output format.
+func extractOutputFlag(args []string) ([]string, string, error) {
+	format := outputTable
+	var filtered []string
+
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+
+		switch {
+		case a == "-o" || a == "--output" || a == "-output":
+			if i+1 >= len(args) {
+				return nil, "", errInvalidUsage
+			}
+			i++
+			format = args[i]
+		case strings.HasPrefix(a, "--output="):
+			format = strings.TrimPrefix(a, "--output=")
+		case strings.HasPrefix(a, "-output="):
+			format = strings.TrimPrefix(a, "-output=")
+		default:
+			filtered = append(filtered, a)
+			continue
+		}
+
+		if format != outputTable && format != outputJSON && format != outputYAML {
+			return nil, "", fmt.Errorf("invalid output format '%s': expected json, yaml or table", format)
+		}
+	}
+
+	return filtered, format, nil
+}
+
+//############//
+//### YAML ###//
+//############//
+
+// yamlField is a key value pair of an ordered YAML mapping.
+type yamlField struct {
+	key   string
+	value interface{}
+}
+
+// toYAML converts the value to YAML.
+// The value is first encoded to JSON to use the same field names
+// and the field order is preserved.
+func toYAML(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	node, err := decodeOrdered(decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, l := range yamlLines(node, 0) {
+		buf.WriteString(l + "\n")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeOrdered decodes the next JSON value and keeps the order of object keys.
+func decodeOrdered(d *json.Decoder) (interface{}, error) {
+	t, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := t.(json.Delim)
+	if !ok {
+		return t, nil
+	}
+
+	switch delim {
+	case '{':
+		fields := []yamlField{}
+		for d.More() {
+			k, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeOrdered(d)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, yamlField{key: fmt.Sprint(k), value: v})
+		}
+		_, err = d.Token() // Closing delimiter.
+		return fields, err
+
+	case '[':
+		list := []interface{}{}
+		for d.More() {
+			v, err := decodeOrdered(d)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		_, err = d.Token() // Closing delimiter.
+		return list, err
+	}
+
+	return nil, fmt.Errorf("unexpected JSON delimiter '%v'", delim)
+}
+
+// yamlLines returns the YAML lines of the node with the given indentation.
+func yamlLines(node interface{}, indent int) []string {
+	pad := strings.Repeat(" ", indent)
+
+	switch n := node.(type) {
+	case []yamlField:
+		if len(n) == 0 {
+			return []string{pad + "{}"}
+		}
+
+		var lines []string
+		for _, f := range n {
+			if isYAMLScalar(f.value) {
+				lines = append(lines, pad+f.key+": "+yamlScalar(f.value))
+				continue
+			}
+			lines = append(lines, pad+f.key+":")
+			lines = append(lines, yamlLines(f.value, indent+2)...)
+		}
+		return lines
+
+	case []interface{}:
+		if len(n) == 0 {
+			return []string{pad + "[]"}
+		}
+
+		var lines []string
+		for _, item := range n {
+			if isYAMLScalar(item) {
+				lines = append(lines, pad+"- "+yamlScalar(item))
+				continue
+			}
+
+			// Start the nested block on the same line as the dash.
+			sub := yamlLines(item, indent+2)
+			sub[0] = pad + "- " + sub[0][indent+2:]
+			lines = append(lines, sub...)
+		}
+		return lines
+
+	default:
+		return []string{pad + yamlScalar(n)}
+	}
+}
+
+// isYAMLScalar returns a boolean whenever the node is a scalar
+// or an empty mapping or list which is printed inline.
+func isYAMLScalar(node interface{}) bool {
+	switch n := node.(type) {
+	case []yamlField:
+		return len(n) == 0
+	case []interface{}:
+		return len(n) == 0
+	}
+	return true
+}
+
+// yamlScalar formats a scalar value.
+// Strings are double-quoted JSON strings, which are valid YAML.
+func yamlScalar(node interface{}) string {
+	switch n := node.(type) {
+	case nil:
+		return "null"
+	case string:
+		b, _ := json.Marshal(n)
+		return string(b)
+	case []yamlField:
+		return "{}"
+	case []interface{}:
+		return "[]"
+	}
+	return fmt.Sprint(node)
+}
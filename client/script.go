@@ -0,0 +1,161 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/desertbit/turtle/api"
+)
+
+const (
+	exitCodeSuccess          = 0
+	exitCodeError            = 1
+	exitCodeInvalidUsage     = 2
+	exitCodeAuthFailed       = 3
+	exitCodePermissionDenied = 4
+)
+
+var (
+	// Confirm all requests without asking the user.
+	// Set by the global --yes flag.
+	assumeYes bool
+
+	// Set if the user did not confirm a request.
+	commitAborted bool
+)
+
+//######################//
+//### Scripting mode ###//
+//######################//
+
+// runScript runs a single command passed as command line arguments
+// and returns the exit code.
+func runScript(args []string) int {
+	// Don't print any terminal colors.
+	disableColors()
+
+	cmd, err := runCommand(args[0], args[1:])
+	if err == nil && commitAborted {
+		fmt.Fprintln(os.Stderr, "error: request not confirmed. Pass --yes to confirm requests.")
+		return exitCodeError
+	} else if err == nil {
+		return exitCodeSuccess
+	}
+
+	fmt.Fprintf(os.Stderr, "error: %v\n", err)
+
+	if cmd == nil {
+		return exitCodeInvalidUsage
+	} else if err == errInvalidUsage {
+		fmt.Fprintln(os.Stderr)
+		cmd.PrintUsage()
+		return exitCodeInvalidUsage
+	}
+
+	// Map the API error codes.
+	if apiErr, ok := err.(*api.Error); ok {
+		switch apiErr.Code {
+		case api.ErrorCodeAuthFailed:
+			return exitCodeAuthFailed
+		case api.ErrorCodePermissionDenied:
+			return exitCodePermissionDenied
+		}
+	}
+
+	return exitCodeError
+}
+
+// disableColors removes all terminal color codes.
+func disableColors() {
+	colorInput = ""
+	colorPrompt = ""
+	colorOutput = ""
+	colorError = ""
+	colorHint = ""
+	colorReset = ""
+}
+
+//#############//
+//### Flags ###//
+//#############//
+
+// stringList is a flag value which can be passed multiple times.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// newFlagSet creates a new flag set for a command.
+// Errors are returned by parseFlags and not printed.
+func newFlagSet(name string) *flag.FlagSet {
+	f := flag.NewFlagSet(name, flag.ContinueOnError)
+	f.SetOutput(ioutil.Discard)
+	return f
+}
+
+// parseFlags parses the flags which may be mixed with positional
+// arguments and returns the positional arguments.
+func parseFlags(f *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+
+	for {
+		if err := f.Parse(args); err != nil {
+			return nil, errInvalidUsage
+		}
+
+		args = f.Args()
+		if len(args) == 0 {
+			break
+		}
+
+		// Add the next positional argument and continue parsing.
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	return positional, nil
+}
+
+// extractYesFlag removes the global yes flag from the arguments.
+// The boolean is true, if the flag was present.
+func extractYesFlag(args []string) ([]string, bool) {
+	var yes bool
+	var filtered []string
+
+	for _, a := range args {
+		if a == "-y" || a == "--yes" || a == "-yes" {
+			yes = true
+			continue
+		}
+		filtered = append(filtered, a)
+	}
+
+	return filtered, yes
+}
@@ -23,6 +23,8 @@ import (
 	"os"
 	"strings"
 	"text/tabwriter"
+
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 var (
@@ -63,6 +65,22 @@ func printc(a ...interface{}) {
 	printf(f, a...)
 }
 
+// formatBytes formats a byte value with a binary unit.
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
 func flush() {
 	tabWriterStdout.Flush()
 }
@@ -87,8 +105,62 @@ func readline(defaultValue ...string) (string, error) {
 	return in, nil
 }
 
+// readPassword asks the user for a password.
+// The password has to be repeated if confirm is set.
+func readPassword(confirm bool) (string, error) {
+	password, err := readSecret("Password: ")
+	if err != nil {
+		return "", err
+	} else if len(password) == 0 {
+		return "", fmt.Errorf("the password is required.")
+	}
+
+	if confirm {
+		repeated, err := readSecret("Repeat password: ")
+		if err != nil {
+			return "", err
+		} else if repeated != password {
+			return "", fmt.Errorf("the passwords don't match.")
+		}
+	}
+
+	return password, nil
+}
+
+// readSecret prints the prompt and reads a line from stdin.
+// The input is not echoed if stdin is a terminal.
+func readSecret(prompt string) (string, error) {
+	fmt.Print(prompt)
+
+	// Fallback to a plain line if the secret is piped to stdin.
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return readline()
+	}
+
+	secret, err := terminal.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(secret)), nil
+}
+
 // confirmCommit asks the user to confirm his request.
-func confirmCommit() bool {
+// The request is confirmed automatically if the yes flag is set.
+func confirmCommit() (confirmed bool) {
+	if assumeYes {
+		return true
+	}
+
+	// Remember the abort for the scripting mode exit code.
+	defer func() {
+		if !confirmed {
+			commitAborted = true
+		}
+	}()
+
 	for {
 		// Get the app name.
 		fmt.Print("Continue and commit the request? (y/n): ")
@@ -107,3 +179,11 @@ func confirmCommit() bool {
 		fmt.Println("invalid option!")
 	}
 }
+
+// shortCommit abbreviates a git commit hash.
+func shortCommit(commit string) string {
+	if len(commit) > 10 {
+		return commit[:10]
+	}
+	return commit
+}
@@ -0,0 +1,251 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/desertbit/turtle/api"
+	"github.com/desertbit/turtle/daemon/config"
+	"github.com/desertbit/turtle/utils"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	// Wildcard matches all permissions or apps.
+	accessWildcard = "*"
+)
+
+var (
+	// Request types which are not bound to a specific app.
+	globalRequestTypes = []api.Type{
+		api.TypeList,
+		api.TypeConfig,
+		api.TypeAddHostFingerprint,
+		api.TypeHostFingerprintInfo,
+		api.TypePermissions,
+		api.TypePrometheus,
+		api.TypeAddRegistry,
+		api.TypeListRegistries,
+		api.TypeRemoveRegistry,
+	}
+)
+
+//#########################//
+//### Access group type ###//
+//#########################//
+
+type accessGroups struct {
+	Groups []*accessGroup `toml:"Group"`
+}
+
+type accessGroup struct {
+	Name        string
+	Users       []string // The user names of the group members.
+	Permissions []string // The allowed request types. Use * to allow all requests.
+	Apps        []string // The apps the group can access. Use * to allow all apps.
+}
+
+//##########################//
+//### Access rights type ###//
+//##########################//
+
+// access holds the merged permissions of all groups of a user.
+type access struct {
+	user     string
+	groups   []string
+	allTypes bool
+	types    map[api.Type]bool
+	allApps  bool
+	apps     map[string]bool
+}
+
+// newFullAccess returns an access value which allows everything.
+func newFullAccess(user string) *access {
+	return &access{
+		user:     user,
+		allTypes: true,
+		allApps:  true,
+	}
+}
+
+// isTypeAllowed returns a boolean whenever the request type is allowed.
+func (a *access) isTypeAllowed(t api.Type) bool {
+	// The permissions request is always allowed.
+	if t == api.TypePermissions {
+		return true
+	}
+	return a.allTypes || a.types[t]
+}
+
+// isAppAllowed returns a boolean whenever the app can be accessed.
+func (a *access) isAppAllowed(name string) bool {
+	return a.allApps || a.apps[name]
+}
+
+// check returns an error if the request is not permitted.
+func (a *access) check(request *api.Request) error {
+	if !a.isTypeAllowed(request.Type) {
+		return api.NewError(api.ErrorCodePermissionDenied, "permission denied: user '%s' is not allowed to perform '%s' requests", a.user, request.Type)
+	}
+
+	// Skip the app scope check for global requests.
+	for _, t := range globalRequestTypes {
+		if t == request.Type {
+			return nil
+		}
+	}
+
+	// Obtain the app name from the request.
+	var data struct {
+		Name string
+	}
+	if err := request.MapTo(&data); err != nil {
+		return err
+	}
+
+	if !a.isAppAllowed(data.Name) {
+		return api.NewError(api.ErrorCodePermissionDenied, "permission denied: user '%s' is not allowed to access app '%s'", a.user, data.Name)
+	}
+
+	return nil
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// getAccess returns the merged permissions of all groups of the user.
+// All users have full access, if no groups file exists.
+// The local unix socket user has always full access.
+func getAccess(user string) (*access, error) {
+	if user == localUser {
+		return newFullAccess(user), nil
+	}
+
+	g, exists, err := loadAccessGroups()
+	if err != nil {
+		return nil, err
+	} else if !exists {
+		return newFullAccess(user), nil
+	}
+
+	a := &access{
+		user:  user,
+		types: make(map[api.Type]bool),
+		apps:  make(map[string]bool),
+	}
+
+	for _, group := range g.Groups {
+		// Skip if the user is not a member of the group.
+		isMember := false
+		for _, u := range group.Users {
+			if u == user {
+				isMember = true
+				break
+			}
+		}
+		if !isMember {
+			continue
+		}
+
+		a.groups = append(a.groups, group.Name)
+
+		for _, p := range group.Permissions {
+			if p == accessWildcard {
+				a.allTypes = true
+			} else {
+				a.types[api.Type(p)] = true
+			}
+		}
+
+		for _, app := range group.Apps {
+			if app == accessWildcard {
+				a.allApps = true
+			} else {
+				a.apps[app] = true
+			}
+		}
+	}
+
+	return a, nil
+}
+
+// loadAccessGroups loads the groups file.
+// The boolean is false if the file does not exists.
+func loadAccessGroups() (*accessGroups, bool, error) {
+	var g accessGroups
+
+	path := config.Config.GroupsFilePath()
+
+	// Skip if it does not exists.
+	e, err := utils.Exists(path)
+	if err != nil {
+		return nil, false, err
+	} else if !e {
+		return &g, false, nil
+	}
+
+	// Load and decode the file.
+	_, err = toml.DecodeFile(path, &g)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load groups file '%s': %v", path, err)
+	}
+
+	// Validate.
+	for _, group := range g.Groups {
+		if len(group.Name) == 0 {
+			return nil, false, fmt.Errorf("groups file '%s': group name is empty!", path)
+		}
+	}
+
+	return &g, true, nil
+}
+
+// handlePermissions sends the permissions of the requesting user.
+func handlePermissions(request *api.Request, a *access) (interface{}, error) {
+	res := api.ResponsePermissions{
+		User:     a.user,
+		Groups:   a.groups,
+		AllTypes: a.allTypes,
+		AllApps:  a.allApps,
+	}
+
+	for t := range a.types {
+		res.Types = append(res.Types, t)
+	}
+	for app := range a.apps {
+		res.Apps = append(res.Apps, app)
+	}
+
+	// Sort the slices for a stable output.
+	sort.Sort(byType(res.Types))
+	sort.Strings(res.Apps)
+
+	return res, nil
+}
+
+// byType implements sort.Interface to sort request types.
+type byType []api.Type
+
+func (s byType) Len() int           { return len(s) }
+func (s byType) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byType) Less(i, j int) bool { return s[i] < s[j] }
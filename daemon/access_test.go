@@ -0,0 +1,182 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/desertbit/turtle/api"
+	"github.com/desertbit/turtle/daemon/config"
+)
+
+const testGroups = `
+[[Group]]
+Name = "admin"
+Users = ["alice"]
+Permissions = ["*"]
+Apps = ["*"]
+
+[[Group]]
+Name = "shop-team"
+Users = ["bob", "carol"]
+Permissions = ["list", "info", "start", "logs"]
+Apps = ["shop"]
+
+[[Group]]
+Name = "blog-team"
+Users = ["carol"]
+Permissions = ["stop"]
+Apps = ["blog"]
+`
+
+// writeGroupsFile writes the access groups file to the turtle path.
+func writeGroupsFile(t *testing.T, content string) {
+	err := ioutil.WriteFile(config.Config.GroupsFilePath(), []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newAppRequest returns a request of the type for the app.
+func newAppRequest(t api.Type, app string) *api.Request {
+	return &api.Request{
+		Type: t,
+		Data: map[string]interface{}{"Name": app},
+	}
+}
+
+// expectPermissionDenied checks if the error is a permission error.
+func expectPermissionDenied(t *testing.T, err error, context string) {
+	if apiErr, ok := err.(*api.Error); !ok || apiErr.Code != api.ErrorCodePermissionDenied {
+		t.Errorf("%s: expected a permission error, got: %v", context, err)
+	}
+}
+
+func TestAccessWithoutGroups(t *testing.T) {
+	defer useTempTurtlePath(t)()
+
+	// All users have full access without a groups file.
+	a, err := getAccess("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = a.check(newAppRequest(api.TypeRemove, "shop")); err != nil {
+		t.Errorf("expected full access: %v", err)
+	}
+}
+
+func TestAccessGroups(t *testing.T) {
+	defer useTempTurtlePath(t)()
+	writeGroupsFile(t, testGroups)
+
+	// The admin may do everything.
+	a, err := getAccess("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.check(newAppRequest(api.TypeRemove, "blog")); err != nil {
+		t.Errorf("alice: %v", err)
+	}
+
+	// Bob is limited to some request types of the shop app.
+	a, err = getAccess("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = a.check(newAppRequest(api.TypeStart, "shop")); err != nil {
+		t.Errorf("bob: start shop: %v", err)
+	}
+	expectPermissionDenied(t, a.check(newAppRequest(api.TypeStop, "shop")), "bob: stop shop")
+	expectPermissionDenied(t, a.check(newAppRequest(api.TypeStart, "blog")), "bob: start blog")
+
+	// Global requests are not bound to an app.
+	if err = a.check(&api.Request{Type: api.TypeList}); err != nil {
+		t.Errorf("bob: list: %v", err)
+	}
+	expectPermissionDenied(t, a.check(&api.Request{Type: api.TypeConfig}), "bob: config")
+
+	// The permissions are always accessible.
+	if err = a.check(&api.Request{Type: api.TypePermissions}); err != nil {
+		t.Errorf("bob: permissions: %v", err)
+	}
+
+	// Carol gets the merged permissions of both groups.
+	a, err = getAccess("carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(a.groups, []string{"shop-team", "blog-team"}) {
+		t.Errorf("carol: got groups %v", a.groups)
+	}
+	if err = a.check(newAppRequest(api.TypeStop, "blog")); err != nil {
+		t.Errorf("carol: stop blog: %v", err)
+	}
+	if err = a.check(newAppRequest(api.TypeLogs, "blog")); err != nil {
+		t.Errorf("carol: logs blog: %v", err)
+	}
+
+	// Users without a group have no access.
+	a, err = getAccess("eve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectPermissionDenied(t, a.check(newAppRequest(api.TypeInfo, "shop")), "eve: info shop")
+	expectPermissionDenied(t, a.check(&api.Request{Type: api.TypeList}), "eve: list")
+}
+
+func TestAccessInvalidGroups(t *testing.T) {
+	defer useTempTurtlePath(t)()
+
+	for _, content := range []string{"[[Group]]\nUsers = [\"bob\"]", "[[Group"} {
+		writeGroupsFile(t, content)
+		if _, err := getAccess("bob"); err == nil {
+			t.Errorf("'%s': expected an error", content)
+		}
+	}
+}
+
+func TestHandlePermissions(t *testing.T) {
+	defer useTempTurtlePath(t)()
+	writeGroupsFile(t, testGroups)
+
+	a, err := getAccess("carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := handlePermissions(&api.Request{Type: api.TypePermissions}, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := api.ResponsePermissions{
+		User:   "carol",
+		Groups: []string{"shop-team", "blog-team"},
+		Types:  []api.Type{api.TypeInfo, api.TypeList, api.TypeLogs, api.TypeStart, api.TypeStop},
+		Apps:   []string{"blog", "shop"},
+	}
+	if !reflect.DeepEqual(res, want) {
+		t.Errorf("got %+v, want %+v", res, want)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/desertbit/turtle/daemon/btrfs"
 	"github.com/desertbit/turtle/daemon/config"
@@ -59,6 +60,30 @@ type App struct {
 	taskErr   error
 	taskState string
 
+	// The temporary update clone refers to its original app.
+	origin *App
+
+	// The safe update state.
+	updating    bool
+	updateState string
+	updateErr   error
+	updateMutex sync.Mutex
+
+	// The automatic update state.
+	autoUpdating        bool
+	autoUpdateState     string
+	autoUpdateErr       error
+	lastAutoUpdateCheck time.Time
+	autoUpdateMutex     sync.Mutex
+
+	// This mutex is locked while the pinned backups file is accessed.
+	pinnedBackupsMutex sync.Mutex
+
+	// The automatic backup schedule.
+	nextBackup             time.Time // Zero if not calculated yet.
+	scheduledBackupRunning bool
+	backupScheduleMutex    sync.Mutex
+
 	//##
 	//## Run task values:
 	//##
@@ -69,12 +94,25 @@ type App struct {
 	checkRestartRunning bool
 	checkRestartError   chan error
 
+	restartTimes []time.Time // The automatic restarts within the restart policy window.
+	nextRestart  time.Time   // The time of the next pending restart. Zero if none.
+	restartMutex sync.Mutex
+
 	stopRequested           chan struct{}
 	stopRequestedChanExists bool
 	stopRequestedMutex      sync.Mutex
+
+	// The resource usage history.
+	metrics appMetrics
+
+	// The container health check states.
+	healthStops           map[string]chan struct{} // The key is the container ID.
+	healthErrors          map[healthCheckID]error
+	unhealthyContainerIDs map[string]bool
+	healthMutex           sync.Mutex
 }
 
-//  newApp creates a new app and sets the app directory path.
+// newApp creates a new app and sets the app directory path.
 // A turtle App name should not not contain any whitespaces.
 func newApp(name string) (*App, error) {
 	// Return an error if the name contains whitespaces.
@@ -94,6 +132,10 @@ func newApp(name string) (*App, error) {
 
 		task:      taskNone,
 		taskState: stateIdle,
+
+		healthStops:           make(map[string]chan struct{}),
+		healthErrors:          make(map[healthCheckID]error),
+		unhealthyContainerIDs: make(map[string]bool),
 	}, nil
 }
 
@@ -129,6 +171,23 @@ func (a *App) ContainerNamePrefix() string {
 	return docker.TurtlePrefix + a.name + "."
 }
 
+// containerImage returns the docker image name and tag of the container.
+// Locally built images are named after the container.
+// Temporary update clones use the update images of their original app.
+func (a *App) containerImage(c *turtlefile.Container) (name string, tag string) {
+	if a.origin != nil {
+		name, tag = a.origin.containerImage(c)
+		return name, docker.UpdateImageTag(a.origin.name, tag)
+	}
+
+	name = c.Image
+	if c.IsLocalBuild() {
+		name = a.ContainerNamePrefix() + c.Name
+	}
+
+	return name, c.Tag
+}
+
 // SourceURL returns the app's source url.
 func (a *App) SourceURL() string {
 	return a.settings.SourceURL
@@ -230,12 +289,14 @@ func (a *App) Remove(removeBackups bool) error {
 	// Abort if any app task is running.
 	if a.IsTaskRunning() {
 		return fmt.Errorf("the app is running!")
+	} else if a.IsUpdating() {
+		return fmt.Errorf("the app is updating!")
 	}
 
 	if !removeBackups {
 		// Create a backup first.
 		// Call the private method, because we locked the taskMutex already.
-		err := a.backup()
+		_, err := a.backup()
 		if err != nil {
 			return err
 		}
@@ -251,6 +312,16 @@ func (a *App) Remove(removeBackups bool) error {
 		}
 	}
 
+	// Remove the resource usage history.
+	if err = a.removeMetrics(); err != nil {
+		return fmt.Errorf("failed to remove app metrics: %v", err)
+	}
+
+	// Remove the captured container logs.
+	if err = os.RemoveAll(a.LogsDirectoryPath()); err != nil {
+		return fmt.Errorf("failed to remove app logs: %v", err)
+	}
+
 	func() {
 		// Lock the apps mutex.
 		appsMutex.Lock()
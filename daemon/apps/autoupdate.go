@@ -0,0 +1,279 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package apps
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/desertbit/turtle/daemon/config"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	AutoUpdateDisabled = "disabled"
+	AutoUpdatePoll     = "poll"
+	AutoUpdateWebhook  = "webhook"
+
+	minAutoUpdateInterval = time.Minute
+	webhookSecretLength   = 32
+)
+
+//##############//
+//### Public ###//
+//##############//
+
+// CheckAutoUpdates triggers an automatic update of all apps
+// polling their source, if their poll interval elapsed.
+func CheckAutoUpdates() {
+	for _, a := range Apps() {
+		mode, interval, _ := a.AutoUpdate()
+		if mode != AutoUpdatePoll {
+			continue
+		}
+
+		// Check if the poll interval elapsed.
+		a.autoUpdateMutex.Lock()
+		due := !a.autoUpdating && time.Since(a.lastAutoUpdateCheck) >= interval
+		a.autoUpdateMutex.Unlock()
+
+		if !due {
+			continue
+		}
+
+		if err := a.TriggerAutoUpdate(); err != nil {
+			log.Warningf("app '%s': automatic update: %v", a.name, err)
+		}
+	}
+}
+
+//##########################//
+//### Public App methods ###//
+//##########################//
+
+// AutoUpdate returns the automatic update mode, the poll interval
+// and whenever the updates are verified with a temporary clone.
+func (a *App) AutoUpdate() (mode string, interval time.Duration, safe bool) {
+	s := a.settings.AutoUpdate
+
+	mode = s.Mode
+	if len(mode) == 0 {
+		mode = AutoUpdateDisabled
+	}
+
+	interval = s.Interval.Duration
+	if interval == 0 {
+		interval = config.Config.AutoUpdateInterval
+	}
+
+	return mode, interval, s.Safe
+}
+
+// WebhookSecret returns the HMAC secret of the update webhook.
+// The secret is empty if the webhook is disabled.
+func (a *App) WebhookSecret() string {
+	if a.settings.AutoUpdate.Mode != AutoUpdateWebhook {
+		return ""
+	}
+
+	return a.settings.AutoUpdate.Secret
+}
+
+// SetAutoUpdate sets the automatic update mode. Pass a zero interval to use
+// the daemon default poll interval. A webhook secret is created if the webhook
+// is enabled and no secret exists or if newSecret is set.
+func (a *App) SetAutoUpdate(mode string, interval time.Duration, safe, newSecret bool) error {
+	if mode != AutoUpdateDisabled && mode != AutoUpdatePoll && mode != AutoUpdateWebhook {
+		return fmt.Errorf("invalid automatic update mode '%s'!", mode)
+	} else if interval != 0 && interval < minAutoUpdateInterval {
+		return fmt.Errorf("the poll interval has to be at least %v!", minAutoUpdateInterval)
+	}
+
+	s := &a.settings.AutoUpdate
+
+	// Create a new webhook secret if required.
+	if mode == AutoUpdateWebhook && (len(s.Secret) == 0 || newSecret) {
+		b := make([]byte, webhookSecretLength)
+		if _, err := rand.Read(b); err != nil {
+			return fmt.Errorf("failed to create random webhook secret: %v", err)
+		}
+		s.Secret = hex.EncodeToString(b)
+	}
+
+	s.Mode = mode
+	s.Interval.Duration = interval
+	s.Safe = safe
+
+	return a.saveSettings()
+}
+
+// AutoUpdateState returns the state of the current or last automatic update
+// check and its error. The state is empty if no check was performed.
+func (a *App) AutoUpdateState() (string, error) {
+	// Lock the mutex.
+	a.autoUpdateMutex.Lock()
+	defer a.autoUpdateMutex.Unlock()
+
+	return a.autoUpdateState, a.autoUpdateErr
+}
+
+// TriggerAutoUpdate checks the app source for new commits in the background.
+// If present, then a backup is created and the app is updated and restarted.
+func (a *App) TriggerAutoUpdate() error {
+	// Lock the mutex.
+	a.autoUpdateMutex.Lock()
+	defer a.autoUpdateMutex.Unlock()
+
+	if a.autoUpdating {
+		return fmt.Errorf("an automatic update is already running!")
+	}
+
+	// Set the flag and reset the previous state.
+	a.autoUpdating = true
+	a.autoUpdateState = "checking for updates..."
+	a.autoUpdateErr = nil
+	a.lastAutoUpdateCheck = time.Now()
+
+	go func() {
+		// Perform the actual update.
+		commit, err := autoUpdate(a)
+
+		// Lock the mutex.
+		a.autoUpdateMutex.Lock()
+		defer a.autoUpdateMutex.Unlock()
+
+		// Reset the flag and set the final state.
+		a.autoUpdating = false
+		if err != nil {
+			a.autoUpdateState = "failed"
+			a.autoUpdateErr = err
+			log.Errorf("app '%s': automatic update failed: %v", a.name, err)
+		} else if len(commit) > 0 {
+			a.autoUpdateState = "updated to commit " + commit
+			log.Infof("app '%s': automatically updated to commit '%s'.", a.name, commit)
+		} else {
+			a.autoUpdateState = "up to date"
+		}
+	}()
+
+	return nil
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// autoUpdate updates the app if the upstream source has new commits.
+// The app is restarted if it was running. The new commit is returned.
+// An empty commit is returned if the app is up to date.
+func autoUpdate(app *App) (string, error) {
+	// Skip apps which are busy with another task.
+	if app.IsUpdating() || (app.IsTaskRunning() && !app.IsRunning()) {
+		return "", fmt.Errorf("another task is running!")
+	}
+
+	path := app.SourceDirectoryPath()
+
+	// Fetch the upstream commits and tags.
+	err := fetchSource(path)
+	if err != nil {
+		return "", err
+	}
+
+	// Check if the pinned revision or the branch moved.
+	revision := "origin/" + app.settings.Branch
+	if len(app.settings.Pin) > 0 {
+		revision = app.settings.Pin
+	}
+
+	target, err := resolveRevision(path, revision)
+	if err != nil {
+		return "", err
+	}
+
+	commit, err := sourceCommit(path)
+	if err != nil {
+		return "", err
+	} else if commit == target {
+		return "", nil
+	}
+
+	app.setAutoUpdateState("updating to commit " + target + "...")
+	log.Infof("app '%s': automatic update to commit '%s'", app.name, target)
+
+	// Safe updates create the backup and restart the app on their own.
+	if _, _, safe := app.AutoUpdate(); safe {
+		if err = app.SafeUpdate(); err != nil {
+			return "", err
+		}
+
+		for app.IsUpdating() {
+			time.Sleep(updateCheckInterval)
+		}
+
+		_, err = app.UpdateState()
+		return target, err
+	}
+
+	// Stop the app if running.
+	wasRunning := app.IsRunning()
+	if err = stopAppTask(app); err != nil {
+		return "", err
+	}
+
+	// Create a backup and update the app.
+	err = app.Update()
+	if err == nil {
+		if err = waitForTask(app); err == nil {
+			err = app.Error()
+		}
+	}
+
+	// Start the app again, even if the update failed.
+	if wasRunning {
+		if errS := app.Start(); errS != nil {
+			if err != nil {
+				return "", fmt.Errorf("%v: failed to restart the app: %v", err, errS)
+			}
+			return "", fmt.Errorf("failed to restart the app: %v", errS)
+		}
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return target, nil
+}
+
+//###########################//
+//### Private App methods ###//
+//###########################//
+
+// setAutoUpdateState sets the automatic update state.
+func (a *App) setAutoUpdateState(s string) {
+	// Lock the mutex.
+	a.autoUpdateMutex.Lock()
+	defer a.autoUpdateMutex.Unlock()
+
+	a.autoUpdateState = s
+}
@@ -43,16 +43,17 @@ func (a *App) Backup() error {
 	defer a.taskMutex.Unlock()
 
 	// Perform the actual backup.
-	return a.backup()
+	_, err := a.backup()
+	return err
 }
 
-// backup the app data.
+// backup the app data and return the backup timestamp.
 // This method won't lock the taskMutex. You have to handle it!
-func (a *App) backup() error {
+func (a *App) backup() (string, error) {
 	// Don't backup during some special app tasks.
 	if a.task == taskCloneSource ||
 		a.task == taskUpdate {
-		return fmt.Errorf("can't backup app '%s' during an update task!", a.name)
+		return "", fmt.Errorf("can't backup app '%s' during an update task!", a.name)
 	}
 
 	// Get the app's base backup folder.
@@ -61,22 +62,48 @@ func (a *App) backup() error {
 	// Create the base app backup folder if not present.
 	err := utils.MkDirIfNotExists(backupPath)
 	if err != nil {
-		return fmt.Errorf("failed to backup app '%s': %v", a.name, err)
+		return "", fmt.Errorf("failed to backup app '%s': %v", a.name, err)
 	}
 
 	// Create a new backup directory with the current timestamp.
-	backupPath += "/" + strconv.FormatInt(time.Now().Unix(), 10)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	backupPath += "/" + timestamp
 
 	// Log
 	log.Infof("creating backup of app '%s': %s", a.name, backupPath)
 
-	// Create a snapshot of the complete app subvolume.
-	err = btrfs.Snapshot(a.path, backupPath, true)
+	// Obtain the backup hooks of the running containers.
+	hooks, err := a.newBackupHooks()
 	if err != nil {
-		return fmt.Errorf("failed to backup app '%s': %v", a.name, err)
+		return "", fmt.Errorf("failed to backup app '%s': %v", a.name, err)
 	}
 
-	return nil
+	// Prepare the containers for the snapshot.
+	start := time.Now()
+	err = hooks.pre()
+	if err == nil {
+		// Create a snapshot of the complete app subvolume.
+		err = btrfs.Snapshot(a.path, backupPath, true)
+	}
+
+	// Always resume the prepared containers.
+	hooks.post()
+
+	if err != nil {
+		return "", fmt.Errorf("failed to backup app '%s': %v", a.name, err)
+	}
+
+	a.setLastBackupDuration(time.Since(start))
+
+	// Record the hooks output with the backup.
+	if !hooks.empty() {
+		err = hooks.save(a.backupHookLogPath(timestamp))
+		if err != nil {
+			log.Warningf("app '%s': %v", a.name, err)
+		}
+	}
+
+	return timestamp, nil
 }
 
 // Backups returns a slice of all app backup timestamps.
@@ -97,21 +124,26 @@ func (a *App) Backups() ([]string, error) {
 		return nil, err
 	}
 
-	backups := make([]string, len(files))
+	var backups []string
 
 	// Get all the backup timestampts.
-	for i, f := range files {
+	for _, f := range files {
 		// Skip if not a directory.
 		if !f.IsDir() {
 			continue
 		}
 
-		backups[i] = f.Name()
+		backups = append(backups, f.Name())
 	}
 
 	return backups, nil
 }
 
+// BackupExists returns a boolean whenever the backup exists.
+func (a *App) BackupExists(timestamp string) bool {
+	return isValidBackupTimestamp(timestamp) && btrfs.IsSubvolume(a.BackupDirectoryPath()+"/"+timestamp)
+}
+
 // RemoveBackup removes the given backup.
 func (a *App) RemoveBackup(timestamp string) error {
 	// Create the backup directory path.
@@ -130,6 +162,21 @@ func (a *App) RemoveBackup(timestamp string) error {
 		return fmt.Errorf("failed to delete backup subvolume '%s': %v", timestamp, err)
 	}
 
+	// A new backup might be created with the same timestamp.
+	forgetSubvolumeID(path)
+
+	// Remove the pin of the removed backup.
+	err = a.PinBackup(timestamp, false)
+	if err != nil {
+		return err
+	}
+
+	// Remove the recorded backup hooks output.
+	err = a.removeBackupHookLog(timestamp)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -159,7 +206,16 @@ func (a *App) RemoveAllBackups() error {
 }
 
 // RestoreBackup restores the given app backup.
-func (a *App) RestoreBackup(timestamp string) (err error) {
+func (a *App) RestoreBackup(timestamp string) error {
+	if a.IsUpdating() {
+		return fmt.Errorf("the app is updating!")
+	}
+
+	return a.restoreBackup(timestamp)
+}
+
+// restoreBackup restores the given app backup.
+func (a *App) restoreBackup(timestamp string) (err error) {
 	// Lock the task mutex.
 	// The app should not be started during a backup process.
 	a.taskMutex.Lock()
@@ -0,0 +1,268 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package apps
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/desertbit/turtle/daemon/docker"
+	"github.com/desertbit/turtle/daemon/turtlefile"
+	"github.com/desertbit/turtle/utils"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	// The suffix of the file next to a backup containing the backup hooks output.
+	backupHookLogSuffix = ".hooks"
+)
+
+//##########################//
+//### Public App methods ###//
+//##########################//
+
+// BackupHookLog returns the recorded backup hooks output of the backup.
+// The log is empty if no backup hooks were executed.
+func (a *App) BackupHookLog(timestamp string) (string, error) {
+	if !a.BackupExists(timestamp) {
+		return "", fmt.Errorf("no backup '%s' found!", timestamp)
+	}
+
+	path := a.backupHookLogPath(timestamp)
+
+	// Skip if it does not exists.
+	e, err := utils.Exists(path)
+	if err != nil {
+		return "", err
+	} else if !e {
+		return "", nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read backup hook log: %v", err)
+	}
+
+	return string(data), nil
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// backupHook is a turtlefile backup hook bound to a running container.
+type backupHook struct {
+	*turtlefile.BackupHook
+
+	containerName string
+	containerID   string
+	prepared      bool // Set if the post hook has to run.
+}
+
+// backupHooks runs the backup hooks of the running app containers.
+type backupHooks struct {
+	appName string
+	hooks   []*backupHook
+	output  bytes.Buffer
+}
+
+// newBackupHooks obtains the backup hooks of the app.
+// No hooks are returned if the app is not running.
+// This method won't lock the taskMutex. You have to handle it!
+func (a *App) newBackupHooks() (*backupHooks, error) {
+	h := &backupHooks{
+		appName: a.name,
+	}
+
+	if !a.IsRunning() {
+		return h, nil
+	}
+
+	// Get the turtlefile.
+	t, err := a.Turtlefile()
+	if err != nil {
+		return nil, err
+	}
+
+	// The container IDs are sorted to the startup order of the turtlefile containers.
+	containerIDs := make([]string, len(a.containerIDs))
+	copy(containerIDs, a.containerIDs)
+	if len(containerIDs) != len(t.Containers) {
+		return nil, fmt.Errorf("app container IDs do not match the turtlefile containers!")
+	}
+
+	for i, c := range t.Containers {
+		for _, hook := range c.BackupHooks {
+			h.hooks = append(h.hooks, &backupHook{
+				BackupHook:    hook,
+				containerName: c.Name,
+				containerID:   containerIDs[i],
+			})
+		}
+	}
+
+	return h, nil
+}
+
+// empty returns a boolean whenever no hooks have to be executed.
+func (h *backupHooks) empty() bool {
+	return len(h.hooks) == 0
+}
+
+// pre runs the pre hooks in the container startup order.
+// An error is returned if a hook with the abort failure policy failed.
+// The post hooks have to be executed in any case.
+func (h *backupHooks) pre() error {
+	for _, hook := range h.hooks {
+		err := h.runPre(hook)
+		if err == nil {
+			continue
+		}
+
+		err = fmt.Errorf("container '%s': %s pre backup hook failed: %v", hook.containerName, hook.Type, err)
+		h.record("%v", err)
+
+		if hook.OnFailure == turtlefile.BackupHookOnFailureAbort {
+			return err
+		}
+
+		log.Warningf("app '%s': %v", h.appName, err)
+	}
+
+	return nil
+}
+
+// post runs the post hooks of the prepared containers in the reverse order.
+// Failures are only logged, because the snapshot was already taken.
+func (h *backupHooks) post() {
+	for i := len(h.hooks) - 1; i >= 0; i-- {
+		hook := h.hooks[i]
+		if !hook.prepared {
+			continue
+		}
+
+		err := h.runPost(hook)
+		if err != nil {
+			err = fmt.Errorf("container '%s': %s post backup hook failed: %v", hook.containerName, hook.Type, err)
+			h.record("%v", err)
+			log.Warningf("app '%s': %v", h.appName, err)
+		}
+	}
+}
+
+// save writes the recorded hooks output to the file.
+func (h *backupHooks) save(path string) error {
+	err := ioutil.WriteFile(path, h.output.Bytes(), 0600)
+	if err != nil {
+		return fmt.Errorf("failed to save backup hook log: %v", err)
+	}
+
+	return nil
+}
+
+func (h *backupHooks) runPre(hook *backupHook) error {
+	// Skip containers which are currently restarted.
+	if len(hook.containerID) == 0 {
+		h.record("container '%s': skipping %s pre backup hook: container is not running", hook.containerName, hook.Type)
+		return nil
+	}
+
+	if hook.Type == turtlefile.BackupHookPause {
+		h.record("container '%s': pausing container", hook.containerName)
+		err := docker.PauseContainer(hook.containerID)
+		if err != nil {
+			return err
+		}
+
+		hook.prepared = true
+		return nil
+	}
+
+	// The post command has to run even if the pre command failed,
+	// because the command might have been executed partially.
+	hook.prepared = true
+
+	if len(hook.Pre) == 0 {
+		return nil
+	}
+
+	return h.exec(hook, "pre", hook.Pre)
+}
+
+func (h *backupHooks) runPost(hook *backupHook) error {
+	if hook.Type == turtlefile.BackupHookPause {
+		h.record("container '%s': unpausing container", hook.containerName)
+		return docker.UnpauseContainer(hook.containerID)
+	}
+
+	if len(hook.Post) == 0 {
+		return nil
+	}
+
+	return h.exec(hook, "post", hook.Post)
+}
+
+// exec runs the hook command in the container and records its output.
+func (h *backupHooks) exec(hook *backupHook, stage string, cmd []string) error {
+	h.record("container '%s': running %s command: %s", hook.containerName, stage, strings.Join(cmd, " "))
+
+	start := time.Now()
+	exitCode, output, err := docker.Exec(hook.containerID, cmd, hook.Timeout.Duration)
+	if err != nil {
+		return err
+	}
+
+	// Record the command output.
+	if output = strings.TrimSpace(output); len(output) > 0 {
+		h.output.WriteString(output + "\n")
+	}
+
+	h.record("container '%s': %s command exited with code %v after %v", hook.containerName, stage, exitCode, time.Since(start))
+
+	if exitCode != 0 {
+		return fmt.Errorf("command exited with code %v", exitCode)
+	}
+
+	return nil
+}
+
+// record adds a timestamped line to the hooks output.
+func (h *backupHooks) record(format string, args ...interface{}) {
+	fmt.Fprintf(&h.output, "[%s] %s\n", time.Now().Format(time.RFC3339), fmt.Sprintf(format, args...))
+}
+
+// backupHookLogPath returns the path of the backup hooks output file.
+func (a *App) backupHookLogPath(timestamp string) string {
+	return a.BackupDirectoryPath() + "/" + timestamp + backupHookLogSuffix
+}
+
+// removeBackupHookLog removes the backup hooks output file if present.
+func (a *App) removeBackupHookLog(timestamp string) error {
+	err := os.Remove(a.backupHookLogPath(timestamp))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove backup hook log: %v", err)
+	}
+
+	return nil
+}
@@ -42,5 +42,11 @@ func taskFuncCloneSource(app *App) error {
 		return fmt.Errorf("failed to clone application source with git: %v", err)
 	}
 
-	return nil
+	// Record the deployed commit.
+	commit, err := sourceCommit(app.SourceDirectoryPath())
+	if err != nil {
+		return err
+	}
+
+	return app.setDeployedCommit(commit)
 }
@@ -24,8 +24,8 @@ import (
 	"strings"
 	"time"
 
-	"github.com/desertbit/turtle/daemon/config"
 	"github.com/desertbit/turtle/daemon/docker"
+	"github.com/desertbit/turtle/daemon/turtlefile"
 	"github.com/desertbit/turtle/utils"
 
 	log "github.com/Sirupsen/logrus"
@@ -35,8 +35,6 @@ import (
 const (
 	defaultContainerWaitAfterStartup = 300 * time.Millisecond
 	waitAfterAppStart                = 3 * time.Second
-
-	maximumRestartRetryCount = 3
 )
 
 //##########################//
@@ -54,6 +52,8 @@ func (a *App) Start() error {
 		return fmt.Errorf("app is already running!")
 	} else if !a.IsSetup() {
 		return fmt.Errorf("you have to setup the app first!")
+	} else if a.IsUpdating() {
+		return fmt.Errorf("app is updating!")
 	}
 
 	// Create a backup.
@@ -114,36 +114,12 @@ func (a *App) createStopChannel() {
 }
 
 func taskFuncRun(app *App) (err error) {
-	// Create a new  backup ticker
-	ticker := time.NewTicker(config.Config.BackupInterval)
-	stopBackupLoop := make(chan struct{})
-
-	defer func() {
-		// Stop the ticker
-		ticker.Stop()
+	// The app was backed up during the start.
+	// Start a new backup schedule.
+	app.resetBackupSchedule()
 
-		// Stop the loop by triggering the quit trigger.
-		close(stopBackupLoop)
-	}()
-
-	// Start a backup job in a new goroutine.
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				log.Infof("creating automatic backup of app '%s'.", app.name)
-
-				// Create a backup.
-				err := app.Backup()
-				if err != nil {
-					log.Errorf("failed to create automatic backup of app '%s': %v", app.name, err)
-				}
-			case <-stopBackupLoop:
-				// Just exit the loop
-				return
-			}
-		}
-	}()
+	// Reset the restart budget.
+	app.resetRestarts()
 
 	for {
 		// Reset the restart flag.
@@ -152,6 +128,10 @@ func taskFuncRun(app *App) (err error) {
 		// Create the stop channel and update the flag.
 		app.createStopChannel()
 
+		// Create the error channel of the restart checks.
+		// Health checks might trigger a restart check during the startup.
+		app.checkRestartError = make(chan error, 1)
+
 		// Stop and delete all containers if present.
 		app.setState("cleaning up containers...")
 		if err = stopContainers(app); err != nil {
@@ -205,6 +185,12 @@ func setupRunEnvironment(app *App) error {
 		return err
 	}
 
+	// Create the app networks.
+	err = setupNetworks(app, turtlefile)
+	if err != nil {
+		return err
+	}
+
 	// Setup the container volume directories if not present.
 	var path string
 	for _, c := range turtlefile.Containers {
@@ -230,9 +216,8 @@ func setupRunEnvironment(app *App) error {
 
 // stopContainers stops and removes the containers.
 func stopContainers(app *App) error {
-	if app.containerIDs == nil {
-		return nil
-	}
+	// Stop all health checks.
+	app.resetHealth()
 
 	var err error
 
@@ -240,6 +225,12 @@ func stopContainers(app *App) error {
 	// Do this in the reverse order, because the container IDs are
 	// sorted to the startup order.
 	for i := len(app.containerIDs) - 1; i >= 0; i-- {
+		// Skip containers which were already removed by a partial restart.
+		if len(app.containerIDs[i]) == 0 {
+			app.containerIDs = app.containerIDs[:i]
+			continue
+		}
+
 		err = docker.StopAndDeleteContainer(app.containerIDs[i])
 		if err != nil {
 			return fmt.Errorf("failed to stop and remove container '%s': %v", app.containerIDs[i], err)
@@ -251,7 +242,8 @@ func stopContainers(app *App) error {
 	// Clear the container slice completly.
 	app.containerIDs = nil
 
-	return nil
+	// Remove the app networks.
+	return removeNetworks(app)
 }
 
 func startContainers(app *App) (err error) {
@@ -267,178 +259,301 @@ func startContainers(app *App) (err error) {
 	// Clear the container IDs slice.
 	app.containerIDs = nil
 
-	// Get the app's directory path.
-	volumesPath := app.VolumesDirectoryPath()
-	sourcePath := app.SourceDirectoryPath()
-
 	// Get the turtlefile.
 	turtlefile, err := app.Turtlefile()
 	if err != nil {
 		return err
 	}
 
-	// Get the container name prefix.
-	cNamePrefix := app.ContainerNamePrefix()
-
 	// Start each app container.
 	// Hint: the containers are already sorted by the turtlefile Load method.
 	for _, container := range turtlefile.Containers {
-		// Create the docker container name.
-		containerName := cNamePrefix + container.Name
-
-		// Check if a container with the same name is present.
-		c, err := docker.GetContainerByName(containerName)
+		id, err := startContainer(app, container)
 		if err != nil {
 			return err
-		} else if c != nil {
-			// Stop and remove it.
-			err = docker.StopAndDeleteContainer(c.ID)
-			if err != nil {
-				return fmt.Errorf("failed to stop and remove container '%s': %v", c.ID, err)
-			}
 		}
 
-		// Create the port bindings.
-		portBindings := make(map[d.Port][]d.PortBinding)
-		for _, p := range app.settings.Ports {
-			// Skip if this is not for this container or if disabled.
-			if p.ContainerName != container.Name || p.HostPort <= 0 {
-				continue
-			}
+		// Add the continer ID to the slice.
+		app.containerIDs = append(app.containerIDs, id)
+	}
 
-			portProtocol := d.Port(fmt.Sprintf("%v/%s", p.ContainerPort, p.Protocol))
+	// Set the app state.
+	app.setState(stateRunning)
 
-			portBindings[portProtocol] = []d.PortBinding{
-				d.PortBinding{
-					HostPort: fmt.Sprintf("%v", p.HostPort),
-				},
+	// Wait, to be sure all containers started up.
+	time.Sleep(waitAfterAppStart)
+
+	return nil
+}
+
+// restartContainers stops and starts the passed containers again.
+// The other app containers keep running. The containers have to be
+// sorted by their startup order.
+func restartContainers(app *App, containers turtlefile.Containers) (err error) {
+	// Stop all containers on error.
+	defer func() {
+		if err != nil {
+			if errS := stopContainers(app); errS != nil {
+				log.Errorf("failed to stop and delete previous app containers: %v", errS)
 			}
 		}
+	}()
+
+	// Get the turtlefile.
+	t, err := app.Turtlefile()
+	if err != nil {
+		return err
+	}
+
+	// The container IDs are sorted to the startup order of the turtlefile containers.
+	if len(app.containerIDs) != len(t.Containers) {
+		return fmt.Errorf("app container IDs do not match the turtlefile containers!")
+	}
+
+	// Create a map of the container indexes.
+	indexes := make(map[string]int)
+	for i, c := range t.Containers {
+		indexes[c.Name] = i
+	}
+
+	// Stop and remove the containers in the reverse order.
+	for i := len(containers) - 1; i >= 0; i-- {
+		index := indexes[containers[i].Name]
+		id := app.containerIDs[index]
+		if len(id) == 0 {
+			continue
+		}
+
+		// Stop the container health checks.
+		app.resetContainerHealth(containers[i].Name, id)
 
-		// Create the links.
-		links := make([]string, len(container.Links))
-		for i, l := range container.Links {
-			links[i] = cNamePrefix + l + ":" + l
+		err = docker.StopAndDeleteContainer(id)
+		if err != nil {
+			return fmt.Errorf("failed to stop and remove container '%s': %v", id, err)
 		}
 
-		// Create the environment variables slice.
-		env, err := app.getEnv(container.Name)
+		app.containerIDs[index] = ""
+	}
+
+	// Start the containers again.
+	for _, container := range containers {
+		id, err := startContainer(app, container)
 		if err != nil {
 			return err
 		}
 
-		// Add the static environment variables.
-		env = append(container.Env, env...)
+		// Replace the container ID.
+		app.containerIDs[indexes[container.Name]] = id
+	}
 
-		// Create the bind volumes slice.
-		binds := container.GetVolumeBinds(volumesPath)
+	// Set the app state.
+	app.setState(stateRunning)
 
-		// Create the host config.
-		hostConfig := &d.HostConfig{
-			RestartPolicy:   d.NeverRestart(), // the docker daemon will not restart the container automatically.
-			Links:           links,
-			Privileged:      false,
-			PublishAllPorts: false,
-			PortBindings:    portBindings,
-			Binds:           binds,
-			NetworkMode:     container.NetworkMode,
-		}
+	// Wait, to be sure all containers started up.
+	time.Sleep(waitAfterAppStart)
 
-		// Check if the container image should be build from source locally.
-		isLocalBuild := container.IsLocalBuild()
+	return nil
+}
 
-		// Create the container image and image name.
-		imageName := container.Image
-		if isLocalBuild {
-			imageName = containerName
-		}
-		image := imageName + ":" + container.Tag
+// startContainer creates and starts the app container.
+// The container ID is returned on success.
+func startContainer(app *App, container *turtlefile.Container) (string, error) {
+	// Get the app's directory path.
+	volumesPath := app.VolumesDirectoryPath()
+	sourcePath := app.SourceDirectoryPath()
 
-		// Create the container config.
-		cConfig := &d.Config{
-			Image:           image,
-			Hostname:        container.Hostname,
-			Domainname:      container.Domainname,
-			Env:             env,
-			Cmd:             container.Cmd,
-			Entrypoint:      container.Entrypoint,
-			WorkingDir:      container.WorkingDir,
-			DNS:             container.DNS,
-			NetworkDisabled: container.NetworkDisabled,
-		}
+	// Get the container name prefix.
+	cNamePrefix := app.ContainerNamePrefix()
 
-		// Create the container options.
-		options := &d.CreateContainerOptions{
-			Name:       containerName,
-			Config:     cConfig,
-			HostConfig: hostConfig,
-		}
+	// Create the docker container name.
+	containerName := cNamePrefix + container.Name
 
-		// Check if the image exists.
-		_, err = docker.Client.InspectImage(image)
+	// Check if a container with the same name is present.
+	c, err := docker.GetContainerByName(containerName)
+	if err != nil {
+		return "", err
+	} else if c != nil {
+		// Stop and remove it.
+		err = docker.StopAndDeleteContainer(c.ID)
 		if err != nil {
-			// Check whenever to build or pull the image.
-			if isLocalBuild {
-				app.setState("building local docker image: " + image)
-				log.Infof("building local docker image: %s", image)
-
-				// Build the local image.
-				err = docker.Build(imageName, container.Tag, container.BuildPath(sourcePath))
-				if err != nil {
-					return fmt.Errorf("failed to build image '%s': %v", image, err)
-				}
-			} else {
-				app.setState("pulling docker image: " + image)
-				log.Infof("pulling docker image: %s", image)
-
-				// Pull the image.
-				err = docker.Client.PullImage(d.PullImageOptions{
-					Repository: container.Image,
-					Tag:        container.Tag,
-				}, d.AuthConfiguration{})
-
-				if err != nil {
-					return fmt.Errorf("failed to pull docker image '%s': %v", image, err)
-				}
-			}
+			return "", fmt.Errorf("failed to stop and remove container '%s': %v", c.ID, err)
 		}
+	}
 
-		app.setState("starting container: " + containerName)
-		log.Infof("starting container: %s", containerName)
+	// Create the port bindings.
+	portBindings := make(map[d.Port][]d.PortBinding)
+	for _, p := range app.settings.Ports {
+		// Skip if this is not for this container or if disabled.
+		if p.ContainerName != container.Name || p.HostPort <= 0 {
+			continue
+		}
 
-		// Create the docker container.
-		c, err = docker.Client.CreateContainer(*options)
-		if err != nil {
-			return fmt.Errorf("failed to create docker container:\nName: %s\nConfig: %+v\nHost Config: %+v\nError: %v",
-				options.Name, options.Config, options.HostConfig, err)
+		portProtocol := d.Port(fmt.Sprintf("%v/%s", p.ContainerPort, p.Protocol))
+
+		portBindings[portProtocol] = []d.PortBinding{
+			d.PortBinding{
+				HostPort: fmt.Sprintf("%v", p.HostPort),
+			},
 		}
+	}
 
-		// Start the container.
-		err = docker.Client.StartContainer(c.ID, hostConfig)
-		if err != nil {
-			return fmt.Errorf("failed to start docker container:\nHost Config: %+v\nError: %v", hostConfig, err)
+	// Attach the container to the app network with its name as DNS alias.
+	// The links are only used as startup order hint.
+	networkMode := container.NetworkMode
+	var networkingConfig *d.NetworkingConfig
+	if networkMode == turtlefile.DefaultNetworkMode {
+		networkMode = app.NetworkName()
+		networkingConfig = &d.NetworkingConfig{
+			EndpointsConfig: map[string]*d.EndpointConfig{
+				networkMode: &d.EndpointConfig{
+					Aliases: []string{container.Name},
+				},
+			},
 		}
+	}
 
-		// Add the continer ID to the slice.
-		app.containerIDs = append(app.containerIDs, c.ID)
+	// Create the environment variables slice.
+	env, err := app.getEnv(container.Name)
+	if err != nil {
+		return "", err
+	}
+
+	// Add the static environment variables.
+	env = append(container.Env, env...)
+
+	// Create the bind volumes slice.
+	binds := container.GetVolumeBinds(volumesPath)
+
+	// Obtain the resource limits. The app settings overwrite the turtlefile limits.
+	resources := container.Resources.Merge(app.settings.Resources[container.Name])
+	memory, err := resources.MemoryBytes()
+	if err != nil {
+		return "", err
+	}
+	memorySwap, err := resources.MemorySwapBytes()
+	if err != nil {
+		return "", err
+	}
+
+	// Create the host config.
+	hostConfig := &d.HostConfig{
+		RestartPolicy:   d.NeverRestart(), // the docker daemon will not restart the container automatically.
+		Privileged:      false,
+		PublishAllPorts: false,
+		PortBindings:    portBindings,
+		Binds:           binds,
+		NetworkMode:     networkMode,
+
+		// Resource limits.
+		Memory:      memory,
+		MemorySwap:  memorySwap,
+		CPUShares:   resources.CPUShares,
+		CPUSet:      resources.CPUSet,
+		CPUQuota:    resources.CPUQuota,
+		CPUPeriod:   resources.CPUPeriod,
+		PidsLimit:   resources.PidsLimit,
+		BlkioWeight: resources.BlkioWeight,
+	}
+
+	// Check if the container image should be build from source locally.
+	isLocalBuild := container.IsLocalBuild()
+
+	// Create the container image and image name.
+	imageName, tag := app.containerImage(container)
+	image := imageName + ":" + tag
+
+	// Create the container config.
+	cConfig := &d.Config{
+		Image:           image,
+		Hostname:        container.Hostname,
+		Domainname:      container.Domainname,
+		Env:             env,
+		Cmd:             container.Cmd,
+		Entrypoint:      container.Entrypoint,
+		WorkingDir:      container.WorkingDir,
+		DNS:             container.DNS,
+		NetworkDisabled: container.NetworkDisabled,
+	}
+
+	// Create the container options.
+	options := &d.CreateContainerOptions{
+		Name:             containerName,
+		Config:           cConfig,
+		HostConfig:       hostConfig,
+		NetworkingConfig: networkingConfig,
+	}
 
-		// Wait x milliseconds after the container started.
-		// This delays the next container startup.
-		// If set to 0, use the default value.
-		if container.WaitAfterStartup == 0 {
-			time.Sleep(defaultContainerWaitAfterStartup)
+	// Check if the image exists.
+	_, err = docker.Client.InspectImage(image)
+	if err != nil && app.origin != nil {
+		// The images of update clones are prepared by the safe update.
+		return "", fmt.Errorf("update image '%s' is missing!", image)
+	} else if err != nil {
+		// Check whenever to build or pull the image.
+		if isLocalBuild {
+			app.setState("building local docker image: " + image)
+			log.Infof("building local docker image: %s", image)
+
+			// Build the local image.
+			err = docker.Build(imageName, tag, container.BuildPath(sourcePath))
+			if err != nil {
+				return "", fmt.Errorf("failed to build image '%s': %v", image, err)
+			}
 		} else {
-			time.Sleep(time.Duration(container.WaitAfterStartup) * time.Millisecond)
+			app.setState("pulling docker image: " + image)
+			log.Infof("pulling docker image: %s", image)
+
+			// Pull the image.
+			err = docker.PullImage(container.Image, tag)
+
+			if err != nil {
+				return "", fmt.Errorf("failed to pull docker image '%s': %v", image, err)
+			}
 		}
 	}
 
-	// Set the app state.
-	app.setState("running")
+	app.setState("starting container: " + containerName)
+	log.Infof("starting container: %s", containerName)
 
-	// Wait, to be sure all containers started up.
-	time.Sleep(waitAfterAppStart)
+	// Create the docker container.
+	c, err = docker.Client.CreateContainer(*options)
+	if err != nil {
+		return "", fmt.Errorf("failed to create docker container:\nName: %s\nConfig: %+v\nHost Config: %+v\nError: %v",
+			options.Name, options.Config, options.HostConfig, err)
+	}
 
-	return nil
+	// Connect the container to the shared networks.
+	// The container is reachable as CONTAINER.APP within the shared network.
+	// Update clones are isolated from other apps.
+	for _, n := range app.sharedNetworks(container) {
+		err = docker.ConnectNetwork(sharedNetworkName(n), c.ID, container.Name+"."+app.name)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Start the container.
+	err = docker.Client.StartContainer(c.ID, hostConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to start docker container:\nHost Config: %+v\nError: %v", hostConfig, err)
+	}
+
+	// Capture the container logs to the log files.
+	captureLogs(app, container.Name, c.ID)
+
+	// Start the container health checks.
+	startHealthChecks(app, container, c.ID, app.healthStopChan(c.ID))
+
+	// Wait x milliseconds after the container started.
+	// This delays the next container startup.
+	// If set to 0, use the default value.
+	if container.WaitAfterStartup == 0 {
+		time.Sleep(defaultContainerWaitAfterStartup)
+	} else {
+		time.Sleep(time.Duration(container.WaitAfterStartup) * time.Millisecond)
+	}
+
+	return c.ID, nil
 }
 
 func watchRunState(app *App) (err error) {
@@ -469,9 +584,6 @@ func watchRunState(app *App) (err error) {
 		docker.OffEvent(eventID)
 	}()
 
-	// Create the error channel.
-	app.checkRestartError = make(chan error, 1)
-
 	// Perfom an initial check of all app containers.
 	checkRestart(app)
 
@@ -533,7 +645,7 @@ func checkRestart(app *App) {
 		}()
 
 		// Perform the actual request.
-		err := _checkRestart(app, 0)
+		err := _checkRestart(app)
 		if err != nil {
 			// Send the error to the channel.
 			app.checkRestartError <- err
@@ -541,81 +653,246 @@ func checkRestart(app *App) {
 	}()
 }
 
-func _checkRestart(app *App, retryCount int) (err error) {
-	// Increment the retry count.
-	retryCount++
+func _checkRestart(app *App) error {
+	// Recheck the state after each restart until all containers are running.
+	for {
+		restarted, err := restartStoppedContainers(app)
+		if err != nil || !restarted {
+			return err
+		}
+	}
+}
+
+// restartStoppedContainers restarts the stopped and unhealthy containers once.
+// The boolean is true if containers were restarted and the state has to be checked again.
+func restartStoppedContainers(app *App) (restarted bool, err error) {
+	// Obtain the restart policy.
+	policy, err := app.RestartPolicy()
+	if err != nil {
+		return false, err
+	}
+
+	// Get the turtlefile.
+	t, err := app.Turtlefile()
+	if err != nil {
+		return false, err
+	}
 
 	var stoppedContainers []*d.Container
+	var stoppedNames []string
 
 	// Get all containers which stopped running.
-	for _, id := range app.containerIDs {
+	// Hint: the container IDs are sorted to the startup order of the turtlefile containers.
+	for i, id := range app.containerIDs {
+		// Skip containers which are currently restarted.
+		if len(id) == 0 {
+			continue
+		}
+
 		// Obtain the container with its ID.
 		c, err := docker.Client.InspectContainer(id)
 		if err != nil {
-			return err
+			return false, err
 		}
 
-		// Check if not running.
-		if !c.State.Running {
-			// Append the stopped container to the slice.
-			stoppedContainers = append(stoppedContainers, c)
+		// Skip running and healthy containers.
+		unhealthy := app.isUnhealthy(id)
+		if c.State.Running && !unhealthy {
+			continue
+		}
 
-			// Log
+		// Successfully exited containers are only restarted by the always policy.
+		if !unhealthy && c.State.ExitCode == 0 && policy.Policy == turtlefile.RestartOnFailure {
 			log.WithFields(
 				log.Fields{
 					"app":            app.name,
 					"container ID":   c.ID,
 					"container name": c.Name,
-				}).Warning("app container stopped running!")
+				}).Info("app container exited successfully.")
+			continue
+		}
+
+		// Append the stopped container to the slices.
+		stoppedContainers = append(stoppedContainers, c)
+		if i < len(t.Containers) {
+			stoppedNames = append(stoppedNames, t.Containers[i].Name)
 		}
+
+		// Log
+		log.WithFields(
+			log.Fields{
+				"app":            app.name,
+				"container ID":   c.ID,
+				"container name": c.Name,
+				"exit code":      c.State.ExitCode,
+			}).Warning("app container stopped running or is unhealthy!")
 	}
 
 	// Return if everything is ok.
 	if len(stoppedContainers) == 0 {
-		return nil
+		app.setNextRestart(time.Time{})
+		return false, nil
 	}
 
-	// Abort and return an error if the retry count is reached.
-	if retryCount > maximumRestartRetryCount {
-		var errStr string
+	// Don't restart the app if disabled.
+	if policy.Policy == turtlefile.RestartNever {
+		return false, fmt.Errorf("App '%s' stopped running! Restarts are disabled by the restart policy.%s",
+			app.name, containersErrorOutput(stoppedContainers))
+	}
 
-		// Obtain the error logs from the stopped containers.
-		for _, c := range stoppedContainers {
-			stderr, err := docker.Logs(c.ID, docker.StdStreamError)
-			if err != nil {
-				log.Errorln(err)
-				continue
-			}
+	// Abort and return an error if the restart budget is used up.
+	attempt := app.restartAttempts(policy.Window.Duration) + 1
+	if policy.MaxRetries != turtlefile.UnlimitedRestarts && attempt > policy.MaxRetries {
+		return false, fmt.Errorf("failed to restart app: max restart retries reached within %v! App '%s' stopped running!%s",
+			policy.Window.Duration, app.name, containersErrorOutput(stoppedContainers))
+	}
 
-			// Add a spacing to each error line.
-			lines := strings.Split(stderr, "\n")
-			for i := 0; i < len(lines); i++ {
-				lines[i] = "   " + lines[i]
-			}
-			stderr = "   " + strings.TrimSpace(strings.Join(lines, "\n"))
+	// Wait for the backoff delay.
+	delay := policy.Delay(attempt)
+	next := time.Now().Add(delay)
+	app.setNextRestart(next)
 
-			errStr += fmt.Sprintf("\n\nContainer '%s' error output:\n%s", c.Name, stderr)
-		}
+	// Set the app state.
+	app.setState(fmt.Sprintf("restarting app (attempt %v, next retry at %s)...", attempt, next.Format("15:04:05")))
+
+	// Log.
+	log.Infof("restarting app '%s' in %v (attempt %v)", app.name, delay, attempt)
+
+	select {
+	case <-time.After(delay):
+	case <-app.stopRequested:
+		// The app is stopped by the watch loop.
+		app.setNextRestart(time.Time{})
+		return false, nil
+	}
+
+	// Obtain the stopped containers and all containers which link to them.
+	// The other containers keep running.
+	containers := t.Containers.Dependents(stoppedNames...)
 
-		return fmt.Errorf("failed to restart app: max restart retries reached! App '%s' stopped running!%s", app.name, errStr)
+	names := make([]string, len(containers))
+	for i, c := range containers {
+		names[i] = c.Name
 	}
 
 	// Set the app state.
-	app.setState("restarting app...")
+	app.setState("restarting containers: " + strings.Join(names, ", "))
+	app.addRestartAttempt()
+	app.addRestart()
 
 	// Log.
-	log.Infof("restarting app '%s'", app.name)
+	log.Infof("app '%s': restarting containers: %s", app.name, strings.Join(names, ", "))
 
-	// First stop and remove all app containers.
-	if err = stopContainers(app); err != nil {
-		return err
+	// Restart the affected containers.
+	if err = restartContainers(app, containers); err != nil {
+		return false, err
 	}
 
-	// Start the app containers again.
-	if err = startContainers(app); err != nil {
-		return err
+	return true, nil
+}
+
+// containersErrorOutput returns the error output of the containers.
+func containersErrorOutput(containers []*d.Container) string {
+	var errStr string
+
+	// Obtain the error logs from the stopped containers.
+	for _, c := range containers {
+		stderr, err := docker.Logs(c.ID, docker.StdStreamError)
+		if err != nil {
+			log.Errorln(err)
+			continue
+		}
+
+		// Add a spacing to each error line.
+		lines := strings.Split(stderr, "\n")
+		for i := 0; i < len(lines); i++ {
+			lines[i] = "   " + lines[i]
+		}
+		stderr = "   " + strings.TrimSpace(strings.Join(lines, "\n"))
+
+		errStr += fmt.Sprintf("\n\nContainer '%s' error output:\n%s", c.Name, stderr)
 	}
 
-	// Recall this method to recheck the state.
-	return _checkRestart(app, retryCount)
+	return errStr
+}
+
+// RestartPolicy returns the restart policy of the app.
+// The app settings overwrite the turtlefile policy.
+func (a *App) RestartPolicy() (turtlefile.RestartPolicy, error) {
+	// Get the turtlefile.
+	t, err := a.Turtlefile()
+	if err != nil {
+		return turtlefile.RestartPolicy{}, err
+	}
+
+	return t.RestartPolicy.Merge(a.settings.RestartPolicy).WithDefaults(), nil
+}
+
+// RestartState returns the number of restarts within the restart policy
+// window and the time of the next pending restart. The time is zero if no
+// restart is pending.
+func (a *App) RestartState() (attempts int, next time.Time) {
+	policy, err := a.RestartPolicy()
+	if err != nil {
+		return 0, time.Time{}
+	}
+
+	// Lock the mutex.
+	a.restartMutex.Lock()
+	defer a.restartMutex.Unlock()
+
+	for _, t := range a.restartTimes {
+		if time.Since(t) <= policy.Window.Duration {
+			attempts++
+		}
+	}
+
+	return attempts, a.nextRestart
+}
+
+// restartAttempts returns the number of restarts within the window
+// and removes all older restarts.
+func (a *App) restartAttempts(window time.Duration) int {
+	// Lock the mutex.
+	a.restartMutex.Lock()
+	defer a.restartMutex.Unlock()
+
+	var times []time.Time
+	for _, t := range a.restartTimes {
+		if time.Since(t) <= window {
+			times = append(times, t)
+		}
+	}
+	a.restartTimes = times
+
+	return len(times)
+}
+
+// addRestartAttempt adds a restart to the restart budget.
+func (a *App) addRestartAttempt() {
+	// Lock the mutex.
+	a.restartMutex.Lock()
+	defer a.restartMutex.Unlock()
+
+	a.restartTimes = append(a.restartTimes, time.Now())
+	a.nextRestart = time.Time{}
+}
+
+// setNextRestart sets the time of the next pending restart.
+func (a *App) setNextRestart(t time.Time) {
+	// Lock the mutex.
+	a.restartMutex.Lock()
+	defer a.restartMutex.Unlock()
+
+	a.nextRestart = t
+}
+
+// resetRestarts resets the restart budget.
+func (a *App) resetRestarts() {
+	// Lock the mutex.
+	a.restartMutex.Lock()
+	defer a.restartMutex.Unlock()
+
+	a.restartTimes = nil
+	a.nextRestart = time.Time{}
 }
@@ -0,0 +1,182 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package apps
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Give up searching the next cron time after this duration.
+	maxCronSearchDuration = 5 * 366 * 24 * time.Hour
+)
+
+//##########################//
+//### Cron schedule type ###//
+//##########################//
+
+// cronSchedule is a parsed cron expression with the fields:
+// minute hour day-of-month month day-of-week
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64 // Bit sets of the allowed values.
+
+	// The day matches if the day-of-month or the day-of-week matches,
+	// if both fields are restricted. This is the behavior of the cron daemon.
+	domStar, dowStar bool
+}
+
+// parseCron parses a standard cron expression with five fields.
+// Each field supports *, values, ranges, lists and steps, e.g. */15 or 1-5.
+// The day-of-week is in the range of 0 to 7, where 0 and 7 are sunday.
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression '%s': expected 5 fields", expr)
+	}
+
+	c := &cronSchedule{
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}
+
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid cron minute field: %v", err)
+	} else if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid cron hour field: %v", err)
+	} else if c.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid cron day-of-month field: %v", err)
+	} else if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid cron month field: %v", err)
+	} else if c.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid cron day-of-week field: %v", err)
+	}
+
+	// Sunday is 0 and 7.
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+
+	return c, nil
+}
+
+// next returns the next time after t matching the schedule.
+// A zero time is returned if the schedule never matches.
+func (c *cronSchedule) next(t time.Time) time.Time {
+	// Start with the next full minute.
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	end := t.Add(maxCronSearchDuration)
+
+	for t.Before(end) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			// Skip to the first day of the next month.
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !c.matchDay(t) {
+			// Skip to the next day.
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			// Skip to the next hour. The hour is built in the local time,
+			// because some time zones are offset by half an hour.
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// matchDay returns a boolean whenever the day of t matches the schedule.
+func (c *cronSchedule) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// parseCronField parses a comma separated cron field to a bit set.
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		// Obtain the optional step.
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in '%s'", part)
+			}
+			part = part[:i]
+		}
+
+		// Obtain the range.
+		start, end := min, max
+		if part != "*" {
+			var err error
+			if i := strings.Index(part, "-"); i >= 0 {
+				if start, err = strconv.Atoi(part[:i]); err == nil {
+					end, err = strconv.Atoi(part[i+1:])
+				}
+			} else if start, err = strconv.Atoi(part); err == nil {
+				end = start
+
+				// A value with a step ranges to the maximum, e.g. 5/10.
+				if step > 1 {
+					end = max
+				}
+			}
+			if err != nil {
+				return 0, fmt.Errorf("invalid value '%s'", part)
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("value '%s' is out of the range %d-%d", part, min, max)
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
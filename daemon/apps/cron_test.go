@@ -0,0 +1,182 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package apps
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	valid := []string{
+		"* * * * *",
+		"0 3 * * *",
+		"*/15 * * * *",
+		"0 8-18/2 * * 1-5",
+		"5,35 0 1,15 * *",
+		"0 0 * 1/3 7",
+		"59 23 31 12 0",
+	}
+
+	for _, expr := range valid {
+		if _, err := parseCron(expr); err != nil {
+			t.Errorf("'%s': unexpected error: %v", expr, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"-1 * * * *",
+	}
+
+	for _, expr := range invalid {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("'%s': expected an error", expr)
+		}
+	}
+}
+
+func TestParseCronField(t *testing.T) {
+	tests := []struct {
+		field    string
+		min, max int
+		want     []int
+	}{
+		{"*", 0, 5, []int{0, 1, 2, 3, 4, 5}},
+		{"3", 0, 5, []int{3}},
+		{"1-3", 0, 5, []int{1, 2, 3}},
+		{"*/2", 0, 5, []int{0, 2, 4}},
+		{"1/2", 0, 5, []int{1, 3, 5}},
+		{"0-4/3", 0, 5, []int{0, 3}},
+		{"0,2-3,5", 0, 5, []int{0, 2, 3, 5}},
+	}
+
+	for _, test := range tests {
+		bits, err := parseCronField(test.field, test.min, test.max)
+		if err != nil {
+			t.Errorf("'%s': unexpected error: %v", test.field, err)
+			continue
+		}
+
+		var want uint64
+		for _, v := range test.want {
+			want |= 1 << uint(v)
+		}
+		if bits != want {
+			t.Errorf("'%s': got %b, want %b", test.field, bits, want)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	utc := time.UTC
+	ist := time.FixedZone("IST", 5*3600+30*60)
+
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		// Every minute starts with the next full minute.
+		{"* * * * *", time.Date(2015, 10, 19, 10, 0, 30, 0, utc), time.Date(2015, 10, 19, 10, 1, 0, 0, utc)},
+		{"* * * * *", time.Date(2015, 10, 19, 10, 0, 0, 0, utc), time.Date(2015, 10, 19, 10, 1, 0, 0, utc)},
+
+		// Daily at 3 o'clock.
+		{"0 3 * * *", time.Date(2015, 10, 19, 2, 59, 0, 0, utc), time.Date(2015, 10, 19, 3, 0, 0, 0, utc)},
+		{"0 3 * * *", time.Date(2015, 10, 19, 3, 0, 0, 0, utc), time.Date(2015, 10, 20, 3, 0, 0, 0, utc)},
+
+		// Steps and lists.
+		{"*/15 * * * *", time.Date(2015, 10, 19, 10, 16, 0, 0, utc), time.Date(2015, 10, 19, 10, 30, 0, 0, utc)},
+		{"5,35 * * * *", time.Date(2015, 10, 19, 10, 36, 0, 0, utc), time.Date(2015, 10, 19, 11, 5, 0, 0, utc)},
+
+		// Month and year boundaries.
+		{"0 0 1 * *", time.Date(2015, 10, 19, 10, 0, 0, 0, utc), time.Date(2015, 11, 1, 0, 0, 0, 0, utc)},
+		{"30 12 * 1 *", time.Date(2015, 10, 19, 10, 0, 0, 0, utc), time.Date(2016, 1, 1, 12, 30, 0, 0, utc)},
+
+		// Leap day.
+		{"0 0 29 2 *", time.Date(2015, 3, 1, 0, 0, 0, 0, utc), time.Date(2016, 2, 29, 0, 0, 0, 0, utc)},
+
+		// Day-of-week: 2015-10-19 is a monday. 0 and 7 are sunday.
+		{"0 9 * * 1-5", time.Date(2015, 10, 23, 10, 0, 0, 0, utc), time.Date(2015, 10, 26, 9, 0, 0, 0, utc)},
+		{"0 0 * * 7", time.Date(2015, 10, 19, 0, 0, 0, 0, utc), time.Date(2015, 10, 25, 0, 0, 0, 0, utc)},
+
+		// Restricted day-of-month and day-of-week fields match either day.
+		{"0 0 1 * 3", time.Date(2015, 10, 19, 0, 0, 0, 0, utc), time.Date(2015, 10, 21, 0, 0, 0, 0, utc)},
+
+		// Half-hour offset time zones match in the local time.
+		{"0 3 * * *", time.Date(2015, 10, 19, 1, 15, 0, 0, ist), time.Date(2015, 10, 19, 3, 0, 0, 0, ist)},
+		{"15,45 */2 * * *", time.Date(2015, 10, 19, 1, 50, 0, 0, ist), time.Date(2015, 10, 19, 2, 15, 0, 0, ist)},
+	}
+
+	for _, test := range tests {
+		c, err := parseCron(test.expr)
+		if err != nil {
+			t.Fatalf("'%s': %v", test.expr, err)
+		}
+
+		if got := c.next(test.from); !got.Equal(test.want) {
+			t.Errorf("'%s' from %v: got %v, want %v", test.expr, test.from, got, test.want)
+		}
+	}
+}
+
+func TestCronNextNever(t *testing.T) {
+	c, err := parseCron("0 0 31 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.next(time.Date(2015, 10, 19, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("expected a zero time, got %v", got)
+	}
+}
+
+func TestCronNextDST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+
+	c, err := parseCron("30 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The clocks are turned back from 3:00 to 2:00 on 2015-10-25.
+	// Each hour has to be matched without getting stuck.
+	from := time.Date(2015, 10, 25, 0, 45, 0, 0, loc)
+	prev := from
+	for i := 0; i < 6; i++ {
+		next := c.next(prev)
+		if !next.After(prev) || next.Minute() != 30 {
+			t.Fatalf("invalid next time after %v: %v", prev, next)
+		}
+		prev = next
+	}
+}
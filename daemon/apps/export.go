@@ -0,0 +1,272 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package apps
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/desertbit/turtle/archive"
+	"github.com/desertbit/turtle/daemon/btrfs"
+	"github.com/desertbit/turtle/daemon/turtlefile"
+	"github.com/desertbit/turtle/utils"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//##############//
+//### Public ###//
+//##############//
+
+// Import creates a new app from a backup archive.
+// The new app is not started.
+func Import(name string, r io.Reader, password string) (m *archive.Manifest, err error) {
+	// The name is used as directory name.
+	if strings.ContainsRune(name, '/') || name == "." || name == ".." {
+		return nil, fmt.Errorf("invalid app name '%s'!", name)
+	}
+
+	a, err := newApp(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if an app with the same name already exists.
+	if _, errG := Get(name); errG == nil {
+		return nil, fmt.Errorf("an App with the name '%s' already exists!", name)
+	} else if e, errE := utils.Exists(a.path); errE != nil {
+		return nil, errE
+	} else if e {
+		return nil, fmt.Errorf("the app directory '%s' already exists!", a.path)
+	}
+
+	log.Infof("importing app '%s'", name)
+
+	// Extract the archive to the new app subvolume.
+	m, err = extractArchive(r, password, a.path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the app subvolume on error.
+	defer func() {
+		if err != nil {
+			if errD := btrfs.DeleteSubvolume(a.path); errD != nil {
+				log.Errorf("failed to cleanup failed import of app '%s': %v", name, errD)
+			}
+		}
+	}()
+
+	// Load the app settings.
+	if err = a.loadSettings(); err != nil {
+		return nil, err
+	}
+
+	// Lock the mutex.
+	appsMutex.Lock()
+	defer appsMutex.Unlock()
+
+	// Check again, because the mutex was not locked during the extraction.
+	if _, ok := apps[name]; ok {
+		return nil, fmt.Errorf("an App with the name '%s' already exists!", name)
+	}
+
+	// Finally add the app to the map.
+	apps[name] = a
+
+	return m, nil
+}
+
+//##########################//
+//### Public App methods ###//
+//##########################//
+
+// ExportBackup writes the backup as encrypted and compressed archive to w.
+func (a *App) ExportBackup(timestamp, password string, w io.Writer) error {
+	// Check if the backup exists.
+	if !a.BackupExists(timestamp) {
+		return fmt.Errorf("no backup '%s' found!", timestamp)
+	}
+
+	path := a.BackupDirectoryPath() + "/" + timestamp
+
+	// Create the manifest.
+	m := &archive.Manifest{
+		App:       a.name,
+		Timestamp: timestamp,
+		Created:   time.Now().Unix(),
+	}
+
+	settings, err := ioutil.ReadFile(path + "/" + settingsFilename)
+	if err != nil {
+		return fmt.Errorf("failed to read backup settings: %v", err)
+	}
+	m.Settings = string(settings)
+
+	// The turtlefile is missing if the source was not cloned yet.
+	m.Turtlefile, err = readTurtlefileContent(path + "/" + sourceDirectory)
+	if err != nil {
+		log.Warningf("app '%s': export backup '%s': %v", a.name, timestamp, err)
+	}
+
+	log.Infof("exporting backup '%s' of app '%s'", timestamp, a.name)
+
+	// Write the archive.
+	aw, err := archive.NewWriter(w, password, m)
+	if err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("tar", "--create", "--file", "-", "--numeric-owner", "-C", path, ".")
+	cmd.Stdout = aw
+	cmd.Stderr = &stderr
+
+	if err = cmd.Run(); err != nil {
+		return fmt.Errorf("failed to archive backup '%s': %v: %s", timestamp, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return aw.Close()
+}
+
+// ImportBackup imports the archive as a new backup of the app.
+// The backup is created with the current timestamp, because the retention
+// would remove old backups immediately. The new backup timestamp is returned.
+func (a *App) ImportBackup(r io.Reader, password string) (string, *archive.Manifest, error) {
+	// Get the app's base backup folder.
+	backupPath := a.BackupDirectoryPath()
+
+	// Create the base app backup folder if not present.
+	err := utils.MkDirIfNotExists(backupPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Create the new backup path with the current timestamp.
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	path := backupPath + "/" + timestamp
+
+	if e, err := utils.Exists(path); err != nil {
+		return "", nil, err
+	} else if e {
+		return "", nil, fmt.Errorf("backup '%s' already exists!", timestamp)
+	}
+
+	log.Infof("importing backup of app '%s': %s", a.name, path)
+
+	// Extract the archive.
+	m, err := extractArchive(r, password, path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Backups are readonly.
+	if err = btrfs.SetSubvolumeReadonly(path, true); err != nil {
+		if errD := btrfs.DeleteSubvolume(path); errD != nil {
+			log.Errorf("failed to remove subvolume '%s' of failed import: %v", path, errD)
+		}
+		return "", nil, err
+	}
+
+	return timestamp, m, nil
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// extractArchive decrypts and extracts the archive to a new subvolume.
+// The subvolume is removed on error.
+func extractArchive(r io.Reader, password, path string) (m *archive.Manifest, err error) {
+	m, data, err := archive.NewReader(r, password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the subvolume.
+	if err = btrfs.CreateSubvolume(path); err != nil {
+		return nil, err
+	}
+
+	// Remove the subvolume on error.
+	defer func() {
+		if err != nil {
+			if errD := btrfs.DeleteSubvolume(path); errD != nil {
+				log.Errorf("failed to remove subvolume '%s' of failed import: %v", path, errD)
+			}
+		}
+	}()
+
+	// Extract the data.
+	var stderr bytes.Buffer
+	cmd := exec.Command("tar", "--extract", "--file", "-", "--numeric-owner", "--same-permissions", "-C", path)
+	cmd.Stdin = data
+	cmd.Stderr = &stderr
+
+	if err = cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to extract archive: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	// Read the remaining data. This verifies the complete archive.
+	if _, err = io.Copy(ioutil.Discard, data); err != nil {
+		return nil, err
+	}
+
+	// The extracted data must contain the app settings.
+	if e, errE := utils.Exists(path + "/" + settingsFilename); errE != nil {
+		return nil, errE
+	} else if !e {
+		return nil, fmt.Errorf("invalid archive: the app settings file is missing!")
+	}
+
+	return m, nil
+}
+
+// readTurtlefileContent reads the turtlefile in the source directory.
+func readTurtlefileContent(sourcePath string) (string, error) {
+	path := filepath.Join(sourcePath, turtlefile.TurtlefileFilename)
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read turtlefile: %v", err)
+	} else if stat.IsDir() {
+		path = filepath.Join(path, turtlefile.TurtlefileFilename)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read turtlefile: %v", err)
+	}
+
+	return string(data), nil
+}
+
+// isValidBackupTimestamp checks if the timestamp is a valid unix timestamp.
+func isValidBackupTimestamp(timestamp string) bool {
+	_, err := strconv.ParseInt(timestamp, 10, 64)
+	return err == nil
+}
@@ -0,0 +1,343 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+package apps
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/desertbit/turtle/daemon/docker"
+	"github.com/desertbit/turtle/daemon/turtlefile"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	stateRunning   = "running"
+	stateUnhealthy = "unhealthy"
+
+	maxHealthCheckBodySize = 64 * 1024
+)
+
+//#############//
+//### Types ###//
+//#############//
+
+// healthCheckID identifies a single health check of an app container.
+type healthCheckID struct {
+	Container string
+	Index     int // The index of the health check in the container definition.
+}
+
+// byHealthCheckID implements sort.Interface to sort health checks by their container and index.
+type byHealthCheckID []healthCheckID
+
+func (s byHealthCheckID) Len() int      { return len(s) }
+func (s byHealthCheckID) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byHealthCheckID) Less(i, j int) bool {
+	if s[i].Container != s[j].Container {
+		return s[i].Container < s[j].Container
+	}
+	return s[i].Index < s[j].Index
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// startHealthChecks starts the health checks of the container in new goroutines.
+// The checks stop if the stop channel is closed.
+func startHealthChecks(app *App, container *turtlefile.Container, containerID string, stop <-chan struct{}) {
+	for i, h := range container.HealthChecks {
+		id := healthCheckID{Container: container.Name, Index: i}
+		go runHealthCheck(app, id, containerID, h, stop)
+	}
+}
+
+// runHealthCheck probes the container in the health check interval.
+// If the maximum retries are reached, then the container is marked as
+// unhealthy and the app restart check is triggered after each failure.
+func runHealthCheck(app *App, id healthCheckID, containerID string, h *turtlefile.HealthCheck, stop <-chan struct{}) {
+	// Wait for the start period.
+	select {
+	case <-time.After(h.StartPeriod.Duration):
+	case <-stop:
+		return
+	}
+
+	ticker := time.NewTicker(h.Interval.Duration)
+	defer ticker.Stop()
+
+	failures := 0
+
+	for {
+		err := probe(containerID, app.NetworkName(), h)
+
+		// Don't update the health if the checks were stopped during the probe.
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		if err == nil {
+			if failures > 0 {
+				log.Infof("app '%s': container '%s' %s health check passes again.", app.name, id.Container, h.Type)
+				app.setContainerHealth(id, containerID, nil, false)
+			}
+			failures = 0
+		} else {
+			failures++
+
+			log.WithFields(log.Fields{
+				"app":       app.name,
+				"container": id.Container,
+				"failures":  failures,
+			}).Warningf("%s health check failed: %v", h.Type, err)
+
+			// Restart the container if the maximum retries are reached.
+			// The checks are stopped by the restart.
+			unhealthy := failures >= h.Retries
+			app.setContainerHealth(id, containerID, fmt.Errorf("%s check: %v", h.Type, err), unhealthy)
+			if unhealthy {
+				checkRestart(app)
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// probe runs the health check once.
+// The container is reached by its address within the passed network.
+func probe(containerID string, network string, h *turtlefile.HealthCheck) error {
+	if h.Type == turtlefile.HealthCheckExec {
+		exitCode, output, err := docker.Exec(containerID, h.Command, h.Timeout.Duration)
+		if err != nil {
+			return err
+		} else if exitCode != 0 {
+			return fmt.Errorf("command exited with code %v: %s", exitCode, strings.TrimSpace(output))
+		}
+		return nil
+	}
+
+	// Obtain the container address.
+	c, err := docker.Client.InspectContainer(containerID)
+	if err != nil {
+		return err
+	}
+
+	ip := c.NetworkSettings.IPAddress
+	if n, ok := c.NetworkSettings.Networks[network]; ok && len(n.IPAddress) > 0 {
+		ip = n.IPAddress
+	}
+	if c.HostConfig != nil && c.HostConfig.NetworkMode == "host" {
+		ip = "127.0.0.1"
+	} else if len(ip) == 0 {
+		return fmt.Errorf("container has no IP address")
+	}
+
+	addr := net.JoinHostPort(ip, strconv.Itoa(h.Port))
+
+	if h.Type == turtlefile.HealthCheckTCP {
+		conn, err := net.DialTimeout("tcp", addr, h.Timeout.Duration)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	return probeHTTP(addr, h)
+}
+
+// probeHTTP requests the health check path and checks the response.
+func probeHTTP(addr string, h *turtlefile.HealthCheck) error {
+	scheme := "http"
+	if h.HTTPS {
+		scheme = "https"
+	}
+
+	client := &http.Client{
+		Timeout: h.Timeout.Duration,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: h.Insecure},
+			DisableKeepAlives: true,
+		},
+	}
+
+	res, err := client.Get(scheme + "://" + addr + h.Path)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// Check the status code.
+	if h.ExpectStatus != 0 && res.StatusCode != h.ExpectStatus {
+		return fmt.Errorf("unexpected status: %s", res.Status)
+	} else if h.ExpectStatus == 0 && (res.StatusCode < 200 || res.StatusCode >= 400) {
+		return fmt.Errorf("unexpected status: %s", res.Status)
+	}
+
+	// Check the body.
+	if len(h.ExpectBody) > 0 {
+		body, err := ioutil.ReadAll(io.LimitReader(res.Body, maxHealthCheckBodySize))
+		if err != nil {
+			return err
+		} else if !strings.Contains(string(body), h.ExpectBody) {
+			return fmt.Errorf("response body does not contain '%s'", h.ExpectBody)
+		}
+	}
+
+	return nil
+}
+
+//###########################//
+//### Private App methods ###//
+//###########################//
+
+// setContainerHealth updates the result of a container health check and the app state.
+// The error is nil if the check passed. Unhealthy containers are
+// restarted by the next restart check.
+func (a *App) setContainerHealth(id healthCheckID, containerID string, err error, unhealthy bool) {
+	// Lock the mutex.
+	a.healthMutex.Lock()
+	defer a.healthMutex.Unlock()
+
+	if err == nil {
+		delete(a.healthErrors, id)
+	} else {
+		a.healthErrors[id] = err
+	}
+
+	if unhealthy {
+		a.unhealthyContainerIDs[containerID] = true
+	}
+
+	// Update the app state.
+	if len(a.healthErrors) == 0 {
+		if strings.HasPrefix(a.State(), stateUnhealthy) {
+			a.setState(stateRunning)
+		}
+		return
+	}
+
+	a.setState(stateUnhealthy + " (" + a.formatHealthErrors() + ")")
+}
+
+// healthError returns the failed health checks of all containers.
+// nil is returned if all containers are healthy.
+func (a *App) healthError() error {
+	// Lock the mutex.
+	a.healthMutex.Lock()
+	defer a.healthMutex.Unlock()
+
+	if len(a.healthErrors) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%s", a.formatHealthErrors())
+}
+
+// formatHealthErrors joins the health errors sorted by the container names.
+// The health mutex has to be locked.
+func (a *App) formatHealthErrors() string {
+	ids := make([]healthCheckID, 0, len(a.healthErrors))
+	for id := range a.healthErrors {
+		ids = append(ids, id)
+	}
+	sort.Sort(byHealthCheckID(ids))
+
+	var errs []string
+	for _, id := range ids {
+		errs = append(errs, id.Container+": "+a.healthErrors[id].Error())
+	}
+
+	return strings.Join(errs, ", ")
+}
+
+// isUnhealthy returns a boolean whenever the container failed its health checks.
+func (a *App) isUnhealthy(containerID string) bool {
+	// Lock the mutex.
+	a.healthMutex.Lock()
+	defer a.healthMutex.Unlock()
+
+	return a.unhealthyContainerIDs[containerID]
+}
+
+// resetHealth stops all health checks and resets the container health.
+func (a *App) resetHealth() {
+	// Lock the mutex.
+	a.healthMutex.Lock()
+	defer a.healthMutex.Unlock()
+
+	for _, stop := range a.healthStops {
+		close(stop)
+	}
+
+	a.healthStops = make(map[string]chan struct{})
+	a.healthErrors = make(map[healthCheckID]error)
+	a.unhealthyContainerIDs = make(map[string]bool)
+}
+
+// resetContainerHealth stops the health checks of a single container
+// and resets its health.
+func (a *App) resetContainerHealth(containerName string, containerID string) {
+	// Lock the mutex.
+	a.healthMutex.Lock()
+	defer a.healthMutex.Unlock()
+
+	if stop, ok := a.healthStops[containerID]; ok {
+		close(stop)
+		delete(a.healthStops, containerID)
+	}
+
+	for id := range a.healthErrors {
+		if id.Container == containerName {
+			delete(a.healthErrors, id)
+		}
+	}
+	delete(a.unhealthyContainerIDs, containerID)
+}
+
+// healthStopChan returns the stop channel of the container health checks.
+// A new channel is created if not present.
+func (a *App) healthStopChan(containerID string) <-chan struct{} {
+	// Lock the mutex.
+	a.healthMutex.Lock()
+	defer a.healthMutex.Unlock()
+
+	stop, ok := a.healthStops[containerID]
+	if !ok {
+		stop = make(chan struct{})
+		a.healthStops[containerID] = stop
+	}
+
+	return stop
+}
@@ -0,0 +1,361 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+package apps
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/desertbit/turtle/daemon/config"
+	"github.com/desertbit/turtle/daemon/docker"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	logFileExt = ".log"
+
+	// A log file line contains the timestamp and stream prefix and the log message.
+	maxLogFileLineSize = docker.MaxLogLineSize + 64
+
+	logStreamStdout = "stdout"
+	logStreamStderr = "stderr"
+)
+
+var (
+	// The open log writers. The key is the log file path.
+	logWriters      = make(map[string]*logWriter)
+	logWritersMutex sync.Mutex
+)
+
+//##########################//
+//### Public App methods ###//
+//##########################//
+
+// LogsDirectoryPath returns the directory path of the captured container logs.
+func (a *App) LogsDirectoryPath() string {
+	return config.Config.LogPath + "/" + a.name
+}
+
+// ReadLogs reads the captured log lines of an app container.
+// The captured logs are also available after the container was removed.
+// Only the stream, tail and since options are used.
+// The reading stops if f returns false.
+func (a *App) ReadLogs(container string, opts docker.StreamLogsOptions, f func(docker.LogLine) bool) error {
+	// Don't allow to escape the logs directory.
+	if len(container) == 0 || filepath.Base(container) != container {
+		return fmt.Errorf("invalid container name '%s'!", container)
+	}
+
+	path := a.LogsDirectoryPath() + "/" + container + logFileExt
+
+	// The tail lines are kept in a ring buffer.
+	var tail []docker.LogLine
+	stopped := false
+
+	// Filter the lines and pass them to the function.
+	handleLine := func(l docker.LogLine) {
+		if opts.Since > 0 && l.Timestamp.Unix() < opts.Since {
+			return
+		} else if opts.Stream != docker.StdStreamCombined && l.Stream != opts.Stream {
+			return
+		}
+
+		if opts.Tail > 0 {
+			if len(tail) == opts.Tail {
+				tail = tail[1:]
+			}
+			tail = append(tail, l)
+		} else if !f(l) {
+			stopped = true
+		}
+	}
+
+	// Read the rotated files first, beginning with the oldest one.
+	paths := []string{path}
+	for i := 1; i <= config.Config.LogMaxFiles; i++ {
+		paths = append([]string{path + "." + strconv.Itoa(i)}, paths...)
+	}
+
+	found := false
+	for _, p := range paths {
+		if stopped {
+			return nil
+		}
+
+		err := readLogFile(p, handleLine, &stopped)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to read log file '%s': %v", p, err)
+		}
+
+		found = true
+	}
+
+	if !found {
+		return fmt.Errorf("no captured logs available for container '%s'!", container)
+	}
+
+	// Pass the tail lines.
+	for _, l := range tail {
+		if !f(l) {
+			break
+		}
+	}
+
+	return nil
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// captureLogs starts a goroutine which writes the container
+// log lines to the container log file.
+// The capture stops as soon as the container stops.
+func captureLogs(app *App, containerName string, containerID string) {
+	go func() {
+		path := app.LogsDirectoryPath() + "/" + containerName + logFileExt
+
+		// Obtain the log writer.
+		w, err := acquireLogWriter(path)
+		if err != nil {
+			log.Errorf("failed to capture logs of app '%s' container '%s': %v", app.name, containerName, err)
+			return
+		}
+		defer releaseLogWriter(w)
+
+		// Stream the logs to the file.
+		opts := docker.StreamLogsOptions{
+			Stream: docker.StdStreamCombined,
+			Follow: true,
+		}
+
+		err = docker.StreamLogs(containerID, opts, func(l docker.LogLine) bool {
+			if err := w.write(l); err != nil {
+				log.Errorf("failed to capture logs of app '%s' container '%s': %v", app.name, containerName, err)
+				return false
+			}
+			return true
+		})
+		if err != nil {
+			log.Warningf("log capture of app '%s' container '%s' stopped: %v", app.name, containerName, err)
+		}
+	}()
+}
+
+// readLogFile reads the log lines of the file and
+// passes them to the function until stopped is set.
+func readLogFile(path string, f func(docker.LogLine), stopped *bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxLogFileLineSize)
+
+	for scanner.Scan() && !*stopped {
+		// Skip invalid lines.
+		l, ok := parseLogFileLine(scanner.Text())
+		if !ok {
+			continue
+		}
+
+		f(l)
+	}
+
+	return scanner.Err()
+}
+
+// parseLogFileLine parses a log file line in the format: TIMESTAMP STREAM MESSAGE
+func parseLogFileLine(s string) (l docker.LogLine, ok bool) {
+	fields := strings.SplitN(s, " ", 3)
+	if len(fields) != 3 {
+		return l, false
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, fields[0])
+	if err != nil {
+		return l, false
+	}
+
+	l.Timestamp = t
+	l.Message = fields[2]
+
+	if fields[1] == logStreamStderr {
+		l.Stream = docker.StdStreamError
+	} else {
+		l.Stream = docker.StdStreamOutput
+	}
+
+	return l, true
+}
+
+//##################//
+//### Log writer ###//
+//##################//
+
+// logWriter writes log lines to a size-capped and rotated log file.
+// Only one writer exists for each file path, because a previous
+// container capture might still be active during a restart.
+type logWriter struct {
+	path  string
+	refs  int
+	file  *os.File
+	size  int64
+	mutex sync.Mutex
+}
+
+// acquireLogWriter returns the log writer of the file path.
+// The writer has to be released with releaseLogWriter.
+func acquireLogWriter(path string) (*logWriter, error) {
+	// Lock the mutex.
+	logWritersMutex.Lock()
+	defer logWritersMutex.Unlock()
+
+	// Return the already opened writer.
+	if w, ok := logWriters[path]; ok {
+		w.refs++
+		return w, nil
+	}
+
+	w := &logWriter{
+		path: path,
+		refs: 1,
+	}
+
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	logWriters[path] = w
+
+	return w, nil
+}
+
+// releaseLogWriter closes the log writer if it is not used anymore.
+func releaseLogWriter(w *logWriter) {
+	// Lock the mutex.
+	logWritersMutex.Lock()
+	defer logWritersMutex.Unlock()
+
+	w.refs--
+	if w.refs > 0 {
+		return
+	}
+
+	delete(logWriters, w.path)
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if err := w.file.Close(); err != nil {
+		log.Warningf("failed to close log file '%s': %v", w.path, err)
+	}
+}
+
+// open opens the log file in append mode.
+func (w *logWriter) open() error {
+	// Create the logs directory if not present.
+	if err := os.MkdirAll(filepath.Dir(w.path), 0750); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return err
+	}
+
+	// Obtain the current file size.
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	w.file = file
+	w.size = stat.Size()
+
+	return nil
+}
+
+// write writes the log line to the file and rotates the file if required.
+func (w *logWriter) write(l docker.LogLine) error {
+	// Lock the mutex.
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	stream := logStreamStdout
+	if l.Stream == docker.StdStreamError {
+		stream = logStreamStderr
+	}
+
+	line := l.Timestamp.Format(time.RFC3339Nano) + " " + stream + " " + l.Message + "\n"
+
+	// Rotate the file if the maximum size would be exceeded.
+	if w.size > 0 && w.size+int64(len(line)) > config.Config.LogMaxSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := w.file.WriteString(line)
+	w.size += int64(n)
+
+	return err
+}
+
+// rotate moves the current log file to the first rotated file
+// and removes the oldest file if the maximum count is reached.
+func (w *logWriter) rotate() error {
+	// Close the current file.
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+
+	if config.Config.LogMaxFiles == 0 {
+		// Don't keep any rotated files.
+		if err := os.Remove(w.path); err != nil {
+			return err
+		}
+	} else {
+		// Shift the rotated files. The oldest file is overwritten.
+		for i := config.Config.LogMaxFiles - 1; i >= 1; i-- {
+			err := os.Rename(w.path+"."+strconv.Itoa(i), w.path+"."+strconv.Itoa(i+1))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+
+		if err := os.Rename(w.path, w.path+".1"); err != nil {
+			return err
+		}
+	}
+
+	// Open a new log file.
+	return w.open()
+}
@@ -0,0 +1,437 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+package apps
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/desertbit/turtle/daemon/btrfs"
+	"github.com/desertbit/turtle/daemon/config"
+	"github.com/desertbit/turtle/daemon/docker"
+	"github.com/desertbit/turtle/utils"
+
+	"github.com/BurntSushi/toml"
+	log "github.com/Sirupsen/logrus"
+)
+
+var (
+	// Cache of the btrfs subvolume IDs of the read-only backups. The key is the subvolume path.
+	// The app subvolume is not cached, because restores replace it at the same path.
+	subvolumeIDs      = make(map[string]uint64)
+	subvolumeIDsMutex sync.Mutex
+
+	// Only warn once if the btrfs quotas are disabled.
+	qgroupsWarned bool
+)
+
+//#############//
+//### Types ###//
+//#############//
+
+// MetricsSample is a resource usage sample of an app.
+type MetricsSample struct {
+	Time time.Time
+
+	CPUPercent  float64 // Summed CPU usage of all containers. 100 percent equals one CPU.
+	MemoryUsage uint64
+	MemoryLimit uint64
+
+	NetworkRxRate float64 // Received bytes per second.
+	NetworkTxRate float64 // Transmitted bytes per second.
+
+	Storage          uint64 // Bytes referenced by the app subvolume.
+	StorageExclusive uint64 // Bytes only used by the app subvolume.
+	BackupsStorage   uint64 // Bytes only used by the app backups.
+}
+
+// ContainerMetrics is the current resource usage of an app container.
+type ContainerMetrics struct {
+	Name string
+
+	CPUPercent  float64
+	MemoryUsage uint64
+	MemoryLimit uint64
+
+	NetworkRx uint64 // Received bytes since the container started.
+	NetworkTx uint64 // Transmitted bytes since the container started.
+}
+
+// appMetrics holds the resource usage history of an app.
+type appMetrics struct {
+	History []MetricsSample `toml:"Sample"`
+
+	loaded     bool
+	expired    int // The number of expired samples, which are still in the metrics file.
+	containers []ContainerMetrics
+	prevTime   time.Time
+	prevStats  map[string]*docker.ContainerStats // The key is the container ID.
+
+	// Counters since the daemon started.
+	restarts           int64
+	lastBackupDuration time.Duration
+
+	mutex sync.Mutex
+}
+
+//##############//
+//### Public ###//
+//##############//
+
+// CollectMetrics samples the resource usage of all apps.
+func CollectMetrics() {
+	// Obtain the disk usage of all subvolumes.
+	qgroups, err := btrfs.Qgroups(config.TurtleRoot)
+	if err != nil {
+		if !qgroupsWarned {
+			log.Warningf("storage metrics are disabled: %v", err)
+			qgroupsWarned = true
+		}
+		qgroups = nil
+	}
+
+	for _, a := range Apps() {
+		if err = a.collectMetrics(qgroups); err != nil {
+			log.Warningf("failed to collect metrics of app '%s': %v", a.name, err)
+		}
+	}
+}
+
+//##########################//
+//### Public App methods ###//
+//##########################//
+
+// MetricsFilePath returns the file path of the app resource usage history.
+func (a *App) MetricsFilePath() string {
+	return config.Config.MetricsDirPath() + "/" + a.name
+}
+
+// Metrics returns the resource usage history sorted by time and
+// the current resource usage of the running app containers.
+func (a *App) Metrics() ([]MetricsSample, []ContainerMetrics, error) {
+	// Lock the mutex.
+	a.metrics.mutex.Lock()
+	defer a.metrics.mutex.Unlock()
+
+	// Load the history from file if required.
+	if err := a.loadMetrics(); err != nil {
+		return nil, nil, err
+	}
+
+	history := make([]MetricsSample, len(a.metrics.History))
+	copy(history, a.metrics.History)
+
+	containers := make([]ContainerMetrics, len(a.metrics.containers))
+	copy(containers, a.metrics.containers)
+
+	return history, containers, nil
+}
+
+// RestartCount returns the number of automatic app restarts since the daemon started.
+func (a *App) RestartCount() int64 {
+	// Lock the mutex.
+	a.metrics.mutex.Lock()
+	defer a.metrics.mutex.Unlock()
+
+	return a.metrics.restarts
+}
+
+// LastBackupDuration returns the duration of the last backup.
+// 0 is returned if no backup was created since the daemon started.
+func (a *App) LastBackupDuration() time.Duration {
+	// Lock the mutex.
+	a.metrics.mutex.Lock()
+	defer a.metrics.mutex.Unlock()
+
+	return a.metrics.lastBackupDuration
+}
+
+//###########################//
+//### Private App methods ###//
+//###########################//
+
+// addRestart increments the restart counter.
+func (a *App) addRestart() {
+	// Lock the mutex.
+	a.metrics.mutex.Lock()
+	defer a.metrics.mutex.Unlock()
+
+	a.metrics.restarts++
+}
+
+// setLastBackupDuration sets the duration of the last backup.
+func (a *App) setLastBackupDuration(d time.Duration) {
+	// Lock the mutex.
+	a.metrics.mutex.Lock()
+	defer a.metrics.mutex.Unlock()
+
+	a.metrics.lastBackupDuration = d
+}
+
+// collectMetrics samples the current resource usage and adds it to the history.
+func (a *App) collectMetrics(qgroups map[uint64]btrfs.QgroupUsage) error {
+	// Obtain the previous sample state.
+	// The mutex is not locked during the docker and btrfs calls.
+	a.metrics.mutex.Lock()
+	prevStats := a.metrics.prevStats
+	prevTime := a.metrics.prevTime
+	a.metrics.mutex.Unlock()
+
+	now := time.Now()
+	sample := MetricsSample{Time: now}
+	elapsed := now.Sub(prevTime).Seconds()
+
+	// Sample the app containers.
+	var containers []ContainerMetrics
+	containerStats := make(map[string]*docker.ContainerStats)
+
+	containerIDs := make([]string, len(a.containerIDs))
+	copy(containerIDs, a.containerIDs)
+
+	for _, id := range containerIDs {
+		// Skip containers which are currently restarted.
+		if len(id) == 0 {
+			continue
+		}
+
+		// Skip containers which can't be sampled. They might be stopped in the meantime.
+		c, err := docker.Client.InspectContainer(id)
+		if err != nil {
+			log.Warningf("app '%s': failed to sample container '%s': %v", a.name, id, err)
+			continue
+		} else if !c.State.Running {
+			continue
+		}
+
+		stats, err := docker.Stats(id)
+		if err != nil {
+			log.Warningf("app '%s': failed to sample container '%s': %v", a.name, id, err)
+			continue
+		}
+		containerStats[id] = stats
+
+		cm := ContainerMetrics{
+			Name:        strings.TrimPrefix(strings.TrimPrefix(c.Name, "/"), a.ContainerNamePrefix()),
+			MemoryUsage: stats.MemoryUsage,
+			MemoryLimit: stats.MemoryLimit,
+			NetworkRx:   stats.NetworkRx,
+			NetworkTx:   stats.NetworkTx,
+		}
+
+		// The CPU usage and network rates are calculated from the previous sample.
+		// Skip them if the container was restarted.
+		prev, ok := prevStats[id]
+		if ok && stats.SystemCPUUsage > prev.SystemCPUUsage && stats.CPUUsage >= prev.CPUUsage {
+			cm.CPUPercent = float64(stats.CPUUsage-prev.CPUUsage) /
+				float64(stats.SystemCPUUsage-prev.SystemCPUUsage) * float64(stats.NumCPUs) * 100
+		}
+		if ok && elapsed > 0 && stats.NetworkRx >= prev.NetworkRx && stats.NetworkTx >= prev.NetworkTx {
+			sample.NetworkRxRate += float64(stats.NetworkRx-prev.NetworkRx) / elapsed
+			sample.NetworkTxRate += float64(stats.NetworkTx-prev.NetworkTx) / elapsed
+		}
+
+		sample.CPUPercent += cm.CPUPercent
+		sample.MemoryUsage += cm.MemoryUsage
+		sample.MemoryLimit += cm.MemoryLimit
+
+		containers = append(containers, cm)
+	}
+
+	// Obtain the disk usage of the app and its backups.
+	if qgroups != nil {
+		if id, err := btrfs.SubvolumeID(a.path); err != nil {
+			log.Warningf("app '%s': %v", a.name, err)
+		} else {
+			sample.Storage = qgroups[id].Referenced
+			sample.StorageExclusive = qgroups[id].Exclusive
+		}
+
+		backups, err := a.Backups()
+		if err != nil {
+			log.Warningf("app '%s': %v", a.name, err)
+		}
+
+		for _, b := range backups {
+			id, err := subvolumeID(a.BackupDirectoryPath() + "/" + b)
+			if err != nil {
+				log.Warningf("app '%s': %v", a.name, err)
+				continue
+			}
+
+			sample.BackupsStorage += qgroups[id].Exclusive
+		}
+	}
+
+	// Lock the mutex.
+	a.metrics.mutex.Lock()
+	defer a.metrics.mutex.Unlock()
+
+	// Load the history from file if required.
+	if err := a.loadMetrics(); err != nil {
+		return err
+	}
+
+	a.metrics.containers = containers
+	a.metrics.prevStats = containerStats
+	a.metrics.prevTime = now
+
+	// Add the sample and remove all expired samples.
+	expire := now.Add(-config.Config.MetricsHistoryDuration)
+	history := append(a.metrics.History, sample)
+	for len(history) > 0 && history[0].Time.Before(expire) {
+		history = history[1:]
+		a.metrics.expired++
+	}
+	a.metrics.History = history
+
+	// Only append the sample to the metrics file. The file is rewritten
+	// if it contains too many expired samples.
+	if a.metrics.expired > len(history)/4 {
+		return a.saveMetrics()
+	}
+
+	return a.appendMetrics(sample)
+}
+
+// loadMetrics loads the history from the metrics file once.
+// This method won't lock the metrics mutex. You have to handle it!
+func (a *App) loadMetrics() error {
+	if a.metrics.loaded {
+		return nil
+	}
+
+	e, err := utils.Exists(a.MetricsFilePath())
+	if err != nil {
+		return err
+	} else if e {
+		_, err = toml.DecodeFile(a.MetricsFilePath(), &a.metrics)
+		if err != nil {
+			return fmt.Errorf("failed to load app metrics file '%s': %v", a.MetricsFilePath(), err)
+		}
+	}
+
+	a.metrics.loaded = true
+
+	return nil
+}
+
+// saveMetrics saves the history to the metrics file.
+// This method won't lock the metrics mutex. You have to handle it!
+func (a *App) saveMetrics() error {
+	// Create the metrics directory if not present.
+	err := utils.MkDirIfNotExists(config.Config.MetricsDirPath())
+	if err != nil {
+		return err
+	}
+
+	// Encode the history to TOML.
+	buf := new(bytes.Buffer)
+	err = toml.NewEncoder(buf).Encode(&a.metrics)
+	if err != nil {
+		return fmt.Errorf("failed to encode app metrics to toml: %v", err)
+	}
+
+	// Write the result to the app metrics file.
+	err = ioutil.WriteFile(a.MetricsFilePath(), buf.Bytes(), 0600)
+	if err != nil {
+		return fmt.Errorf("failed to save app metrics file: %v", err)
+	}
+
+	a.metrics.expired = 0
+
+	return nil
+}
+
+// appendMetrics appends the sample to the metrics file.
+// This method won't lock the metrics mutex. You have to handle it!
+func (a *App) appendMetrics(sample MetricsSample) error {
+	// Create the metrics directory if not present.
+	err := utils.MkDirIfNotExists(config.Config.MetricsDirPath())
+	if err != nil {
+		return err
+	}
+
+	// Encode the sample to TOML. The sample table is appended to the history tables.
+	buf := new(bytes.Buffer)
+	err = toml.NewEncoder(buf).Encode(&appMetrics{History: []MetricsSample{sample}})
+	if err != nil {
+		return fmt.Errorf("failed to encode app metrics to toml: %v", err)
+	}
+
+	// Append the result to the app metrics file.
+	file, err := os.OpenFile(a.MetricsFilePath(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open app metrics file: %v", err)
+	}
+	defer file.Close()
+
+	_, err = file.Write(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to append to app metrics file: %v", err)
+	}
+
+	return nil
+}
+
+// removeMetrics removes the metrics file of the app.
+func (a *App) removeMetrics() error {
+	err := os.Remove(a.MetricsFilePath())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// subvolumeID returns the cached btrfs ID of the subvolume.
+func subvolumeID(path string) (uint64, error) {
+	// Lock the mutex.
+	subvolumeIDsMutex.Lock()
+	defer subvolumeIDsMutex.Unlock()
+
+	if id, ok := subvolumeIDs[path]; ok {
+		return id, nil
+	}
+
+	id, err := btrfs.SubvolumeID(path)
+	if err != nil {
+		return 0, err
+	}
+
+	subvolumeIDs[path] = id
+
+	return id, nil
+}
+
+// forgetSubvolumeID removes the subvolume from the ID cache.
+func forgetSubvolumeID(path string) {
+	// Lock the mutex.
+	subvolumeIDsMutex.Lock()
+	defer subvolumeIDsMutex.Unlock()
+
+	delete(subvolumeIDs, path)
+}
@@ -0,0 +1,119 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package apps
+
+import (
+	"github.com/desertbit/turtle/daemon/docker"
+	"github.com/desertbit/turtle/daemon/turtlefile"
+)
+
+const (
+	sharedNetworkPrefix = "shared."
+)
+
+//##########################//
+//### Public App methods ###//
+//##########################//
+
+// NetworkName returns the name of the app's user-defined network.
+// All app containers with the default network mode are attached to it.
+func (a *App) NetworkName() string {
+	return docker.TurtlePrefix + a.name
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// sharedNetworkName returns the docker network name of a shared network.
+func sharedNetworkName(name string) string {
+	return docker.TurtlePrefix + sharedNetworkPrefix + name
+}
+
+// sharedNetworks returns the shared networks of the container.
+// Temporary update clones are not attached to shared networks.
+func (a *App) sharedNetworks(c *turtlefile.Container) []string {
+	if a.origin != nil {
+		return nil
+	}
+
+	return c.Networks
+}
+
+// setupNetworks creates the app network and the shared networks if not present.
+func setupNetworks(app *App, t *turtlefile.Turtlefile) error {
+	// Create the app network.
+	err := docker.CreateNetwork(app.NetworkName())
+	if err != nil {
+		return err
+	}
+
+	// Connect the daemon to the app network.
+	// The health checks have to reach the app containers.
+	err = docker.ConnectDaemon(app.NetworkName())
+	if err != nil {
+		return err
+	}
+
+	// Create the shared networks.
+	for _, c := range t.Containers {
+		for _, n := range app.sharedNetworks(c) {
+			err = docker.CreateNetwork(sharedNetworkName(n))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// removeNetworks removes the app network and the shared networks.
+// Shared networks which are still used by other apps are kept.
+func removeNetworks(app *App) error {
+	// Disconnect the daemon first, otherwise the network is still in use.
+	err := docker.DisconnectDaemon(app.NetworkName())
+	if err != nil {
+		return err
+	}
+
+	// Remove the app network.
+	err = docker.RemoveNetwork(app.NetworkName())
+	if err != nil {
+		return err
+	}
+
+	// Get the turtlefile.
+	t, err := app.Turtlefile()
+	if err != nil {
+		return err
+	}
+
+	// Remove the shared networks.
+	for _, c := range t.Containers {
+		for _, n := range app.sharedNetworks(c) {
+			err = docker.RemoveNetwork(sharedNetworkName(n))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package apps
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/desertbit/turtle/daemon/turtlefile"
+	"github.com/desertbit/turtle/utils"
+)
+
+//##########################//
+//### UpdatePreview type ###//
+//##########################//
+
+// UpdatePreview describes the changes an update would apply.
+type UpdatePreview struct {
+	Commit   string     // The deployed source commit.
+	Target   string     // The commit the update would check out.
+	Commits  []Revision // Commits which would be applied.
+	Reverted []Revision // Deployed commits which are not part of the target.
+
+	// Changes of the Turtlefile. Nil if the new Turtlefile is invalid.
+	Changes *turtlefile.Changes
+
+	Warnings []string
+}
+
+//##########################//
+//### Public App methods ###//
+//##########################//
+
+// UpdatePreview fetches the upstream source without applying it and returns
+// the changes an update would apply. If the revision is empty, then the
+// pinned revision or the latest commit of the branch is used.
+func (a *App) UpdatePreview(revision string) (*UpdatePreview, error) {
+	if a.IsUpdating() || a.task == taskUpdate {
+		return nil, fmt.Errorf("the app is updating!")
+	} else if a.task == taskCloneSource {
+		return nil, fmt.Errorf("the app source is not cloned yet!")
+	} else if !isValidRevision(revision) {
+		return nil, fmt.Errorf("invalid revision '%s'!", revision)
+	}
+
+	// Obtain the target revision.
+	if len(revision) == 0 {
+		revision = a.settings.Pin
+		if len(revision) == 0 {
+			revision = "origin/" + a.settings.Branch
+		}
+	}
+
+	path := a.SourceDirectoryPath()
+
+	// Fetch the upstream commits and tags.
+	err := fetchSource(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &UpdatePreview{}
+
+	// Resolve the deployed and the target commits.
+	p.Commit, err = sourceCommit(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Target, err = resolveRevision(path, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	// Obtain the commit logs.
+	p.Commits, err = gitLog(path, p.Commit+".."+p.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Reverted, err = gitLog(path, p.Target+".."+p.Commit)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p.Reverted) > 0 {
+		p.addWarning("the target revision does not contain %v of the deployed commits", len(p.Reverted))
+	}
+
+	// Load the current and the new turtlefile.
+	oldT, err := a.Turtlefile()
+	if err != nil {
+		return nil, err
+	}
+
+	newT, err := readTurtlefile(path, p.Target)
+	if err != nil {
+		p.addWarning("%v", err)
+		return p, nil
+	}
+
+	// Compare both turtlefiles.
+	p.Changes = turtlefile.Compare(oldT, newT)
+
+	// Warn about required environment variables which are not set.
+	for _, env := range newT.Env {
+		if !env.Required {
+			continue
+		}
+
+		v, ok := a.settings.Env[env.Name]
+		if !ok || len(v) == 0 {
+			p.addWarning("the required environment variable '%s' is not set: setup the app before updating", env.Name)
+		}
+	}
+
+	// Warn about new ports, which are not published until setup.
+	for _, port := range p.Changes.AddedPorts {
+		p.addWarning("the new port '%s' is not published: setup the app to publish it", port)
+	}
+
+	return p, nil
+}
+
+//#####################################//
+//### Private UpdatePreview methods ###//
+//#####################################//
+
+func (p *UpdatePreview) addWarning(format string, args ...interface{}) {
+	p.Warnings = append(p.Warnings, fmt.Sprintf(format, args...))
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// readTurtlefile reads and validates the turtlefile of the source commit
+// without checking it out.
+func readTurtlefile(path, commit string) (*turtlefile.Turtlefile, error) {
+	// The turtlefile might be a directory containing the turtlefile.
+	filePath := turtlefile.TurtlefileFilename
+	objType, err := utils.RunCommandOutputInPath(path, "git", "cat-file", "-t", commit+":"+filePath)
+	if err != nil {
+		return nil, fmt.Errorf("Turtlefile is missing in the target revision!")
+	} else if strings.TrimSpace(objType) == "tree" {
+		filePath += "/" + turtlefile.TurtlefileFilename
+	}
+
+	// Read the turtlefile content.
+	data, err := utils.RunCommandOutputInPath(path, "git", "show", commit+":"+filePath)
+	if err != nil {
+		return nil, fmt.Errorf("Turtlefile is missing in the target revision!")
+	}
+
+	// Parse the turtlefile.
+	t, err := turtlefile.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("the new turtlefile is invalid: %v", err)
+	}
+
+	// Check if the turtlefile is valid.
+	if err = t.IsValid(); err != nil {
+		return nil, fmt.Errorf("the new turtlefile is invalid: %v", err)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,150 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package apps
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/desertbit/turtle/daemon/btrfs"
+	"github.com/desertbit/turtle/utils"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//##############//
+//### Public ###//
+//##############//
+
+// ReceiveApp creates a new app from the btrfs send stream of a replicated backup.
+// The backup is added to the app backups and restored as app data.
+// The new app is not started.
+func ReceiveApp(name, timestamp string, r io.Reader) (err error) {
+	// The name is used as directory name.
+	if strings.ContainsRune(name, '/') || name == "." || name == ".." {
+		return fmt.Errorf("invalid app name '%s'!", name)
+	}
+
+	a, err := newApp(name)
+	if err != nil {
+		return err
+	}
+
+	// Check if an app with the same name already exists.
+	if _, errG := Get(name); errG == nil {
+		return fmt.Errorf("an App with the name '%s' already exists!", name)
+	} else if e, errE := utils.Exists(a.path); errE != nil {
+		return errE
+	} else if e {
+		return fmt.Errorf("the app directory '%s' already exists!", a.path)
+	}
+
+	log.Infof("receiving app '%s' from replicated backup '%s'", name, timestamp)
+
+	// Receive the backup.
+	if err = a.ReceiveBackup(timestamp, r); err != nil {
+		return err
+	}
+
+	// Create the app subvolume from the received backup.
+	if err = btrfs.Snapshot(a.BackupDirectoryPath()+"/"+timestamp, a.path, false); err != nil {
+		return fmt.Errorf("failed to restore app '%s': %v", name, err)
+	}
+
+	// Remove the app subvolume on error.
+	// The received backup is kept.
+	defer func() {
+		if err != nil {
+			if errD := btrfs.DeleteSubvolume(a.path); errD != nil {
+				log.Errorf("failed to cleanup failed receive of app '%s': %v", name, errD)
+			}
+		}
+	}()
+
+	// Load the app settings.
+	if err = a.loadSettings(); err != nil {
+		return err
+	}
+
+	// Lock the mutex.
+	appsMutex.Lock()
+	defer appsMutex.Unlock()
+
+	// Check again, because the mutex was not locked during the receive.
+	if _, ok := apps[name]; ok {
+		return fmt.Errorf("an App with the name '%s' already exists!", name)
+	}
+
+	// Finally add the app to the map.
+	apps[name] = a
+
+	return nil
+}
+
+//##########################//
+//### Public App methods ###//
+//##########################//
+
+// SendBackup writes the btrfs send stream of the backup to w.
+// The stream contains only the changes to the parent backup if set.
+func (a *App) SendBackup(timestamp, parent string, w io.Writer) error {
+	// Check if the backups exist.
+	if !a.BackupExists(timestamp) {
+		return fmt.Errorf("no backup '%s' found!", timestamp)
+	} else if len(parent) > 0 && !a.BackupExists(parent) {
+		return fmt.Errorf("no parent backup '%s' found!", parent)
+	}
+
+	parentPath := ""
+	if len(parent) > 0 {
+		parentPath = a.BackupDirectoryPath() + "/" + parent
+	}
+
+	return btrfs.Send(a.BackupDirectoryPath()+"/"+timestamp, parentPath, w)
+}
+
+// ReceiveBackup receives the btrfs send stream of a replicated backup.
+// The backup is added with its original timestamp.
+func (a *App) ReceiveBackup(timestamp string, r io.Reader) error {
+	if !isValidBackupTimestamp(timestamp) {
+		return fmt.Errorf("invalid backup timestamp '%s'!", timestamp)
+	}
+
+	// Get the app's base backup folder.
+	backupPath := a.BackupDirectoryPath()
+
+	// Create the base app backup folder if not present.
+	err := utils.MkDirIfNotExists(backupPath)
+	if err != nil {
+		return err
+	}
+
+	path := backupPath + "/" + timestamp
+
+	if e, err := utils.Exists(path); err != nil {
+		return err
+	} else if e {
+		return fmt.Errorf("backup '%s' already exists!", timestamp)
+	}
+
+	log.Infof("receiving backup of app '%s': %s", a.name, path)
+
+	return btrfs.Receive(backupPath, timestamp, r)
+}
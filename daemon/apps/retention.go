@@ -0,0 +1,190 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package apps
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/desertbit/turtle/daemon/config"
+	"github.com/desertbit/turtle/utils"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	// The file in the app backup directory containing the pinned backups.
+	pinnedBackupsFilename = "pinned"
+)
+
+type pinnedBackups struct {
+	Backups []string // The timestamps of the pinned backups.
+}
+
+//##########################//
+//### Public App methods ###//
+//##########################//
+
+// Retention returns the effective backup retention policy of the app.
+// The boolean is true if the app overwrites the daemon default.
+func (a *App) Retention() (config.Retention, bool) {
+	if len(a.settings.Retention) > 0 {
+		r, err := config.ParseRetention(a.settings.Retention)
+		if err == nil {
+			return r, true
+		}
+	}
+
+	return config.Config.DefaultRetention(), false
+}
+
+// SetRetention sets the backup retention policy of the app.
+// Pass an empty policy to use the daemon default.
+func (a *App) SetRetention(policy string) error {
+	if len(policy) > 0 {
+		r, err := config.ParseRetention(policy)
+		if err != nil {
+			return err
+		}
+		policy = r.String()
+	}
+
+	a.settings.Retention = policy
+
+	return a.saveSettings()
+}
+
+// PinnedBackups returns the timestamps of the pinned backups.
+// Pinned backups are never removed by the retention policy.
+func (a *App) PinnedBackups() (map[string]bool, error) {
+	// Lock the mutex.
+	a.pinnedBackupsMutex.Lock()
+	defer a.pinnedBackupsMutex.Unlock()
+
+	p, err := a.loadPinnedBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	pinned := make(map[string]bool)
+	for _, b := range p.Backups {
+		pinned[b] = true
+	}
+
+	return pinned, nil
+}
+
+// PinBackup pins or unpins the backup.
+func (a *App) PinBackup(timestamp string, pin bool) error {
+	if pin && !a.BackupExists(timestamp) {
+		return fmt.Errorf("no backup '%s' found!", timestamp)
+	}
+
+	// Lock the mutex.
+	a.pinnedBackupsMutex.Lock()
+	defer a.pinnedBackupsMutex.Unlock()
+
+	p, err := a.loadPinnedBackups()
+	if err != nil {
+		return err
+	}
+
+	// Check if the backup is already pinned.
+	index := -1
+	for i, b := range p.Backups {
+		if b == timestamp {
+			index = i
+			break
+		}
+	}
+
+	// Skip if nothing changed.
+	if pin == (index >= 0) {
+		return nil
+	}
+
+	if pin {
+		p.Backups = append(p.Backups, timestamp)
+		sort.Strings(p.Backups)
+	} else {
+		p.Backups = append(p.Backups[:index], p.Backups[index+1:]...)
+	}
+
+	return a.savePinnedBackups(p)
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// loadPinnedBackups loads the pinned backups file.
+// This won't lock the pinned backups mutex. You have to handle it!
+func (a *App) loadPinnedBackups() (*pinnedBackups, error) {
+	var p pinnedBackups
+
+	path := a.BackupDirectoryPath() + "/" + pinnedBackupsFilename
+
+	// Skip if it does not exists.
+	e, err := utils.Exists(path)
+	if err != nil {
+		return nil, err
+	} else if !e {
+		return &p, nil
+	}
+
+	// Load and decode the file.
+	_, err = toml.DecodeFile(path, &p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pinned backups file '%s': %v", path, err)
+	}
+
+	return &p, nil
+}
+
+// savePinnedBackups saves the pinned backups file.
+// The file is removed if no backup is pinned.
+// This won't lock the pinned backups mutex. You have to handle it!
+func (a *App) savePinnedBackups(p *pinnedBackups) error {
+	path := a.BackupDirectoryPath() + "/" + pinnedBackupsFilename
+
+	if len(p.Backups) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove pinned backups file: %v", err)
+		}
+		return nil
+	}
+
+	// Encode the value to TOML.
+	buf := new(bytes.Buffer)
+	err := toml.NewEncoder(buf).Encode(p)
+	if err != nil {
+		return fmt.Errorf("failed to encode pinned backups to toml: %v", err)
+	}
+
+	// Write the result to the file.
+	err = ioutil.WriteFile(path, buf.Bytes(), 0600)
+	if err != nil {
+		return fmt.Errorf("failed to save pinned backups file: %v", err)
+	}
+
+	return nil
+}
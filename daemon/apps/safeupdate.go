@@ -0,0 +1,553 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package apps
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/desertbit/turtle/daemon/btrfs"
+	"github.com/desertbit/turtle/daemon/config"
+	"github.com/desertbit/turtle/daemon/docker"
+	"github.com/desertbit/turtle/daemon/turtlefile"
+	"github.com/desertbit/turtle/utils"
+
+	log "github.com/Sirupsen/logrus"
+	d "github.com/fsouza/go-dockerclient"
+)
+
+const (
+	updateCloneSuffix   = ".update"
+	updateTaskTimeout   = 5 * time.Minute
+	updateCheckInterval = time.Second
+
+	maxPort = 65535
+)
+
+//##########################//
+//### Public App methods ###//
+//##########################//
+
+// IsUpdating returns a boolean whenever a safe update is in progress.
+func (a *App) IsUpdating() bool {
+	// Lock the mutex.
+	a.updateMutex.Lock()
+	defer a.updateMutex.Unlock()
+
+	return a.updating
+}
+
+// UpdateState returns the state of the current or last safe update
+// and its error. The state is empty if no safe update was performed.
+func (a *App) UpdateState() (string, error) {
+	// Lock the mutex.
+	a.updateMutex.Lock()
+	defer a.updateMutex.Unlock()
+
+	return a.updateState, a.updateErr
+}
+
+// SafeUpdate updates the app in the background. The update is verified
+// with a temporary clone of the app before the app is replaced. The
+// pre-update snapshot and images are restored if the updated app fails
+// to become healthy. Running apps keep running during the verification.
+func (a *App) SafeUpdate() error {
+	err := a.canSafeUpdate()
+	if err != nil {
+		return err
+	}
+
+	// Lock the mutex.
+	a.updateMutex.Lock()
+	defer a.updateMutex.Unlock()
+
+	if a.updating {
+		return fmt.Errorf("the app is already updating!")
+	}
+
+	// Set the flag and reset the previous state.
+	a.updating = true
+	a.updateState = "starting safe update..."
+	a.updateErr = nil
+
+	go func() {
+		// Perform the actual update.
+		err := safeUpdate(a)
+
+		// Lock the mutex.
+		a.updateMutex.Lock()
+		defer a.updateMutex.Unlock()
+
+		// Reset the flag and set the final state.
+		a.updating = false
+		if err != nil {
+			a.updateState = "failed"
+			a.updateErr = err
+			log.Errorf("app '%s': safe update failed: %v", a.name, err)
+		} else {
+			a.updateState = "done"
+			log.Infof("app '%s': safe update done.", a.name)
+		}
+	}()
+
+	return nil
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// updateImage is a docker image which is replaced by an update.
+type updateImage struct {
+	Name      string
+	Tag       string
+	BuildPath string // Set if the image is built from the local source.
+}
+
+// canSafeUpdate checks if a safe update of the app can be started.
+func (a *App) canSafeUpdate() error {
+	if !a.IsSetup() {
+		return fmt.Errorf("you have to setup the app first!")
+	} else if a.IsTaskRunning() && !a.IsRunning() {
+		return fmt.Errorf("another task is running!")
+	} else if a.IsUpdating() {
+		return fmt.Errorf("the app is already updating!")
+	}
+
+	// The clone must not conflict with another app.
+	if _, err := Get(a.name + updateCloneSuffix); err == nil {
+		return fmt.Errorf("can't create update clone: an app with the name '%s' exists!", a.name+updateCloneSuffix)
+	}
+
+	return nil
+}
+
+func safeUpdate(app *App) (err error) {
+	// Create a snapshot of the current app.
+	app.setUpdateState("creating pre-update snapshot...")
+	app.taskMutex.Lock()
+	snapshot, err := app.backup()
+	app.taskMutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// Create the temporary clone from a snapshot.
+	app.setUpdateState("creating temporary clone...")
+	clone, err := newUpdateClone(app)
+	if err != nil {
+		return err
+	}
+	defer removeUpdateClone(clone)
+
+	// Checkout the pinned revision or the latest branch commit in the clone.
+	app.setUpdateState("fetching source...")
+	commit, err := updateSource(clone.SourceDirectoryPath(), app.Pin(), app.Branch())
+	if err != nil {
+		return err
+	}
+
+	// Load the new turtlefile.
+	t, err := clone.Turtlefile()
+	if err != nil {
+		return err
+	}
+
+	// Build and pull the new images.
+	// They are tagged with the update tag and don't replace the current images yet.
+	images := updateImages(app, t, clone.SourceDirectoryPath())
+	applied := false
+
+	defer func() {
+		if applied {
+			return
+		}
+
+		for _, img := range images {
+			if errR := docker.RemoveUpdateImage(app.name, img.Name, img.Tag); errR != nil {
+				log.Errorf("app '%s': %v", app.name, errR)
+			}
+		}
+	}()
+
+	for _, img := range images {
+		if len(img.BuildPath) > 0 {
+			app.setUpdateState("building docker image: " + img.Name)
+			log.Infof("building update docker image: %s", img.Name)
+			err = docker.BuildUpdateImage(app.name, img.Name, img.Tag, img.BuildPath)
+		} else {
+			app.setUpdateState("pulling docker image: " + img.Name + ":" + img.Tag)
+			log.Infof("pulling update docker image: %s:%s", img.Name, img.Tag)
+			err = docker.PullUpdateImage(app.name, img.Name, img.Tag)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to prepare image '%s': %v", img.Name, err)
+		}
+	}
+
+	// Start and verify the clone.
+	app.setUpdateState("starting temporary clone...")
+	if err = setupRunEnvironment(clone); err != nil {
+		return fmt.Errorf("failed to setup environment of temporary clone: %v", err)
+	}
+	if err = startContainers(clone); err != nil {
+		return fmt.Errorf("failed to start temporary clone: %v", err)
+	}
+
+	app.setUpdateState("verifying temporary clone...")
+	err = verifyUpdate(clone, t)
+	if errS := stopContainers(clone); errS != nil {
+		log.Errorf("app '%s': failed to stop temporary clone: %v", app.name, errS)
+	}
+	if err != nil {
+		return fmt.Errorf("temporary clone failed: %v", err)
+	}
+
+	// Swap the verified update in.
+	wasRunning := app.IsRunning()
+	app.setUpdateState("stopping app...")
+	if err = stopAppTask(app); err != nil {
+		return err
+	}
+
+	app.setUpdateState("applying update...")
+	applied = true
+
+	err = applyUpdate(app, commit, images)
+	if err == nil && wasRunning {
+		// Start the updated app and verify it.
+		app.setUpdateState("starting updated app...")
+		if err = app.runTask(taskRun, taskFuncRun); err == nil {
+			app.setUpdateState("verifying updated app...")
+			err = verifyUpdate(app, t)
+		}
+	}
+
+	if err != nil {
+		app.setUpdateState("rolling back...")
+		log.Warningf("app '%s': update failed: rolling back to snapshot '%s'", app.name, snapshot)
+
+		if errR := rollbackUpdate(app, snapshot, images, wasRunning); errR != nil {
+			return fmt.Errorf("%v\nrollback failed: %v", err, errR)
+		}
+
+		return fmt.Errorf("%v\nrolled back to snapshot '%s'", err, snapshot)
+	}
+
+	return nil
+}
+
+// newUpdateClone creates a temporary clone of the app from a snapshot.
+// The clone publishes its ports with the update port offset and is never restarted.
+func newUpdateClone(app *App) (*App, error) {
+	clone, err := newApp(app.name + updateCloneSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	clone.origin = app
+	clone.path = config.Config.UpdatesDirPath() + "/" + app.name
+	clone.checkRestartError = make(chan error, 1)
+
+	// Copy the settings. Disable restarts and move the ports.
+	settings := *app.settings
+	settings.RestartPolicy = &turtlefile.RestartPolicy{Policy: turtlefile.RestartNever}
+	settings.Ports = make(appSettingsPorts, len(app.settings.Ports))
+	for i, p := range app.settings.Ports {
+		port := *p
+		if port.HostPort > 0 {
+			port.HostPort += config.Config.UpdatePortOffset
+			if port.HostPort > maxPort {
+				port.HostPort = 0
+			}
+		}
+		settings.Ports[i] = &port
+	}
+	clone.settings = &settings
+
+	// Create the updates directory if not present.
+	err = utils.MkDirIfNotExists(config.Config.UpdatesDirPath())
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove a leftover clone of a previous update.
+	if btrfs.IsSubvolume(clone.path) {
+		if err = btrfs.DeleteSubvolume(clone.path); err != nil {
+			return nil, fmt.Errorf("failed to remove previous update clone: %v", err)
+		}
+	}
+
+	// Create a writable snapshot of the app.
+	err = btrfs.Snapshot(app.path, clone.path, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create update clone: %v", err)
+	}
+
+	return clone, nil
+}
+
+// removeUpdateClone stops the clone containers and removes the clone.
+func removeUpdateClone(clone *App) {
+	if err := stopContainers(clone); err != nil {
+		log.Errorf("app '%s': failed to stop temporary clone: %v", clone.origin.name, err)
+	}
+
+	if err := btrfs.DeleteSubvolume(clone.path); err != nil {
+		log.Errorf("app '%s': failed to remove temporary clone: %v", clone.origin.name, err)
+	}
+
+	if err := os.RemoveAll(clone.LogsDirectoryPath()); err != nil {
+		log.Errorf("app '%s': failed to remove temporary clone logs: %v", clone.origin.name, err)
+	}
+}
+
+// updateImages returns the distinct container images of the turtlefile.
+func updateImages(app *App, t *turtlefile.Turtlefile, sourcePath string) []updateImage {
+	var images []updateImage
+	added := make(map[string]bool)
+
+	for _, c := range t.Containers {
+		name, tag := app.containerImage(c)
+		if added[name+":"+tag] {
+			continue
+		}
+		added[name+":"+tag] = true
+
+		img := updateImage{
+			Name: name,
+			Tag:  tag,
+		}
+		if c.IsLocalBuild() {
+			img.BuildPath = c.BuildPath(sourcePath)
+		}
+
+		images = append(images, img)
+	}
+
+	return images
+}
+
+// applyUpdate checks out the verified source commit and replaces
+// the current images with the update images.
+func applyUpdate(app *App, commit string, images []updateImage) error {
+	sourcePath := app.SourceDirectoryPath()
+
+	// Fetch and checkout the verified commit.
+	err := fetchSource(sourcePath)
+	if err != nil {
+		return err
+	}
+
+	_, err = checkoutSource(sourcePath, commit, app.Pin(), app.Branch())
+	if err != nil {
+		return err
+	}
+
+	// Reload the turtlefile.
+	app.turtlefile = nil
+	if _, err = app.Turtlefile(); err != nil {
+		return err
+	}
+
+	// Replace the images. The current images are kept with the old tag.
+	for _, img := range images {
+		err = docker.ApplyUpdateImage(app.name, img.Name, img.Tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Record the deployed commit.
+	return app.setDeployedCommit(commit)
+}
+
+// rollbackUpdate restores the pre-update snapshot and the old images.
+// The app is started again if start is set.
+func rollbackUpdate(app *App, snapshot string, images []updateImage, start bool) error {
+	// Stop the updated app.
+	err := stopAppTask(app)
+	if err != nil {
+		return err
+	}
+
+	// Restore the old images.
+	for _, img := range images {
+		// Skip images which were not replaced yet.
+		_, err = docker.Client.InspectImage(img.Name + ":" + docker.UpdateImageTag(app.name, img.Tag))
+		if err == nil {
+			if err = docker.RemoveUpdateImage(app.name, img.Name, img.Tag); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err = docker.RestoreOldImage(app.name, img.Name, img.Tag); err != nil {
+			return err
+		}
+	}
+
+	// Restore the snapshot.
+	err = app.restoreBackup(snapshot)
+	if err != nil {
+		return err
+	}
+
+	if !start {
+		return nil
+	}
+
+	return app.runTask(taskRun, taskFuncRun)
+}
+
+// stopAppTask stops the app if running and waits for the task to exit.
+func stopAppTask(app *App) error {
+	if app.IsRunning() {
+		if err := app.Stop(); err != nil {
+			return err
+		}
+	}
+
+	return waitForTask(app)
+}
+
+// waitForTask waits for the current app task to exit.
+func waitForTask(app *App) error {
+	deadline := time.Now().Add(updateTaskTimeout)
+	for app.IsTaskRunning() {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout while waiting for the app task to exit!")
+		}
+		time.Sleep(300 * time.Millisecond)
+	}
+
+	return nil
+}
+
+// verifyUpdate waits until the app is started and checks for the verify
+// duration whether all app containers are running and healthy.
+func verifyUpdate(app *App, t *turtlefile.Turtlefile) error {
+	// Wait for the app startup. Update clones are already started.
+	deadline := time.Now().Add(updateTaskTimeout)
+	for app.origin == nil && app.IsRunning() && app.State() != stateRunning &&
+		!strings.HasPrefix(app.State(), stateUnhealthy) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout while waiting for the app startup!")
+		}
+		time.Sleep(300 * time.Millisecond)
+	}
+
+	// Wait long enough for every health check to fail.
+	duration := config.Config.UpdateVerifyDuration
+	for _, c := range t.Containers {
+		for _, h := range c.HealthChecks {
+			if hd := h.StartPeriod.Duration + h.Interval.Duration*time.Duration(h.Retries); hd > duration {
+				duration = hd
+			}
+		}
+	}
+
+	attempts, _ := app.RestartState()
+
+	timeout := time.After(duration)
+	ticker := time.NewTicker(updateCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-timeout:
+			if err := checkUpdateHealth(app, attempts); err != nil {
+				return err
+			}
+
+			// Pending health check failures fail the update at the end.
+			if err := app.healthError(); err != nil {
+				return fmt.Errorf("unhealthy: %v", err)
+			}
+
+			return nil
+		case <-ticker.C:
+			if err := checkUpdateHealth(app, attempts); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// checkUpdateHealth returns an error if an app container stopped,
+// was restarted or is unhealthy.
+func checkUpdateHealth(app *App, attempts int) error {
+	// The run task has to be active. Update clones are started without a task.
+	if app.origin == nil && !app.IsRunning() {
+		if err := app.Error(); err != nil {
+			return err
+		}
+		return fmt.Errorf("app stopped running!")
+	}
+
+	// Check the restarts.
+	if a, _ := app.RestartState(); a > attempts {
+		return fmt.Errorf("app containers were restarted!")
+	}
+
+	// Check the errors of the restart checks.
+	if app.origin != nil {
+		select {
+		case err := <-app.checkRestartError:
+			return err
+		default:
+		}
+	}
+
+	for _, id := range app.containerIDs {
+		if len(id) == 0 {
+			return fmt.Errorf("app containers are restarting!")
+		}
+
+		c, err := docker.Client.InspectContainer(id)
+		if err != nil {
+			return err
+		}
+
+		// Successfully exited containers are fine.
+		if !c.State.Running && c.State.ExitCode != 0 {
+			return fmt.Errorf("container '%s' stopped running with exit code %v!%s",
+				c.Name, c.State.ExitCode, containersErrorOutput([]*d.Container{c}))
+		} else if app.isUnhealthy(id) {
+			return fmt.Errorf("container '%s' is unhealthy!", c.Name)
+		}
+	}
+
+	return nil
+}
+
+//###########################//
+//### Private App methods ###//
+//###########################//
+
+// setUpdateState sets the safe update state.
+func (a *App) setUpdateState(s string) {
+	// Lock the mutex.
+	a.updateMutex.Lock()
+	defer a.updateMutex.Unlock()
+
+	a.updateState = s
+}
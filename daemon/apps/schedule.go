@@ -0,0 +1,218 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package apps
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/desertbit/turtle/daemon/config"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	BackupScheduleDefault  = "default" // Backup in the daemon BackupInterval.
+	BackupScheduleInterval = "interval"
+	BackupScheduleCron     = "cron"
+	BackupScheduleDisabled = "disabled"
+
+	minBackupInterval = time.Minute
+)
+
+//##############//
+//### Public ###//
+//##############//
+
+// CheckBackupSchedules creates the scheduled backups of all apps in the background.
+// Stopped apps are only backed up if enabled by their schedule.
+func CheckBackupSchedules() {
+	now := time.Now()
+
+	for _, a := range Apps() {
+		if !a.isBackupDue(now) {
+			continue
+		}
+
+		go a.scheduledBackup()
+	}
+}
+
+//##########################//
+//### Public App methods ###//
+//##########################//
+
+// BackupSchedule returns the backup schedule mode, the interval of the interval
+// modes, the cron expression of the cron mode and whenever stopped apps are backed up.
+func (a *App) BackupSchedule() (mode string, interval time.Duration, cron string, stopped bool) {
+	s := a.settings.BackupSchedule
+
+	mode = s.Mode
+	if len(mode) == 0 {
+		mode = BackupScheduleDefault
+	}
+
+	interval = s.Interval.Duration
+	if mode == BackupScheduleDefault {
+		interval = config.Config.BackupInterval
+	}
+
+	return mode, interval, s.Cron, s.Stopped
+}
+
+// SetBackupSchedule sets the automatic backup schedule.
+// The interval is only used by the interval mode and the cron expression
+// only by the cron mode. Set stopped to also backup the app if it is stopped.
+func (a *App) SetBackupSchedule(mode string, interval time.Duration, cron string, stopped bool) error {
+	switch mode {
+	case BackupScheduleDefault, BackupScheduleDisabled:
+		interval, cron = 0, ""
+	case BackupScheduleInterval:
+		if interval < minBackupInterval {
+			return fmt.Errorf("the backup interval has to be at least %v!", minBackupInterval)
+		}
+		cron = ""
+	case BackupScheduleCron:
+		c, err := parseCron(cron)
+		if err != nil {
+			return err
+		} else if c.next(time.Now()).IsZero() {
+			return fmt.Errorf("the cron expression '%s' never matches!", cron)
+		}
+		interval = 0
+	default:
+		return fmt.Errorf("invalid backup schedule mode '%s'!", mode)
+	}
+
+	s := &a.settings.BackupSchedule
+	s.Mode = mode
+	s.Interval.Duration = interval
+	s.Cron = cron
+	s.Stopped = stopped
+
+	if err := a.saveSettings(); err != nil {
+		return err
+	}
+
+	// Calculate the next backup with the new schedule.
+	a.resetBackupSchedule()
+
+	return nil
+}
+
+// NextBackup returns the time of the next scheduled backup.
+// A zero time is returned if no backup is scheduled.
+func (a *App) NextBackup() time.Time {
+	// Lock the mutex.
+	a.backupScheduleMutex.Lock()
+	defer a.backupScheduleMutex.Unlock()
+
+	if !a.isBackupScheduled() {
+		return time.Time{}
+	} else if a.nextBackup.IsZero() {
+		a.nextBackup = a.nextBackupTime(time.Now())
+	}
+
+	return a.nextBackup
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// isBackupDue returns a boolean whenever the scheduled backup is due.
+// The scheduled backup is marked as running if due.
+func (a *App) isBackupDue(now time.Time) bool {
+	// Lock the mutex.
+	a.backupScheduleMutex.Lock()
+	defer a.backupScheduleMutex.Unlock()
+
+	if a.scheduledBackupRunning {
+		return false
+	} else if !a.isBackupScheduled() {
+		// Start a new schedule if enabled again.
+		a.nextBackup = time.Time{}
+		return false
+	} else if a.nextBackup.IsZero() {
+		a.nextBackup = a.nextBackupTime(now)
+		return false
+	} else if now.Before(a.nextBackup) {
+		return false
+	}
+
+	a.scheduledBackupRunning = true
+
+	return true
+}
+
+// scheduledBackup creates the scheduled backup and schedules the next one.
+func (a *App) scheduledBackup() {
+	log.Infof("creating automatic backup of app '%s'.", a.name)
+
+	// Create a backup.
+	err := a.Backup()
+	if err != nil {
+		log.Errorf("failed to create automatic backup of app '%s': %v", a.name, err)
+	}
+
+	// Lock the mutex.
+	a.backupScheduleMutex.Lock()
+	defer a.backupScheduleMutex.Unlock()
+
+	a.scheduledBackupRunning = false
+	a.nextBackup = a.nextBackupTime(time.Now())
+}
+
+// resetBackupSchedule starts a new backup schedule.
+// The next backup is calculated from the current time.
+func (a *App) resetBackupSchedule() {
+	// Lock the mutex.
+	a.backupScheduleMutex.Lock()
+	defer a.backupScheduleMutex.Unlock()
+
+	a.nextBackup = time.Time{}
+}
+
+// isBackupScheduled returns a boolean whenever automatic backups of the app are enabled
+// in its current state. Apps are not backed up during updates.
+func (a *App) isBackupScheduled() bool {
+	mode, _, _, stopped := a.BackupSchedule()
+	if mode == BackupScheduleDisabled || a.IsUpdating() {
+		return false
+	}
+
+	return a.IsRunning() || (stopped && !a.IsTaskRunning())
+}
+
+// nextBackupTime returns the time of the next scheduled backup after now.
+// A zero time is returned if the cron expression never matches.
+func (a *App) nextBackupTime(now time.Time) time.Time {
+	mode, interval, cron, _ := a.BackupSchedule()
+	if mode != BackupScheduleCron {
+		return now.Add(interval)
+	}
+
+	c, err := parseCron(cron)
+	if err != nil {
+		log.Errorf("app '%s': invalid backup schedule: %v", a.name, err)
+		return time.Time{}
+	}
+
+	return c.next(now)
+}
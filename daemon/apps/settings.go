@@ -18,6 +18,10 @@
 
 package apps
 
+import (
+	"github.com/desertbit/turtle/daemon/turtlefile"
+)
+
 //##########################//
 //### App settings types ###//
 //##########################//
@@ -25,14 +29,37 @@ package apps
 type appSettings struct {
 	SourceURL string            // The git source URL.
 	Branch    string            // Main stable branch.
+	Pin       string            // Optional git tag or commit. Updates check out this revision instead of the branch.
 	Env       map[string]string // The environment values. The key is the name and the value is the variable value.
 	Ports     appSettingsPorts
+
+	// The deployed source commits.
+	Commit         string
+	PreviousCommit string // The commit deployed before the current commit.
+
+	// Resource limits overwriting the Turtlefile limits.
+	// The key is the container name.
+	Resources map[string]*turtlefile.Resources
+
+	// The restart policy overwriting the Turtlefile policy.
+	RestartPolicy *turtlefile.RestartPolicy
+
+	// The automatic update settings.
+	AutoUpdate appSettingsAutoUpdate
+
+	// The automatic backup schedule.
+	BackupSchedule appSettingsBackupSchedule
+
+	// The backup retention policy overwriting the daemon default.
+	// In the format of config.ParseRetention. Empty to use the default.
+	Retention string
 }
 
 // newSettings creates and initializes a new app settings value,
 func newSettings() *appSettings {
 	return &appSettings{
-		Env: make(map[string]string),
+		Env:       make(map[string]string),
+		Resources: make(map[string]*turtlefile.Resources),
 	}
 }
 
@@ -44,3 +71,17 @@ type appSettingsPort struct {
 	HostPort      int // 0 if disabled.
 	Protocol      string
 }
+
+type appSettingsAutoUpdate struct {
+	Mode     string              // disabled, poll or webhook. Disabled if empty.
+	Interval turtlefile.Duration // The poll interval. The daemon default is used if zero.
+	Secret   string              // The webhook HMAC secret.
+	Safe     bool                // Verify the updates with a temporary clone.
+}
+
+type appSettingsBackupSchedule struct {
+	Mode     string              // default, interval, cron or disabled. Default if empty.
+	Interval turtlefile.Duration // The backup interval of the interval mode.
+	Cron     string              // The cron expression of the cron mode.
+	Stopped  bool                // Also backup the app if it is stopped.
+}
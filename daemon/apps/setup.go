@@ -19,7 +19,10 @@
 package apps
 
 import (
+	"fmt"
+
 	"github.com/desertbit/turtle/api"
+	"github.com/desertbit/turtle/daemon/turtlefile"
 
 	log "github.com/Sirupsen/logrus"
 )
@@ -64,8 +67,9 @@ func (a *App) GetSetup() (*api.Setup, error) {
 
 	// Create a new setup value.
 	setup := &api.Setup{
-		Env:   make(api.Env, len(t.Env)),
-		Ports: make(api.Ports, len(t.Ports)),
+		Env:       make(api.Env, len(t.Env)),
+		Ports:     make(api.Ports, len(t.Ports)),
+		Resources: make(api.Resources, len(t.Containers)),
 	}
 
 	// Fill the setup environment values,
@@ -116,13 +120,92 @@ func (a *App) GetSetup() (*api.Setup, error) {
 		i++
 	}
 
+	// Fill the resource limits with the values from the settings.
+	for i, c := range t.Containers {
+		r := &api.ResourceLimits{
+			Container: c.Name,
+		}
+
+		if sr, ok := a.settings.Resources[c.Name]; ok && sr != nil {
+			r.Memory = sr.Memory
+			r.MemorySwap = sr.MemorySwap
+			r.CPUShares = sr.CPUShares
+			r.CPUSet = sr.CPUSet
+			r.CPUQuota = sr.CPUQuota
+			r.CPUPeriod = sr.CPUPeriod
+			r.PidsLimit = sr.PidsLimit
+			r.BlkioWeight = sr.BlkioWeight
+		}
+
+		setup.Resources[i] = r
+	}
+
+	// Set the restart policy from the settings.
+	if p := a.settings.RestartPolicy; p != nil {
+		setup.RestartPolicy = NewAPIRestartPolicy(*p)
+	}
+
 	return setup, nil
 }
 
 // Setup the app and save the values.
 func (a *App) Setup(setup *api.Setup) error {
+	// Get the turtlefile.
+	t, err := a.Turtlefile()
+	if err != nil {
+		return err
+	}
+
+	// Create the resource limits and validate them
+	// together with the turtlefile limits.
+	resources := make(map[string]*turtlefile.Resources)
+	for _, r := range setup.Resources {
+		var container *turtlefile.Container
+		for _, c := range t.Containers {
+			if c.Name == r.Container {
+				container = c
+				break
+			}
+		}
+		if container == nil {
+			return fmt.Errorf("resource limits: container '%s' does not exists!", r.Container)
+		}
+
+		sr := &turtlefile.Resources{
+			Memory:      r.Memory,
+			MemorySwap:  r.MemorySwap,
+			CPUShares:   r.CPUShares,
+			CPUSet:      r.CPUSet,
+			CPUQuota:    r.CPUQuota,
+			CPUPeriod:   r.CPUPeriod,
+			PidsLimit:   r.PidsLimit,
+			BlkioWeight: r.BlkioWeight,
+		}
+
+		merged := container.Resources.Merge(sr)
+		if err = merged.IsValid(); err != nil {
+			return fmt.Errorf("invalid resource limits of container '%s': %v", r.Container, err)
+		}
+
+		// Skip empty values.
+		if *sr != (turtlefile.Resources{}) {
+			resources[r.Container] = sr
+		}
+	}
+
+	// Create the restart policy and validate it together with the turtlefile policy.
+	restartPolicy, err := newRestartPolicy(setup.RestartPolicy)
+	if err != nil {
+		return err
+	}
+
+	merged := t.RestartPolicy.Merge(restartPolicy)
+	if err = merged.IsValid(); err != nil {
+		return err
+	}
+
 	// Create a backup first.
-	err := a.Backup()
+	err = a.Backup()
 	if err != nil {
 		return err
 	}
@@ -130,6 +213,8 @@ func (a *App) Setup(setup *api.Setup) error {
 	// Remove the previous set values first.
 	a.settings.Env = make(map[string]string)
 	a.settings.Ports = make(appSettingsPorts, len(setup.Ports))
+	a.settings.Resources = resources
+	a.settings.RestartPolicy = restartPolicy
 
 	// Set the environment values to the settings.
 	for _, env := range setup.Env {
@@ -149,3 +234,57 @@ func (a *App) Setup(setup *api.Setup) error {
 	// Save the settings.
 	return a.saveSettings()
 }
+
+// NewAPIRestartPolicy converts the restart policy to an API value.
+// Empty durations are not set.
+func NewAPIRestartPolicy(p turtlefile.RestartPolicy) api.RestartPolicy {
+	r := api.RestartPolicy{
+		Policy:     p.Policy,
+		MaxRetries: p.MaxRetries,
+	}
+
+	if p.Window.Duration != 0 {
+		r.Window = p.Window.String()
+	}
+	if p.Backoff.Duration != 0 {
+		r.Backoff = p.Backoff.String()
+	}
+	if p.MaxBackoff.Duration != 0 {
+		r.MaxBackoff = p.MaxBackoff.String()
+	}
+
+	return r
+}
+
+// newRestartPolicy creates a restart policy from the API value.
+// nil is returned if no value is set.
+func newRestartPolicy(r api.RestartPolicy) (*turtlefile.RestartPolicy, error) {
+	if r == (api.RestartPolicy{}) {
+		return nil, nil
+	}
+
+	p := &turtlefile.RestartPolicy{
+		Policy:     r.Policy,
+		MaxRetries: r.MaxRetries,
+	}
+
+	// Parse the durations.
+	var err error
+	parse := func(name, v string, d *turtlefile.Duration) {
+		if err != nil || len(v) == 0 {
+			return
+		}
+		if e := d.UnmarshalText([]byte(v)); e != nil {
+			err = fmt.Errorf("restart policy: invalid %s '%s': %v", name, v, e)
+		}
+	}
+
+	parse("Window", r.Window, &p.Window)
+	parse("Backoff", r.Backoff, &p.Backoff)
+	parse("MaxBackoff", r.MaxBackoff, &p.MaxBackoff)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
@@ -0,0 +1,336 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package apps
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/desertbit/turtle/utils"
+)
+
+const (
+	defaultRevisionsLimit = 20
+)
+
+//#####################//
+//### Revision type ###//
+//#####################//
+
+// Revision is an upstream source commit.
+type Revision struct {
+	Commit  string
+	Time    time.Time
+	Author  string
+	Subject string
+}
+
+// Tag is an upstream source tag.
+type Tag struct {
+	Name   string
+	Commit string
+}
+
+//##########################//
+//### Public App methods ###//
+//##########################//
+
+// Pin returns the git tag or commit the app is pinned to.
+// An empty string is returned if the app follows its branch.
+func (a *App) Pin() string {
+	return a.settings.Pin
+}
+
+// Commit returns the deployed source commit.
+func (a *App) Commit() string {
+	return a.settings.Commit
+}
+
+// PreviousCommit returns the source commit deployed before the current commit.
+func (a *App) PreviousCommit() string {
+	return a.settings.PreviousCommit
+}
+
+// SetPin pins the app to a git tag or commit. The revision is checked out
+// by the next update. Pass an empty revision to follow the branch again.
+func (a *App) SetPin(revision string) error {
+	if a.IsUpdating() || a.task == taskUpdate || a.task == taskCloneSource {
+		return fmt.Errorf("the app is updating!")
+	} else if !isValidRevision(revision) {
+		return fmt.Errorf("invalid revision '%s'!", revision)
+	}
+
+	a.settings.Pin = revision
+
+	return a.saveSettings()
+}
+
+// UpdatePin pins the app to a git tag or commit and updates it. Pass an
+// empty revision to follow the branch again. The pin is only saved if the
+// revision exists upstream and the update is accepted.
+func (a *App) UpdatePin(revision string, safe bool) error {
+	// Check if the update would be accepted.
+	var err error
+	if safe {
+		err = a.canSafeUpdate()
+	} else {
+		err = a.canUpdate()
+	}
+	if err != nil {
+		return err
+	} else if !isValidRevision(revision) {
+		return fmt.Errorf("invalid revision '%s'!", revision)
+	}
+
+	// Check if the revision exists.
+	if len(revision) > 0 {
+		if a.task == taskCloneSource {
+			return fmt.Errorf("the app source is not cloned yet!")
+		}
+
+		path := a.SourceDirectoryPath()
+
+		err = fetchSource(path)
+		if err != nil {
+			return err
+		}
+
+		_, err = resolveRevision(path, revision)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Pin the app to the revision.
+	prevPin := a.settings.Pin
+	err = a.SetPin(revision)
+	if err != nil {
+		return err
+	}
+
+	// Update the app.
+	if safe {
+		err = a.SafeUpdate()
+	} else {
+		err = a.Update()
+	}
+
+	// Restore the previous pin if the update was rejected.
+	if err != nil {
+		a.settings.Pin = prevPin
+		if errS := a.saveSettings(); errS != nil {
+			return fmt.Errorf("%v: failed to restore the previous pin: %v", err, errS)
+		}
+	}
+
+	return err
+}
+
+// Revisions fetches the upstream source and returns the latest commits
+// of the app branch and the latest tags.
+// The default limit is used if the limit is not greater than zero.
+func (a *App) Revisions(limit int) ([]Revision, []Tag, error) {
+	if a.task == taskCloneSource {
+		return nil, nil, fmt.Errorf("the app source is not cloned yet!")
+	} else if limit <= 0 {
+		limit = defaultRevisionsLimit
+	}
+
+	path := a.SourceDirectoryPath()
+
+	// Fetch the upstream commits and tags.
+	err := fetchSource(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Obtain the latest branch commits.
+	revisions, err := gitLog(path, "-n", strconv.Itoa(limit), "origin/"+a.settings.Branch)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Obtain the latest tags.
+	// Annotated tags are dereferenced to their commits.
+	out, err := utils.RunCommandOutputInPath(path, "git", "for-each-ref", "--sort=-creatordate",
+		"--count="+strconv.Itoa(limit), "--format=%(refname:short)%09%(objectname)%09%(*objectname)", "refs/tags")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list tags: %v", err)
+	}
+
+	var tags []Tag
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			continue
+		}
+
+		tag := Tag{
+			Name:   fields[0],
+			Commit: fields[1],
+		}
+		if len(fields[2]) > 0 {
+			tag.Commit = fields[2]
+		}
+
+		tags = append(tags, tag)
+	}
+
+	return revisions, tags, nil
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// isValidRevision checks if the git revision is empty or a
+// valid tag or commit name, which is not mistaken as git option.
+func isValidRevision(revision string) bool {
+	return !strings.HasPrefix(revision, "-") &&
+		!strings.ContainsAny(revision, " \t\n\r\v\f~^:?*[\\")
+}
+
+// gitLog returns the commits listed by git log with the passed arguments.
+func gitLog(path string, args ...string) ([]Revision, error) {
+	args = append([]string{"log", "--format=%H%x09%ct%x09%an%x09%s"}, args...)
+	out, err := utils.RunCommandOutputInPath(path, "git", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits: %v", err)
+	}
+
+	var revisions []Revision
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.SplitN(line, "\t", 4)
+		if len(fields) != 4 {
+			continue
+		}
+
+		unix, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse commit time: %v", err)
+		}
+
+		revisions = append(revisions, Revision{
+			Commit:  fields[0],
+			Time:    time.Unix(unix, 0),
+			Author:  fields[2],
+			Subject: fields[3],
+		})
+	}
+
+	return revisions, nil
+}
+
+// resolveRevision returns the commit of the git revision.
+func resolveRevision(path, revision string) (string, error) {
+	commit, err := utils.RunCommandOutputInPath(path, "git", "rev-parse", "--verify", "-q", revision+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown revision '%s': %v", revision, err)
+	}
+
+	return strings.TrimSpace(commit), nil
+}
+
+// fetchSource fetches the upstream commits and tags.
+func fetchSource(path string) error {
+	err := utils.RunCommandInPath(path, "git", "fetch", "--tags", "origin")
+	if err != nil {
+		return fmt.Errorf("failed to fetch application source with git: %v", err)
+	}
+
+	return nil
+}
+
+// updateSource fetches the upstream source and checks out the pinned
+// revision or the latest commit of the branch.
+// The checked out commit is returned.
+func updateSource(path, pin, branch string) (string, error) {
+	err := fetchSource(path)
+	if err != nil {
+		return "", err
+	}
+
+	revision := "origin/" + branch
+	if len(pin) > 0 {
+		revision = pin
+	}
+
+	return checkoutSource(path, revision, pin, branch)
+}
+
+// checkoutSource checks out the revision. Pinned sources are detached
+// from the branch. Otherwise the branch is fast-forwarded to the revision.
+// The checked out commit is returned.
+func checkoutSource(path, revision, pin, branch string) (string, error) {
+	// Resolve the revision to its commit.
+	commit, err := resolveRevision(path, revision)
+	if err != nil {
+		return "", err
+	}
+
+	if len(pin) > 0 {
+		err = utils.RunCommandInPath(path, "git", "checkout", "-q", commit)
+		if err != nil {
+			return "", fmt.Errorf("failed to checkout commit '%s': %v", commit, err)
+		}
+
+		return commit, nil
+	}
+
+	// Checkout the branch and fast-forward it.
+	err = utils.RunCommandInPath(path, "git", "checkout", "-q", branch)
+	if err != nil {
+		return "", fmt.Errorf("failed to checkout branch '%s': %v", branch, err)
+	}
+
+	err = utils.RunCommandInPath(path, "git", "merge", "--ff-only", commit)
+	if err != nil {
+		return "", fmt.Errorf("failed to merge commit '%s': %v", commit, err)
+	}
+
+	return commit, nil
+}
+
+// sourceCommit returns the checked out commit of the source.
+func sourceCommit(path string) (string, error) {
+	commit, err := utils.RunCommandOutputInPath(path, "git", "rev-parse", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to obtain the source commit: %v", err)
+	}
+
+	return strings.TrimSpace(commit), nil
+}
+
+//###########################//
+//### Private App methods ###//
+//###########################//
+
+// setDeployedCommit records the deployed source commit in the app settings.
+func (a *App) setDeployedCommit(commit string) error {
+	if commit == a.settings.Commit {
+		return nil
+	}
+
+	a.settings.PreviousCommit = a.settings.Commit
+	a.settings.Commit = commit
+
+	return a.saveSettings()
+}
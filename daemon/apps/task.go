@@ -44,10 +44,33 @@ const (
 	taskBackup      taskType = 1 << iota
 )
 
+// String returns the task name.
+func (t taskType) String() string {
+	switch t {
+	case taskNone:
+		return "none"
+	case taskCloneSource:
+		return "clone-source"
+	case taskRun:
+		return "run"
+	case taskUpdate:
+		return "update"
+	case taskBackup:
+		return "backup"
+	default:
+		return "unknown"
+	}
+}
+
 //########################//
 //### App task methods ###//
 //########################//
 
+// Task returns the name of the current task.
+func (a *App) Task() string {
+	return a.task.String()
+}
+
 // IsTaskRunning returns a boolean whenever a task is active and running.
 func (a *App) IsTaskRunning() bool {
 	return a.task != taskNone
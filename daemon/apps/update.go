@@ -22,10 +22,8 @@ import (
 	"fmt"
 
 	"github.com/desertbit/turtle/daemon/docker"
-	"github.com/desertbit/turtle/utils"
 
 	log "github.com/Sirupsen/logrus"
-	d "github.com/fsouza/go-dockerclient"
 )
 
 //####################//
@@ -35,12 +33,13 @@ import (
 // Update the app.
 func (a *App) Update() error {
 	// Abort if any app task is running.
-	if a.IsTaskRunning() {
-		return fmt.Errorf("the app is running!")
+	err := a.canUpdate()
+	if err != nil {
+		return err
 	}
 
 	// Create a backup.
-	err := a.Backup()
+	err = a.Backup()
 	if err != nil {
 		return err
 	}
@@ -49,13 +48,24 @@ func (a *App) Update() error {
 	return a.runTask(taskUpdate, taskFuncUpdate)
 }
 
+// canUpdate checks if the app can be updated.
+func (a *App) canUpdate() error {
+	if a.IsTaskRunning() {
+		return fmt.Errorf("the app is running!")
+	} else if a.IsUpdating() {
+		return fmt.Errorf("the app is updating!")
+	}
+
+	return nil
+}
+
 func taskFuncUpdate(app *App) error {
 	app.setState("updating")
 
-	// Pull the source with git.
-	err := utils.RunCommandInPath(app.SourceDirectoryPath(), "git", "pull")
+	// Checkout the pinned revision or the latest branch commit.
+	commit, err := updateSource(app.SourceDirectoryPath(), app.Pin(), app.Branch())
 	if err != nil {
-		return fmt.Errorf("failed to pull latest application source with git: %v", err)
+		return err
 	}
 
 	// Get and update the turtlefile.
@@ -65,9 +75,6 @@ func taskFuncUpdate(app *App) error {
 		return err
 	}
 
-	// Get the container name prefix.
-	cNamePrefix := app.ContainerNamePrefix()
-
 	// Get the app's source path.
 	sourcePath := app.SourceDirectoryPath()
 
@@ -75,15 +82,9 @@ func taskFuncUpdate(app *App) error {
 	for _, container := range turtlefile.Containers {
 		isLocalBuild := container.IsLocalBuild()
 
-		// Create the docker container name.
-		containerName := cNamePrefix + container.Name
-
 		// Create the container image and image name.
-		imageName := container.Image
-		if isLocalBuild {
-			imageName = containerName
-		}
-		image := imageName + ":" + container.Tag
+		imageName, tag := app.containerImage(container)
+		image := imageName + ":" + tag
 
 		// Check whenever to build or pull the image.
 		if isLocalBuild {
@@ -91,7 +92,7 @@ func taskFuncUpdate(app *App) error {
 			log.Infof("building local docker image: %s", image)
 
 			// Build the local image.
-			err = docker.Build(imageName, container.Tag, container.BuildPath(sourcePath))
+			err = docker.Build(imageName, tag, container.BuildPath(sourcePath))
 			if err != nil {
 				return fmt.Errorf("failed to build image '%s': %v", image, err)
 			}
@@ -100,10 +101,7 @@ func taskFuncUpdate(app *App) error {
 			log.Infof("pulling docker image: %s", image)
 
 			// Pull the image.
-			err = docker.Client.PullImage(d.PullImageOptions{
-				Repository: container.Image,
-				Tag:        container.Tag,
-			}, d.AuthConfiguration{})
+			err = docker.PullImage(container.Image, tag)
 
 			if err != nil {
 				return fmt.Errorf("failed to pull docker image '%s': %v", image, err)
@@ -111,5 +109,6 @@ func taskFuncUpdate(app *App) error {
 		}
 	}
 
-	return nil
+	// Record the deployed commit.
+	return app.setDeployedCommit(commit)
 }
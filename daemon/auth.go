@@ -0,0 +1,243 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/desertbit/turtle/api"
+	"github.com/desertbit/turtle/daemon/config"
+	"github.com/desertbit/turtle/utils"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	tokenLength = 32 // Bytes
+)
+
+var (
+	tokensMutex sync.Mutex
+)
+
+//##################//
+//### Token type ###//
+//##################//
+
+type tokens struct {
+	Tokens []*token `toml:"Token"`
+}
+
+type token struct {
+	Name string // The user name of the token.
+	Hash string // The hex encoded SHA-256 hash of the token.
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// authenticate checks the credential of a request and returns the user name.
+// Local requests from the unix socket are trusted.
+// A verified TLS client certificate is accepted before the request token.
+func authenticate(req *http.Request, request *api.Request) (string, error) {
+	if isLocalRequest(req) {
+		return localUser, nil
+	}
+
+	// Check for a verified client certificate.
+	if req.TLS != nil && len(req.TLS.VerifiedChains) > 0 && len(req.TLS.VerifiedChains[0]) > 0 {
+		name := req.TLS.VerifiedChains[0][0].Subject.CommonName
+		if len(name) == 0 || name == localUser {
+			return "", api.NewError(api.ErrorCodeAuthFailed, "authentication failed: invalid client certificate common name '%s'", name)
+		}
+		return name, nil
+	}
+
+	if len(request.Token) == 0 {
+		return "", api.NewError(api.ErrorCodeAuthFailed, "authentication failed: missing API token")
+	}
+
+	// Load the tokens.
+	t, err := loadTokens()
+	if err != nil {
+		return "", err
+	}
+
+	// Compare the hash with all tokens.
+	hash := hashToken(request.Token)
+	for _, tt := range t.Tokens {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(tt.Hash)) == 1 {
+			return tt.Name, nil
+		}
+	}
+
+	return "", api.NewError(api.ErrorCodeAuthFailed, "authentication failed: invalid API token")
+}
+
+// addToken creates a new API token for the user and saves its hash.
+// The plain token is returned.
+func addToken(name string) (string, error) {
+	if len(name) == 0 {
+		return "", fmt.Errorf("failed to add token: empty name!")
+	} else if name == localUser {
+		return "", fmt.Errorf("failed to add token: the name '%s' is reserved!", name)
+	}
+
+	// Lock the mutex.
+	tokensMutex.Lock()
+	defer tokensMutex.Unlock()
+
+	t, err := loadTokens()
+	if err != nil {
+		return "", err
+	}
+
+	// Check if a token with the same name already exists.
+	for _, tt := range t.Tokens {
+		if tt.Name == name {
+			return "", fmt.Errorf("a token with the name '%s' already exists!", name)
+		}
+	}
+
+	// Create a new random token.
+	b := make([]byte, tokenLength)
+	if _, err = rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to create random token: %v", err)
+	}
+	plain := hex.EncodeToString(b)
+
+	t.Tokens = append(t.Tokens, &token{
+		Name: name,
+		Hash: hashToken(plain),
+	})
+
+	if err = saveTokens(t); err != nil {
+		return "", err
+	}
+
+	return plain, nil
+}
+
+// removeToken removes the API token of the user.
+func removeToken(name string) error {
+	// Lock the mutex.
+	tokensMutex.Lock()
+	defer tokensMutex.Unlock()
+
+	t, err := loadTokens()
+	if err != nil {
+		return err
+	}
+
+	for i, tt := range t.Tokens {
+		if tt.Name == name {
+			t.Tokens = append(t.Tokens[:i], t.Tokens[i+1:]...)
+			return saveTokens(t)
+		}
+	}
+
+	return fmt.Errorf("no token with the name '%s' found!", name)
+}
+
+// loadTokens loads the tokens file.
+// An empty value is returned if the file does not exists.
+func loadTokens() (*tokens, error) {
+	var t tokens
+
+	path := config.Config.TokensFilePath()
+
+	// Skip if it does not exists.
+	e, err := utils.Exists(path)
+	if err != nil {
+		return nil, err
+	} else if !e {
+		return &t, nil
+	}
+
+	// Load and decode the file.
+	_, err = toml.DecodeFile(path, &t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tokens file '%s': %v", path, err)
+	}
+
+	return &t, nil
+}
+
+// saveTokens saves the tokens to the tokens file.
+func saveTokens(t *tokens) error {
+	// Encode the tokens value to TOML.
+	buf := new(bytes.Buffer)
+	err := toml.NewEncoder(buf).Encode(t)
+	if err != nil {
+		return fmt.Errorf("failed to encode tokens to toml: %v", err)
+	}
+
+	// Write the result to the tokens file.
+	err = ioutil.WriteFile(config.Config.TokensFilePath(), buf.Bytes(), 0600)
+	if err != nil {
+		return fmt.Errorf("failed to save tokens file: %v", err)
+	}
+
+	return nil
+}
+
+// hashToken returns the hex encoded SHA-256 hash of the token.
+func hashToken(t string) string {
+	h := sha256.Sum256([]byte(t))
+	return hex.EncodeToString(h[:])
+}
+
+// newTLSConfig creates the TLS config of the API server.
+// Client certificates are verified, if a client CA file is set.
+func newTLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if len(config.Config.TLSClientCAFile) == 0 {
+		return tlsConfig, nil
+	}
+
+	// Load the client CA certificates.
+	pem, err := ioutil.ReadFile(config.Config.TLSClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS client CA file: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("failed to load TLS client CA file: no valid certificates found")
+	}
+
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,267 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/desertbit/turtle/api"
+	"github.com/desertbit/turtle/daemon/config"
+)
+
+// useTempTurtlePath sets the turtle path to a temporary directory.
+// The returned function removes the directory and restores the config.
+func useTempTurtlePath(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "turtle-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := config.Config
+	config.Config.TurtlePath = dir
+
+	return func() {
+		config.Config = c
+		os.RemoveAll(dir)
+	}
+}
+
+// newCertificate creates a certificate with the common name.
+// The certificate is self-signed if the parent is nil.
+func newCertificate(t *testing.T, name string, parent *tls.Certificate) *tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	signer, signerKey := template, interface{}(key)
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer = parent.Leaf
+		signerKey = parent.PrivateKey
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+}
+
+// expectAuthFailed checks if the error is an authentication error.
+func expectAuthFailed(t *testing.T, err error, context string) {
+	if apiErr, ok := err.(*api.Error); !ok || apiErr.Code != api.ErrorCodeAuthFailed {
+		t.Errorf("%s: expected an authentication error, got: %v", context, err)
+	}
+}
+
+func TestTokenAuthentication(t *testing.T) {
+	defer useTempTurtlePath(t)()
+
+	plain, err := addToken("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the hash of the token is stored.
+	data, err := ioutil.ReadFile(config.Config.TokensFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), hashToken(plain)) || strings.Contains(string(data), plain) {
+		t.Errorf("tokens file does not contain only the token hash:\n%s", data)
+	}
+
+	if _, err = addToken("alice"); err == nil {
+		t.Errorf("expected an error for a duplicate token name")
+	}
+
+	req := httptest.NewRequest("POST", "/", nil)
+
+	name, err := authenticate(req, &api.Request{Token: plain})
+	if err != nil {
+		t.Fatal(err)
+	} else if name != "alice" {
+		t.Errorf("got user '%s', want 'alice'", name)
+	}
+
+	_, err = authenticate(req, &api.Request{})
+	expectAuthFailed(t, err, "missing token")
+
+	_, err = authenticate(req, &api.Request{Token: plain + "0"})
+	expectAuthFailed(t, err, "invalid token")
+
+	// A removed token is rejected.
+	if err = removeToken("alice"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = authenticate(req, &api.Request{Token: plain})
+	expectAuthFailed(t, err, "removed token")
+
+	if err = removeToken("alice"); err == nil {
+		t.Errorf("expected an error for a missing token")
+	}
+}
+
+func TestCertificateAuthentication(t *testing.T) {
+	defer useTempTurtlePath(t)()
+
+	ca := newCertificate(t, "turtle-ca", nil)
+	server := newCertificate(t, "turtle", ca)
+	client := newCertificate(t, "bob", ca)
+	unnamed := newCertificate(t, "", ca)
+	untrusted := newCertificate(t, "eve", newCertificate(t, "other-ca", nil))
+
+	// Write the client CA file.
+	caFile := filepath.Join(config.Config.TurtlePath, "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate[0]})
+	if err := ioutil.WriteFile(caFile, caPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	config.Config.TLSClientCAFile = caFile
+
+	tlsConfig, err := newTLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsConfig.Certificates = []tls.Certificate{*server}
+
+	// Authenticate each request with an empty token.
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		name, err := authenticate(req, &api.Request{})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(name))
+	}))
+	s.TLS = tlsConfig
+	s.StartTLS()
+	defer s.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+
+	// request sends a request with the client certificate
+	// and returns the response body or an error.
+	request := func(cert *tls.Certificate) (string, int, error) {
+		clientConfig := &tls.Config{RootCAs: pool}
+		if cert != nil {
+			clientConfig.Certificates = []tls.Certificate{*cert}
+		}
+
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}
+		resp, err := c.Get(s.URL)
+		if err != nil {
+			return "", 0, err
+		}
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		return string(body), resp.StatusCode, err
+	}
+
+	// The common name of a verified certificate is the user name.
+	body, status, err := request(client)
+	if err != nil {
+		t.Fatal(err)
+	} else if status != http.StatusOK || body != "bob" {
+		t.Errorf("client certificate: got %v '%s', want 200 'bob'", status, body)
+	}
+
+	// Without a certificate the token is required.
+	if _, status, err = request(nil); err != nil {
+		t.Fatal(err)
+	} else if status != http.StatusUnauthorized {
+		t.Errorf("no certificate: got status %v, want 401", status)
+	}
+
+	// A certificate without common name is rejected.
+	if _, status, err = request(unnamed); err != nil {
+		t.Fatal(err)
+	} else if status != http.StatusUnauthorized {
+		t.Errorf("certificate without common name: got status %v, want 401", status)
+	}
+
+	// Certificates of other CAs are not accepted.
+	if _, status, err = request(untrusted); err == nil && status != http.StatusUnauthorized {
+		t.Errorf("untrusted certificate: got status %v, want 401", status)
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	defer useTempTurtlePath(t)()
+
+	// Without a client CA no client certificates are requested.
+	tlsConfig, err := newTLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	} else if tlsConfig.ClientAuth != tls.NoClientCert {
+		t.Errorf("got client auth %v, want none", tlsConfig.ClientAuth)
+	}
+
+	// Invalid client CA files are rejected.
+	caFile := filepath.Join(config.Config.TurtlePath, "ca.pem")
+	if err = ioutil.WriteFile(caFile, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config.Config.TLSClientCAFile = caFile
+
+	if _, err = newTLSConfig(); err == nil {
+		t.Errorf("expected an error for an invalid client CA file")
+	}
+}
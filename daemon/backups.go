@@ -26,7 +26,6 @@ import (
 	"time"
 
 	"github.com/desertbit/turtle/daemon/apps"
-	"github.com/desertbit/turtle/daemon/config"
 
 	log "github.com/Sirupsen/logrus"
 )
@@ -66,33 +65,25 @@ func removeOldBackups() error {
 		allErr += err.Error() + "\n"
 	}
 
-	// Create the expiration unix timestamp.
-	expire := time.Now().Unix() - config.Config.KeepBackupsDuration
+	// The last replicated backups are the parents of the next incremental replication.
+	parents, err := replicationParents()
+	if err != nil {
+		addErr(err)
+	}
 
 	// Get all apps.
 	curApps := apps.Apps()
 
 	for _, app := range curApps {
-		// Get all backups of the app.
-		backups, err := app.Backups()
+		// Get all backups expired by the retention policy of the app.
+		expired, err := expiredBackups(app, parents[app.Name()])
 		if err != nil {
 			addErr(err)
 			continue
 		}
 
-		// Remove all old backups.
-		for _, b := range backups {
-			u, err := strconv.ParseInt(b, 10, 64)
-			if err != nil {
-				addErr(err)
-				continue
-			}
-
-			// Skip if this is not an old backup.
-			if (expire - u) <= 0 {
-				continue
-			}
-
+		// Remove all expired backups.
+		for _, b := range expired {
 			log.Infof("Removing old backup '%s' of app '%s'.", b, app.Name())
 
 			// Remove the backup.
@@ -114,3 +105,71 @@ func removeOldBackups() error {
 
 	return nil
 }
+
+// pruneBackups removes the backups of the app expired by its retention policy.
+// If dryRun is set, then the backups are not removed.
+// The expired backups are returned.
+func pruneBackups(app *apps.App, dryRun bool) ([]string, error) {
+	// Lock the mutex.
+	removeOldBackupsMutex.Lock()
+	defer removeOldBackupsMutex.Unlock()
+
+	parents, err := replicationParents()
+	if err != nil {
+		return nil, err
+	}
+
+	expired, err := expiredBackups(app, parents[app.Name()])
+	if err != nil || dryRun {
+		return expired, err
+	}
+
+	for i, b := range expired {
+		log.Infof("Removing old backup '%s' of app '%s'.", b, app.Name())
+
+		if err = app.RemoveBackup(b); err != nil {
+			return expired[:i], err
+		}
+	}
+
+	return expired, nil
+}
+
+// expiredBackups returns the sorted backups of the app, which are not kept
+// by its retention policy. Pinned backups and the replication parent are kept.
+func expiredBackups(app *apps.App, replicationParent string) ([]string, error) {
+	// Get all backups of the app.
+	backups, err := app.Backups()
+	if err != nil {
+		return nil, err
+	}
+
+	pinned, err := app.PinnedBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the backup timestamps.
+	var timestamps []int64
+	names := make(map[int64]string)
+	protected := make(map[int64]bool)
+	for _, b := range backups {
+		u, err := strconv.ParseInt(b, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("app '%s': invalid backup '%s': %v", app.Name(), b, err)
+		}
+		timestamps = append(timestamps, u)
+		names[u] = b
+		protected[u] = pinned[b] || b == replicationParent
+	}
+
+	// Obtain the backups expired by the retention policy.
+	r, _ := app.Retention()
+
+	var expired []string
+	for _, u := range r.Expired(timestamps, time.Now(), protected) {
+		expired = append(expired, names[u])
+	}
+
+	return expired, nil
+}
@@ -19,8 +19,12 @@
 package btrfs
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"os/exec"
 	"strconv"
+	"strings"
 
 	"github.com/desertbit/turtle/daemon/config"
 	"github.com/desertbit/turtle/utils"
@@ -104,6 +108,61 @@ func Snapshot(subvolumeDir string, snapshotDir string, readonly bool) error {
 	return nil
 }
 
+// Send writes the btrfs send stream of a readonly subvolume to w.
+// The stream is incremental if the parent subvolume is set.
+func Send(subvolumeDir string, parentDir string, w io.Writer) error {
+	args := []string{"send", "-q"}
+	if len(parentDir) > 0 {
+		args = append(args, "-p", parentDir)
+	}
+	args = append(args, subvolumeDir)
+
+	// Run the command.
+	var stderr bytes.Buffer
+	cmd := exec.Command("btrfs", args...)
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to send btrfs subvolume '%s': %v: %s", subvolumeDir, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// Receive reads a btrfs send stream from r and creates the received
+// readonly subvolume in the directory. The stream must contain the subvolume
+// with the given name. A partially received subvolume is removed on error.
+func Receive(dir string, name string, r io.Reader) error {
+	path := dir + "/" + name
+
+	// Run the command. The stream is confined to the directory with chroot,
+	// because it is sent by a remote host.
+	var stderr bytes.Buffer
+	cmd := exec.Command("btrfs", "receive", "-C", "-e", dir)
+	cmd.Stdin = r
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		err = fmt.Errorf("failed to receive btrfs subvolume '%s': %v: %s", path, err, strings.TrimSpace(stderr.String()))
+	} else if !IsSubvolume(path) {
+		err = fmt.Errorf("failed to receive btrfs subvolume '%s': the stream does not contain the subvolume!", path)
+	}
+
+	if err != nil {
+		// Remove the partially received subvolume.
+		if IsSubvolume(path) {
+			if errD := DeleteSubvolume(path); errD != nil {
+				return fmt.Errorf("%v\n%v", err, errD)
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
 // Balance a btrfs partition.
 func Balance(path string) error {
 	// Run the command.
@@ -114,3 +173,63 @@ func Balance(path string) error {
 
 	return nil
 }
+
+// QgroupUsage is the disk usage of a btrfs subvolume.
+type QgroupUsage struct {
+	Referenced uint64 // Bytes referenced by the subvolume.
+	Exclusive  uint64 // Bytes only used by the subvolume.
+}
+
+// SubvolumeID returns the btrfs ID of a subvolume.
+func SubvolumeID(subvolumeDir string) (uint64, error) {
+	// Run the command.
+	out, err := utils.RunCommandOutput("btrfs", "inspect-internal", "rootid", subvolumeDir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to obtain the btrfs subvolume ID of '%s': %v", subvolumeDir, err)
+	}
+
+	id, err := strconv.ParseUint(strings.TrimSpace(out), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to obtain the btrfs subvolume ID of '%s': %v", subvolumeDir, err)
+	}
+
+	return id, nil
+}
+
+// Qgroups returns the disk usage of all subvolumes of the btrfs partition.
+// The map key is the subvolume ID. Quotas have to be enabled for the partition.
+func Qgroups(path string) (map[uint64]QgroupUsage, error) {
+	// Run the command.
+	out, err := utils.RunCommandOutput("btrfs", "qgroup", "show", "--raw", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain btrfs qgroups of '%s': %v", path, err)
+	}
+
+	qgroups := make(map[uint64]QgroupUsage)
+
+	// Parse the lines in the format: 0/ID REFERENCED EXCLUSIVE
+	// Skip the header lines and the higher level qgroups.
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !strings.HasPrefix(fields[0], "0/") {
+			continue
+		}
+
+		id, err := strconv.ParseUint(strings.TrimPrefix(fields[0], "0/"), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		var u QgroupUsage
+		if u.Referenced, err = strconv.ParseUint(fields[1], 10, 64); err != nil {
+			continue
+		}
+		if u.Exclusive, err = strconv.ParseUint(fields[2], 10, 64); err != nil {
+			continue
+		}
+
+		qgroups[id] = u
+	}
+
+	return qgroups, nil
+}
@@ -0,0 +1,69 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/desertbit/turtle/daemon/config"
+	"github.com/desertbit/turtle/utils"
+)
+
+//#######################//
+//### Daemon commands ###//
+//#######################//
+
+// runCommand runs a daemon command passed as command line arguments.
+// The daemon exits afterwards and does not start the server.
+func runCommand(args []string) error {
+	// Create the turtle directory if not present.
+	if err := utils.MkDirIfNotExists(config.Config.TurtlePath); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "add-token":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: add-token NAME")
+		}
+
+		t, err := addToken(args[1])
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Created API token for '%s':\n%s\n", args[1], t)
+		return nil
+
+	case "remove-token":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: remove-token NAME")
+		}
+
+		if err := removeToken(args[1]); err != nil {
+			return err
+		}
+
+		fmt.Printf("Removed API token of '%s'.\n", args[1])
+		return nil
+
+	default:
+		return fmt.Errorf("unknown command '%s'", args[0])
+	}
+}
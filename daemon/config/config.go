@@ -19,45 +19,95 @@
 package config
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
 const (
 	TurtleRoot = "/turtle"
-)
 
-// TODO: Load the config from the turtle directory.
+	// DefaultFilePath is the default path of the daemon config file.
+	DefaultFilePath = TurtleRoot + "/turtle/config"
+)
 
 var (
+	// Config holds the current daemon configuration.
+	// The default values are overwritten by Load.
 	Config = config{
 		ListenAddress:  ":28239",
 		DockerEndPoint: "unix:///var/run/docker.sock",
 
+		SocketPath: TurtleRoot + "/turtle/turtle.sock",
+		SocketMode: 0660,
+
 		AppPath:    TurtleRoot + "/apps",
 		BackupPath: TurtleRoot + "/backups",
 		TurtlePath: TurtleRoot + "/turtle",
+		LogPath:    TurtleRoot + "/logs",
+
+		LogMaxSize:  10 * 1024 * 1024, // 10 MB
+		LogMaxFiles: 5,
 
 		BtrfsBalanceInterval: 3 * time.Hour,
 		BtrfsBalanceDusage:   20,
 
+		MetricsInterval:        time.Minute,
+		MetricsHistoryDuration: 24 * time.Hour,
+
 		BackupInterval:      4 * time.Hour,
 		KeepBackupsDuration: 60 * 60 * 24 * 10, // 10 days
+
+		UpdatePortOffset:     10000,
+		UpdateVerifyDuration: time.Minute,
+
+		AutoUpdateInterval: 15 * time.Minute,
+
+		ReplicationInterval: time.Hour,
 	}
 )
 
 type config struct {
-	ListenAddress  string
+	ListenAddress  string // The TCP listen address. Disabled if empty.
 	DockerEndPoint string
 
+	SocketPath  string      // The unix socket path for local clients. Disabled if empty.
+	SocketMode  os.FileMode // The file permissions of the unix socket.
+	SocketGroup string      // Optional: The group owning the unix socket.
+
+	TLSCertFile     string // Serve the API over TLS if the certificate and key files are set.
+	TLSKeyFile      string
+	TLSClientCAFile string // Optional: Authenticate clients with certificates signed by this CA.
+
 	AppPath    string
 	BackupPath string
 	TurtlePath string
+	LogPath    string // The directory containing the captured app container logs.
+
+	LogMaxSize  int64 // Rotate a container log file if it exceeds this size in bytes.
+	LogMaxFiles int   // Keep this number of rotated log files for each container.
 
 	BtrfsBalanceInterval time.Duration
 	BtrfsBalanceDusage   int // In percent
 
-	BackupInterval      time.Duration // Create backups of running apps in this interval.
+	MetricsInterval        time.Duration // Sample the resource usage of all apps in this interval.
+	MetricsHistoryDuration time.Duration // Keep the resource usage samples for this duration.
+
+	BackupInterval      time.Duration // The default interval of automatic app backups.
 	KeepBackupsDuration int64         // Keep backups only for x seconds.
+	BackupRetention     Retention     // Optional: The default retention policy replacing the KeepBackupsDuration.
+
+	UpdatePortOffset     int           // Publish the ports of temporary update clones with this offset.
+	UpdateVerifyDuration time.Duration // Verify the health of updated apps for this duration.
+
+	AutoUpdateInterval time.Duration // The default source poll interval of apps with automatic updates.
+
+	ReplicationTarget    string        // Optional: A local btrfs directory or the URL of a remote turtle daemon.
+	ReplicationTokenFile string        // The file containing the API token for the remote daemon.
+	ReplicationCAFile    string        // Optional: Verify the TLS certificate of the remote daemon with this CA.
+	ReplicationInterval  time.Duration // Replicate the app backups in this interval.
 }
 
 // StateFilePath returns the turtle state file path.
@@ -65,7 +115,141 @@ func (c *config) StateFilePath() string {
 	return c.TurtlePath + "/state"
 }
 
+// TokensFilePath returns the file path to the API tokens.
+func (c *config) TokensFilePath() string {
+	return c.TurtlePath + "/tokens"
+}
+
+// GroupsFilePath returns the file path to the access groups.
+func (c *config) GroupsFilePath() string {
+	return c.TurtlePath + "/groups"
+}
+
+// MetricsDirPath returns the directory path of the app resource usage histories.
+func (c *config) MetricsDirPath() string {
+	return c.TurtlePath + "/metrics"
+}
+
+// TLSEnabled returns a boolean whenever the API is served over TLS.
+func (c *config) TLSEnabled() bool {
+	return len(c.TLSCertFile) > 0 && len(c.TLSKeyFile) > 0
+}
+
+// RegistriesFilePath returns the file path to the encrypted docker registry credentials.
+func (c *config) RegistriesFilePath() string {
+	return c.TurtlePath + "/registries"
+}
+
+// RegistriesKeyFilePath returns the file path to the key of the docker registry credentials.
+func (c *config) RegistriesKeyFilePath() string {
+	return c.TurtlePath + "/registries.key"
+}
+
+// UpdatesDirPath returns the directory path of the temporary update clones.
+func (c *config) UpdatesDirPath() string {
+	return c.TurtlePath + "/updates"
+}
+
+// ReplicasDirPath returns the directory path of the replicas received from remote daemons.
+func (c *config) ReplicasDirPath() string {
+	return c.TurtlePath + "/replicas"
+}
+
+// ReplicationStateFilePath returns the file path of the replication state.
+func (c *config) ReplicationStateFilePath() string {
+	return c.TurtlePath + "/replication"
+}
+
+// IsRemoteReplication returns a boolean whenever the replication target is a remote daemon.
+func (c *config) IsRemoteReplication() bool {
+	return strings.HasPrefix(c.ReplicationTarget, "http://") || strings.HasPrefix(c.ReplicationTarget, "https://")
+}
+
+// DefaultRetention returns the backup retention policy of apps without their own policy.
+// All backups within the KeepBackupsDuration are kept if no BackupRetention is set.
+func (c *config) DefaultRetention() Retention {
+	if !c.BackupRetention.IsZero() {
+		return c.BackupRetention
+	}
+
+	return Retention{All: time.Duration(c.KeepBackupsDuration) * time.Second}
+}
+
 // KnownHostsFilePath returns the file path to the known and trusted hosts.
 func (c *config) KnownHostsFilePath() string {
 	return c.TurtlePath + "/ssh/known_hosts"
 }
+
+// Validate checks if required values are missing or invalid.
+func (c *config) Validate() error {
+	if len(c.ListenAddress) == 0 && len(c.SocketPath) == 0 {
+		return fmt.Errorf("ListenAddress and SocketPath are empty!")
+	} else if len(c.SocketPath) > 0 && !filepath.IsAbs(c.SocketPath) {
+		return fmt.Errorf("SocketPath '%s' is not an absolute path!", c.SocketPath)
+	} else if c.SocketMode&^os.ModePerm != 0 {
+		return fmt.Errorf("SocketMode '%04o' is not a valid permission mode!", uint32(c.SocketMode))
+	} else if len(c.DockerEndPoint) == 0 {
+		return fmt.Errorf("DockerEndPoint is empty!")
+	}
+
+	// The TLS certificate requires a key and vice versa.
+	if (len(c.TLSCertFile) > 0) != (len(c.TLSKeyFile) > 0) {
+		return fmt.Errorf("TLSCertFile and TLSKeyFile have to be set both!")
+	} else if len(c.TLSClientCAFile) > 0 && !c.TLSEnabled() {
+		return fmt.Errorf("TLSClientCAFile requires TLSCertFile and TLSKeyFile!")
+	}
+
+	// All turtle paths have to be absolute.
+	paths := map[string]string{
+		"AppPath":    c.AppPath,
+		"BackupPath": c.BackupPath,
+		"TurtlePath": c.TurtlePath,
+		"LogPath":    c.LogPath,
+	}
+	for name, path := range paths {
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("%s '%s' is not an absolute path!", name, path)
+		}
+	}
+
+	if c.LogMaxSize <= 0 {
+		return fmt.Errorf("LogMaxSize '%v' has to be greater than zero!", c.LogMaxSize)
+	} else if c.LogMaxFiles < 0 {
+		return fmt.Errorf("LogMaxFiles '%v' must not be negative!", c.LogMaxFiles)
+	}
+
+	if c.BtrfsBalanceInterval <= 0 {
+		return fmt.Errorf("BtrfsBalanceInterval '%v' has to be greater than zero!", c.BtrfsBalanceInterval)
+	} else if c.BtrfsBalanceDusage < 0 || c.BtrfsBalanceDusage > 100 {
+		return fmt.Errorf("BtrfsBalanceDusage '%v' is not a valid percentage!", c.BtrfsBalanceDusage)
+	} else if c.MetricsInterval <= 0 {
+		return fmt.Errorf("MetricsInterval '%v' has to be greater than zero!", c.MetricsInterval)
+	} else if c.MetricsHistoryDuration < c.MetricsInterval {
+		return fmt.Errorf("MetricsHistoryDuration '%v' has to be at least the MetricsInterval!", c.MetricsHistoryDuration)
+	} else if c.BackupInterval <= 0 {
+		return fmt.Errorf("BackupInterval '%v' has to be greater than zero!", c.BackupInterval)
+	} else if c.KeepBackupsDuration <= 0 {
+		return fmt.Errorf("KeepBackupsDuration '%v' has to be greater than zero!", c.KeepBackupsDuration)
+	} else if c.UpdatePortOffset <= 0 || c.UpdatePortOffset > 65535 {
+		return fmt.Errorf("UpdatePortOffset '%v' has an invalid range!", c.UpdatePortOffset)
+	} else if c.UpdateVerifyDuration <= 0 {
+		return fmt.Errorf("UpdateVerifyDuration '%v' has to be greater than zero!", c.UpdateVerifyDuration)
+	} else if c.AutoUpdateInterval < time.Minute {
+		return fmt.Errorf("AutoUpdateInterval '%v' has to be at least one minute!", c.AutoUpdateInterval)
+	} else if c.ReplicationInterval < time.Minute {
+		return fmt.Errorf("ReplicationInterval '%v' has to be at least one minute!", c.ReplicationInterval)
+	}
+
+	// The replication target is either a local directory or a remote daemon.
+	if len(c.ReplicationTarget) > 0 {
+		if c.IsRemoteReplication() {
+			if len(c.ReplicationTokenFile) == 0 {
+				return fmt.Errorf("ReplicationTokenFile is required for the remote replication target!")
+			}
+		} else if !filepath.IsAbs(c.ReplicationTarget) {
+			return fmt.Errorf("ReplicationTarget '%s' is neither an absolute path nor a daemon URL!", c.ReplicationTarget)
+		}
+	}
+
+	return nil
+}
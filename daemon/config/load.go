@@ -0,0 +1,551 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/desertbit/turtle/utils"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	// The environment variable to set the config file path.
+	FilePathEnv = "TURTLE_CONFIG"
+
+	// The sources of an option value.
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+var (
+	// FilePath is the path of the loaded config file.
+	FilePath = DefaultFilePath
+
+	// sources holds the source of each option value.
+	sources = make(map[string]string)
+)
+
+//###################//
+//### Option type ###//
+//###################//
+
+// Option is a single config value with its source.
+type Option struct {
+	Name   string
+	Value  string
+	Source string
+}
+
+// option describes how a config value is set and obtained.
+type option struct {
+	Name  string // The option name used in the config file.
+	Env   string // The environment variable name.
+	Flag  string // The command line flag name.
+	Usage string
+
+	set func(c *config, value string) error
+	get func(c *config) string
+
+	// Optional: formats integer config file values.
+	// Integers are formatted as decimal numbers if nil.
+	formatInt func(v int64) string
+}
+
+var options = []*option{
+	{
+		Name:  "ListenAddress",
+		Env:   "TURTLE_LISTEN_ADDRESS",
+		Flag:  "listen-address",
+		Usage: "The TCP address the daemon listens on. Set to an empty string to disable it.",
+		set:   func(c *config, v string) error { c.ListenAddress = v; return nil },
+		get:   func(c *config) string { return c.ListenAddress },
+	},
+	{
+		Name:  "DockerEndPoint",
+		Env:   "TURTLE_DOCKER_ENDPOINT",
+		Flag:  "docker-endpoint",
+		Usage: "The docker daemon endpoint.",
+		set:   func(c *config, v string) error { c.DockerEndPoint = v; return nil },
+		get:   func(c *config) string { return c.DockerEndPoint },
+	},
+	{
+		Name:  "SocketPath",
+		Env:   "TURTLE_SOCKET_PATH",
+		Flag:  "socket-path",
+		Usage: "The unix socket path for local clients. Set to an empty string to disable it.",
+		set:   func(c *config, v string) error { c.SocketPath = v; return nil },
+		get:   func(c *config) string { return c.SocketPath },
+	},
+	{
+		Name:  "SocketMode",
+		Env:   "TURTLE_SOCKET_MODE",
+		Flag:  "socket-mode",
+		Usage: "The octal file permissions of the unix socket.",
+		set: func(c *config, v string) error {
+			m, err := strconv.ParseUint(v, 8, 32)
+			if err != nil {
+				return err
+			}
+			c.SocketMode = os.FileMode(m)
+			return nil
+		},
+		get: func(c *config) string { return fmt.Sprintf("%04o", uint32(c.SocketMode)) },
+
+		// TOML integers like 0o660 are already decoded to their value.
+		formatInt: func(v int64) string { return strconv.FormatInt(v, 8) },
+	},
+	{
+		Name:  "SocketGroup",
+		Env:   "TURTLE_SOCKET_GROUP",
+		Flag:  "socket-group",
+		Usage: "The group owning the unix socket.",
+		set:   func(c *config, v string) error { c.SocketGroup = v; return nil },
+		get:   func(c *config) string { return c.SocketGroup },
+	},
+	{
+		Name:  "TLSCertFile",
+		Env:   "TURTLE_TLS_CERT_FILE",
+		Flag:  "tls-cert-file",
+		Usage: "The TLS certificate file. Enables TLS if set together with the key file.",
+		set:   func(c *config, v string) error { c.TLSCertFile = v; return nil },
+		get:   func(c *config) string { return c.TLSCertFile },
+	},
+	{
+		Name:  "TLSKeyFile",
+		Env:   "TURTLE_TLS_KEY_FILE",
+		Flag:  "tls-key-file",
+		Usage: "The TLS private key file.",
+		set:   func(c *config, v string) error { c.TLSKeyFile = v; return nil },
+		get:   func(c *config) string { return c.TLSKeyFile },
+	},
+	{
+		Name:  "TLSClientCAFile",
+		Env:   "TURTLE_TLS_CLIENT_CA_FILE",
+		Flag:  "tls-client-ca-file",
+		Usage: "Authenticate clients with certificates signed by this CA.",
+		set:   func(c *config, v string) error { c.TLSClientCAFile = v; return nil },
+		get:   func(c *config) string { return c.TLSClientCAFile },
+	},
+	{
+		Name:  "AppPath",
+		Env:   "TURTLE_APP_PATH",
+		Flag:  "app-path",
+		Usage: "The directory containing all app subvolumes.",
+		set:   func(c *config, v string) error { c.AppPath = v; return nil },
+		get:   func(c *config) string { return c.AppPath },
+	},
+	{
+		Name:  "BackupPath",
+		Env:   "TURTLE_BACKUP_PATH",
+		Flag:  "backup-path",
+		Usage: "The directory containing all app backups.",
+		set:   func(c *config, v string) error { c.BackupPath = v; return nil },
+		get:   func(c *config) string { return c.BackupPath },
+	},
+	{
+		Name:  "TurtlePath",
+		Env:   "TURTLE_TURTLE_PATH",
+		Flag:  "turtle-path",
+		Usage: "The directory containing the turtle state and ssh files.",
+		set:   func(c *config, v string) error { c.TurtlePath = v; return nil },
+		get:   func(c *config) string { return c.TurtlePath },
+	},
+	{
+		Name:  "LogPath",
+		Env:   "TURTLE_LOG_PATH",
+		Flag:  "log-path",
+		Usage: "The directory containing the captured app container logs.",
+		set:   func(c *config, v string) error { c.LogPath = v; return nil },
+		get:   func(c *config) string { return c.LogPath },
+	},
+	{
+		Name:  "LogMaxSize",
+		Env:   "TURTLE_LOG_MAX_SIZE",
+		Flag:  "log-max-size",
+		Usage: "Rotate a container log file if it exceeds this size in bytes.",
+		set: func(c *config, v string) (err error) {
+			c.LogMaxSize, err = strconv.ParseInt(v, 10, 64)
+			return err
+		},
+		get: func(c *config) string { return strconv.FormatInt(c.LogMaxSize, 10) },
+	},
+	{
+		Name:  "LogMaxFiles",
+		Env:   "TURTLE_LOG_MAX_FILES",
+		Flag:  "log-max-files",
+		Usage: "The number of rotated log files to keep for each container.",
+		set: func(c *config, v string) (err error) {
+			c.LogMaxFiles, err = strconv.Atoi(v)
+			return err
+		},
+		get: func(c *config) string { return strconv.Itoa(c.LogMaxFiles) },
+	},
+	{
+		Name:  "BtrfsBalanceInterval",
+		Env:   "TURTLE_BTRFS_BALANCE_INTERVAL",
+		Flag:  "btrfs-balance-interval",
+		Usage: "Balance the btrfs partition in this interval.",
+		set:   durationSetter(func(c *config) *time.Duration { return &c.BtrfsBalanceInterval }),
+		get:   func(c *config) string { return c.BtrfsBalanceInterval.String() },
+	},
+	{
+		Name:  "BtrfsBalanceDusage",
+		Env:   "TURTLE_BTRFS_BALANCE_DUSAGE",
+		Flag:  "btrfs-balance-dusage",
+		Usage: "The btrfs balance data usage filter in percent.",
+		set: func(c *config, v string) (err error) {
+			c.BtrfsBalanceDusage, err = strconv.Atoi(v)
+			return err
+		},
+		get: func(c *config) string { return strconv.Itoa(c.BtrfsBalanceDusage) },
+	},
+	{
+		Name:  "MetricsInterval",
+		Env:   "TURTLE_METRICS_INTERVAL",
+		Flag:  "metrics-interval",
+		Usage: "Sample the resource usage of all apps in this interval.",
+		set:   durationSetter(func(c *config) *time.Duration { return &c.MetricsInterval }),
+		get:   func(c *config) string { return c.MetricsInterval.String() },
+	},
+	{
+		Name:  "MetricsHistoryDuration",
+		Env:   "TURTLE_METRICS_HISTORY_DURATION",
+		Flag:  "metrics-history-duration",
+		Usage: "Keep the resource usage samples for this duration.",
+		set:   durationSetter(func(c *config) *time.Duration { return &c.MetricsHistoryDuration }),
+		get:   func(c *config) string { return c.MetricsHistoryDuration.String() },
+	},
+	{
+		Name:  "BackupInterval",
+		Env:   "TURTLE_BACKUP_INTERVAL",
+		Flag:  "backup-interval",
+		Usage: "Create backups of running apps in this interval, if the app has no own backup schedule.",
+		set:   durationSetter(func(c *config) *time.Duration { return &c.BackupInterval }),
+		get:   func(c *config) string { return c.BackupInterval.String() },
+	},
+	{
+		Name:  "KeepBackupsDuration",
+		Env:   "TURTLE_KEEP_BACKUPS_DURATION",
+		Flag:  "keep-backups-duration",
+		Usage: "Remove backups older than this duration.",
+		set: func(c *config, v string) error {
+			d, err := parseDuration(v)
+			if err != nil {
+				return err
+			}
+			c.KeepBackupsDuration = int64(d / time.Second)
+			return nil
+		},
+		get: func(c *config) string {
+			return (time.Duration(c.KeepBackupsDuration) * time.Second).String()
+		},
+	},
+	{
+		Name:  "BackupRetention",
+		Env:   "TURTLE_BACKUP_RETENTION",
+		Flag:  "backup-retention",
+		Usage: "The default backup retention policy, e.g. all=24h,daily=7,weekly=4,monthly=12. Replaces KeepBackupsDuration if set.",
+		set: func(c *config, v string) (err error) {
+			if len(v) == 0 {
+				c.BackupRetention = Retention{}
+				return nil
+			}
+			c.BackupRetention, err = ParseRetention(v)
+			return err
+		},
+		get: func(c *config) string { return c.BackupRetention.String() },
+	},
+	{
+		Name:  "UpdatePortOffset",
+		Env:   "TURTLE_UPDATE_PORT_OFFSET",
+		Flag:  "update-port-offset",
+		Usage: "Publish the ports of temporary update clones with this offset.",
+		set: func(c *config, v string) (err error) {
+			c.UpdatePortOffset, err = strconv.Atoi(v)
+			return err
+		},
+		get: func(c *config) string { return strconv.Itoa(c.UpdatePortOffset) },
+	},
+	{
+		Name:  "UpdateVerifyDuration",
+		Env:   "TURTLE_UPDATE_VERIFY_DURATION",
+		Flag:  "update-verify-duration",
+		Usage: "Verify the health of updated apps for this duration.",
+		set:   durationSetter(func(c *config) *time.Duration { return &c.UpdateVerifyDuration }),
+		get:   func(c *config) string { return c.UpdateVerifyDuration.String() },
+	},
+	{
+		Name:  "AutoUpdateInterval",
+		Env:   "TURTLE_AUTO_UPDATE_INTERVAL",
+		Flag:  "auto-update-interval",
+		Usage: "The default source poll interval of apps with automatic updates.",
+		set:   durationSetter(func(c *config) *time.Duration { return &c.AutoUpdateInterval }),
+		get:   func(c *config) string { return c.AutoUpdateInterval.String() },
+	},
+	{
+		Name:  "ReplicationTarget",
+		Env:   "TURTLE_REPLICATION_TARGET",
+		Flag:  "replication-target",
+		Usage: "Replicate the app backups to this local btrfs directory or remote daemon URL. Disabled if empty.",
+		set:   func(c *config, v string) error { c.ReplicationTarget = v; return nil },
+		get:   func(c *config) string { return c.ReplicationTarget },
+	},
+	{
+		Name:  "ReplicationTokenFile",
+		Env:   "TURTLE_REPLICATION_TOKEN_FILE",
+		Flag:  "replication-token-file",
+		Usage: "The file containing the API token for the remote replication daemon.",
+		set:   func(c *config, v string) error { c.ReplicationTokenFile = v; return nil },
+		get:   func(c *config) string { return c.ReplicationTokenFile },
+	},
+	{
+		Name:  "ReplicationCAFile",
+		Env:   "TURTLE_REPLICATION_CA_FILE",
+		Flag:  "replication-ca-file",
+		Usage: "Verify the TLS certificate of the remote replication daemon with this CA.",
+		set:   func(c *config, v string) error { c.ReplicationCAFile = v; return nil },
+		get:   func(c *config) string { return c.ReplicationCAFile },
+	},
+	{
+		Name:  "ReplicationInterval",
+		Env:   "TURTLE_REPLICATION_INTERVAL",
+		Flag:  "replication-interval",
+		Usage: "Replicate the app backups in this interval.",
+		set:   durationSetter(func(c *config) *time.Duration { return &c.ReplicationInterval }),
+		get:   func(c *config) string { return c.ReplicationInterval.String() },
+	},
+}
+
+//##############//
+//### Public ###//
+//##############//
+
+// Load the daemon configuration.
+// The default values are overwritten in the following order:
+// config file, environment variables and command line arguments.
+// Finally the resulting configuration is validated.
+// The remaining non-flag arguments are returned.
+func Load(args []string) ([]string, error) {
+	// Create the command line flags.
+	flagSet := flag.NewFlagSet("turtle", flag.ContinueOnError)
+	configPath := flagSet.String("config", "", "The config file path. Environment variable: "+FilePathEnv)
+
+	flagValues := make(map[string]*string)
+	for _, o := range options {
+		flagValues[o.Name] = flagSet.String(o.Flag, "", o.Usage+" Environment variable: "+o.Env)
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// Set the default sources.
+	for _, o := range options {
+		sources[o.Name] = SourceDefault
+	}
+
+	// Obtain the config file path.
+	// The file is optional, if the default path is used.
+	required := true
+	if len(*configPath) > 0 {
+		FilePath = *configPath
+	} else if p := os.Getenv(FilePathEnv); len(p) > 0 {
+		FilePath = p
+	} else {
+		required = false
+	}
+
+	// Load the config file.
+	if err := loadFile(FilePath, required); err != nil {
+		return nil, err
+	}
+
+	// Apply the environment variables.
+	for _, o := range options {
+		v, ok := os.LookupEnv(o.Env)
+		if !ok {
+			continue
+		}
+
+		if err := o.set(&Config, v); err != nil {
+			return nil, fmt.Errorf("invalid value for environment variable '%s': %v", o.Env, err)
+		}
+		sources[o.Name] = SourceEnv
+	}
+
+	// Apply the command line flags which were set.
+	var err error
+	flagSet.Visit(func(f *flag.Flag) {
+		for _, o := range options {
+			if err != nil || f.Name != o.Flag {
+				continue
+			}
+
+			if errS := o.set(&Config, *flagValues[o.Name]); errS != nil {
+				err = fmt.Errorf("invalid value for flag '-%s': %v", o.Flag, errS)
+				return
+			}
+			sources[o.Name] = SourceFlag
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the final configuration.
+	if err = Config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %v", err)
+	}
+
+	return flagSet.Args(), nil
+}
+
+// Options returns all current config values sorted by their name.
+func Options() []Option {
+	list := make([]Option, len(options))
+	for i, o := range options {
+		source, ok := sources[o.Name]
+		if !ok {
+			source = SourceDefault
+		}
+
+		list[i] = Option{
+			Name:   o.Name,
+			Value:  o.get(&Config),
+			Source: source,
+		}
+	}
+
+	// Sort the options by their name.
+	sort.Sort(byOptionName(list))
+
+	return list
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// loadFile loads and applies the config file.
+// An error is returned if the file is required and missing.
+func loadFile(path string, required bool) error {
+	e, err := utils.Exists(path)
+	if err != nil {
+		return err
+	} else if !e {
+		if required {
+			return fmt.Errorf("config file '%s' does not exists!", path)
+		}
+		return nil
+	}
+
+	// Decode the file to a map. The values are converted to strings,
+	// because they are parsed the same way as environment variables.
+	values := make(map[string]interface{})
+	_, err = toml.DecodeFile(path, &values)
+	if err != nil {
+		return fmt.Errorf("failed to load config file '%s': %v", path, err)
+	}
+
+	for name, v := range values {
+		o := getOption(name)
+		if o == nil {
+			return fmt.Errorf("config file '%s': unknown option '%s'", path, name)
+		}
+
+		value, err := fileValue(o, v)
+		if err != nil {
+			return fmt.Errorf("config file '%s': invalid value for option '%s': %v", path, name, err)
+		}
+
+		if err = o.set(&Config, value); err != nil {
+			return fmt.Errorf("config file '%s': invalid value for option '%s': %v", path, name, err)
+		}
+		sources[o.Name] = SourceFile
+	}
+
+	return nil
+}
+
+// fileValue converts a decoded config file value to its string representation.
+func fileValue(o *option, v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case int64:
+		if o.formatInt != nil {
+			return o.formatInt(v), nil
+		}
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		return "", fmt.Errorf("unsupported value type '%T'", v)
+	}
+}
+
+// getOption returns the option with the given name or nil if not found.
+func getOption(name string) *option {
+	for _, o := range options {
+		if o.Name == name {
+			return o
+		}
+	}
+	return nil
+}
+
+// durationSetter returns a setter function which parses a duration string.
+func durationSetter(field func(c *config) *time.Duration) func(c *config, v string) error {
+	return func(c *config, v string) error {
+		d, err := parseDuration(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+// parseDuration parses a duration string like "90s" or "2h45m".
+// Bare integers are interpreted as seconds.
+func parseDuration(v string) (time.Duration, error) {
+	if s, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Duration(s) * time.Second, nil
+	}
+
+	return time.ParseDuration(v)
+}
+
+// byOptionName implements sort.Interface to sort options by their name.
+type byOptionName []Option
+
+func (s byOptionName) Len() int           { return len(s) }
+func (s byOptionName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byOptionName) Less(i, j int) bool { return s[i].Name < s[j].Name }
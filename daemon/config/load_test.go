@@ -0,0 +1,222 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// useDefaults restores the default configuration after the test.
+func useDefaults() func() {
+	c := Config
+	return func() {
+		Config = c
+		FilePath = DefaultFilePath
+		sources = make(map[string]string)
+	}
+}
+
+// setEnv sets the environment variable. The returned function unsets it.
+func setEnv(key, value string) func() {
+	os.Setenv(key, value)
+	return func() {
+		os.Unsetenv(key)
+	}
+}
+
+// writeConfigFile writes the content to a temporary config file.
+// The returned function removes the file.
+func writeConfigFile(t *testing.T, content string) (string, func()) {
+	f, err := ioutil.TempFile("", "turtle-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err = f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+
+	return f.Name(), func() {
+		os.Remove(f.Name())
+	}
+}
+
+// optionSource returns the source of the option value.
+func optionSource(name string) string {
+	for _, o := range Options() {
+		if o.Name == name {
+			return o.Source
+		}
+	}
+	return ""
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	defer useDefaults()()
+
+	path, remove := writeConfigFile(t, `
+BackupInterval = "1h"
+BtrfsBalanceInterval = "2h"
+BtrfsBalanceDusage = "30"
+`)
+	defer remove()
+
+	defer setEnv("TURTLE_BTRFS_BALANCE_INTERVAL", "3h")()
+	defer setEnv("TURTLE_BTRFS_BALANCE_DUSAGE", "40")()
+
+	args, err := Load([]string{"-config", path, "-btrfs-balance-dusage", "50", "arg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(args) != 1 || args[0] != "arg" {
+		t.Errorf("remaining args: got %v, want [arg]", args)
+	}
+
+	// The file is overwritten by the environment and the environment by the flags.
+	if Config.BackupInterval != time.Hour {
+		t.Errorf("BackupInterval: got %v, want 1h", Config.BackupInterval)
+	}
+	if Config.BtrfsBalanceInterval != 3*time.Hour {
+		t.Errorf("BtrfsBalanceInterval: got %v, want 3h", Config.BtrfsBalanceInterval)
+	}
+	if Config.BtrfsBalanceDusage != 50 {
+		t.Errorf("BtrfsBalanceDusage: got %v, want 50", Config.BtrfsBalanceDusage)
+	}
+
+	sources := map[string]string{
+		"BackupInterval":       SourceFile,
+		"BtrfsBalanceInterval": SourceEnv,
+		"BtrfsBalanceDusage":   SourceFlag,
+		"KeepBackupsDuration":  SourceDefault,
+	}
+	for name, want := range sources {
+		if got := optionSource(name); got != want {
+			t.Errorf("%s: got source '%s', want '%s'", name, got, want)
+		}
+	}
+}
+
+func TestLoadFileValues(t *testing.T) {
+	defer useDefaults()()
+
+	// Integers are accepted for numbers and durations in seconds.
+	path, remove := writeConfigFile(t, `
+KeepBackupsDuration = 864000
+BackupInterval = 5400
+BtrfsBalanceDusage = 30
+`)
+	defer remove()
+
+	if _, err := Load([]string{"-config", path}); err != nil {
+		t.Fatal(err)
+	}
+
+	if Config.KeepBackupsDuration != 864000 {
+		t.Errorf("KeepBackupsDuration: got %v, want 864000", Config.KeepBackupsDuration)
+	}
+	if Config.BackupInterval != 90*time.Minute {
+		t.Errorf("BackupInterval: got %v, want 1h30m", Config.BackupInterval)
+	}
+	if Config.BtrfsBalanceDusage != 30 {
+		t.Errorf("BtrfsBalanceDusage: got %v, want 30", Config.BtrfsBalanceDusage)
+	}
+}
+
+func TestLoadSocketMode(t *testing.T) {
+	reset := useDefaults()
+	defer reset()
+
+	// Octal integers and strings result in the same mode.
+	for _, content := range []string{`SocketMode = 0o640`, `SocketMode = "0640"`, `SocketMode = "640"`} {
+		path, remove := writeConfigFile(t, content)
+		if _, err := Load([]string{"-config", path}); err != nil {
+			t.Errorf("'%s': %v", content, err)
+		} else if Config.SocketMode != 0640 {
+			t.Errorf("'%s': got mode %04o, want 0640", content, uint32(Config.SocketMode))
+		}
+		remove()
+		reset()
+	}
+
+	for _, content := range []string{`SocketMode = 0o1777`, `SocketMode = -1`, `SocketMode = "rw"`} {
+		path, remove := writeConfigFile(t, content)
+		if _, err := Load([]string{"-config", path}); err == nil {
+			t.Errorf("'%s': expected an error", content)
+		}
+		remove()
+		reset()
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	reset := useDefaults()
+	defer reset()
+
+	files := []string{
+		`Unknown = "x"`,
+		`BackupInterval = "x"`,
+		`BackupInterval = ["1h"]`,
+		`BtrfsBalanceDusage = 1.5`,
+		`BtrfsBalanceDusage = 101`,
+		`KeepBackupsDuration = 0`,
+	}
+
+	for _, content := range files {
+		path, remove := writeConfigFile(t, content)
+		if _, err := Load([]string{"-config", path}); err == nil {
+			t.Errorf("'%s': expected an error", content)
+		}
+		remove()
+		reset()
+	}
+
+	// A missing config file is only an error if the path is set explicitly.
+	if _, err := Load([]string{"-config", "/nonexistent/turtle/config"}); err == nil {
+		t.Errorf("expected an error for a missing config file")
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"0":     0,
+		"90":    90 * time.Second,
+		"90s":   90 * time.Second,
+		"2h45m": 2*time.Hour + 45*time.Minute,
+	}
+
+	for s, want := range tests {
+		d, err := parseDuration(s)
+		if err != nil {
+			t.Errorf("'%s': %v", s, err)
+		} else if d != want {
+			t.Errorf("'%s': got %v, want %v", s, d, want)
+		}
+	}
+
+	for _, s := range []string{"", "x", "1.5", "10 days"} {
+		if _, err := parseDuration(s); err == nil {
+			t.Errorf("'%s': expected an error", s)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//######################//
+//### Retention type ###//
+//######################//
+
+// Retention is a grandfather-father-son backup retention policy.
+// A backup is kept if it is kept by any rule.
+// The interval rules keep the latest backup of each of the
+// last N hours, days, weeks, months or years with backups.
+type Retention struct {
+	All     time.Duration // Keep all backups within this duration.
+	Hourly  int
+	Daily   int
+	Weekly  int
+	Monthly int
+	Yearly  int
+}
+
+// ParseRetention parses a retention policy in the form of
+// comma separated RULE=VALUE pairs, e.g. all=24h,daily=7,weekly=4,monthly=12
+// The rules are all, hourly, daily, weekly, monthly and yearly.
+func ParseRetention(s string) (r Retention, err error) {
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if len(pair) == 0 {
+			continue
+		}
+
+		i := strings.Index(pair, "=")
+		if i < 0 {
+			return r, fmt.Errorf("invalid retention rule '%s': expected RULE=VALUE", pair)
+		}
+		rule, value := strings.TrimSpace(pair[:i]), strings.TrimSpace(pair[i+1:])
+
+		// The duration of all backups.
+		if rule == "all" {
+			if r.All, err = time.ParseDuration(value); err != nil {
+				return r, fmt.Errorf("invalid retention rule '%s': %v", pair, err)
+			} else if r.All < 0 {
+				return r, fmt.Errorf("invalid retention rule '%s': negative duration", pair)
+			}
+			continue
+		}
+
+		var field *int
+		switch rule {
+		case "hourly":
+			field = &r.Hourly
+		case "daily":
+			field = &r.Daily
+		case "weekly":
+			field = &r.Weekly
+		case "monthly":
+			field = &r.Monthly
+		case "yearly":
+			field = &r.Yearly
+		default:
+			return r, fmt.Errorf("invalid retention rule '%s': unknown rule '%s'", pair, rule)
+		}
+
+		if *field, err = strconv.Atoi(value); err != nil {
+			return r, fmt.Errorf("invalid retention rule '%s': %v", pair, err)
+		} else if *field < 0 {
+			return r, fmt.Errorf("invalid retention rule '%s': negative count", pair)
+		}
+	}
+
+	if r.IsZero() {
+		return r, fmt.Errorf("the retention policy '%s' would not keep any backups", s)
+	}
+
+	return r, nil
+}
+
+// IsZero returns a boolean whenever no rule is set.
+func (r Retention) IsZero() bool {
+	return r == Retention{}
+}
+
+// String returns the policy in the format of ParseRetention.
+func (r Retention) String() string {
+	var rules []string
+	if r.All > 0 {
+		rules = append(rules, "all="+r.All.String())
+	}
+
+	counts := []struct {
+		rule  string
+		count int
+	}{
+		{"hourly", r.Hourly},
+		{"daily", r.Daily},
+		{"weekly", r.Weekly},
+		{"monthly", r.Monthly},
+		{"yearly", r.Yearly},
+	}
+	for _, c := range counts {
+		if c.count > 0 {
+			rules = append(rules, c.rule+"="+strconv.Itoa(c.count))
+		}
+	}
+
+	return strings.Join(rules, ",")
+}
+
+// Keep returns the unix timestamps of the backups which are kept by the policy.
+func (r Retention) Keep(backups []int64, now time.Time) map[int64]bool {
+	keep := make(map[int64]bool)
+
+	// Sort the backups from newest to oldest.
+	sorted := make([]int64, len(backups))
+	copy(sorted, backups)
+	sort.Sort(sort.Reverse(byUnix(sorted)))
+
+	// Keep all recent backups.
+	for _, u := range sorted {
+		if now.Sub(time.Unix(u, 0)) <= r.All {
+			keep[u] = true
+		}
+	}
+
+	// Keep the latest backup of each period.
+	periods := []struct {
+		count int
+		key   func(t time.Time) string
+	}{
+		{r.Hourly, func(t time.Time) string { return t.Format("2006-01-02 15") }},
+		{r.Daily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{r.Weekly, func(t time.Time) string {
+			y, w := t.ISOWeek()
+			return fmt.Sprintf("%d-%d", y, w)
+		}},
+		{r.Monthly, func(t time.Time) string { return t.Format("2006-01") }},
+		{r.Yearly, func(t time.Time) string { return t.Format("2006") }},
+	}
+
+	for _, p := range periods {
+		last := ""
+		count := 0
+		for _, u := range sorted {
+			if count >= p.count {
+				break
+			}
+
+			if k := p.key(time.Unix(u, 0)); k != last {
+				keep[u] = true
+				last = k
+				count++
+			}
+		}
+	}
+
+	return keep
+}
+
+// Expired returns the sorted unix timestamps of the backups which are not kept
+// by the policy. Protected backups, like pinned backups, are never expired.
+func (r Retention) Expired(backups []int64, now time.Time, protected map[int64]bool) []int64 {
+	keep := r.Keep(backups, now)
+
+	var expired []int64
+	for _, u := range backups {
+		if !keep[u] && !protected[u] {
+			expired = append(expired, u)
+		}
+	}
+
+	sort.Sort(byUnix(expired))
+
+	return expired
+}
+
+// byUnix implements sort.Interface to sort unix timestamps.
+type byUnix []int64
+
+func (s byUnix) Len() int           { return len(s) }
+func (s byUnix) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byUnix) Less(i, j int) bool { return s[i] < s[j] }
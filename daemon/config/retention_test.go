@@ -0,0 +1,289 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package config
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// unix returns the unix timestamp of the UTC date.
+func unix(year int, month time.Month, day, hour, min, sec int) int64 {
+	return time.Date(year, month, day, hour, min, sec, 0, time.UTC).Unix()
+}
+
+// useUTC sets the local time zone to UTC, because the periods are bucketed
+// in the local time. The returned function restores the local time zone.
+func useUTC() func() {
+	local := time.Local
+	time.Local = time.UTC
+	return func() {
+		time.Local = local
+	}
+}
+
+func sortedKeys(m map[int64]bool) []int64 {
+	keys := []int64{}
+	for k, v := range m {
+		if v {
+			keys = append(keys, k)
+		}
+	}
+	sort.Sort(byUnix(keys))
+	return keys
+}
+
+func TestParseRetention(t *testing.T) {
+	r, err := ParseRetention("all=24h, hourly=6,daily=7,weekly=4,monthly=12,yearly=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Retention{All: 24 * time.Hour, Hourly: 6, Daily: 7, Weekly: 4, Monthly: 12, Yearly: 2}
+	if r != want {
+		t.Errorf("got %+v, want %+v", r, want)
+	}
+
+	// The string has to be parsed to the same policy.
+	if r2, err := ParseRetention(r.String()); err != nil || r2 != r {
+		t.Errorf("round trip of '%s' failed: %+v, %v", r.String(), r2, err)
+	}
+
+	for _, s := range []string{"", "daily=0", "daily", "daily=-1", "all=-1h", "all=x", "minutely=5", "daily=a"} {
+		if _, err := ParseRetention(s); err == nil {
+			t.Errorf("'%s': expected an error", s)
+		}
+	}
+}
+
+func TestRetentionKeep(t *testing.T) {
+	defer useUTC()()
+
+	now := time.Date(2016, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		r       Retention
+		backups []int64
+		want    []int64
+	}{
+		{
+			name: "all",
+			r:    Retention{All: 24 * time.Hour},
+			backups: []int64{
+				unix(2016, 1, 9, 11, 59, 59),
+				unix(2016, 1, 9, 12, 0, 0),
+				unix(2016, 1, 10, 11, 0, 0),
+			},
+			want: []int64{
+				unix(2016, 1, 9, 12, 0, 0),
+				unix(2016, 1, 10, 11, 0, 0),
+			},
+		},
+		{
+			name: "hourly keeps the newest backup of each hour",
+			r:    Retention{Hourly: 2},
+			backups: []int64{
+				unix(2016, 1, 10, 9, 30, 0),
+				unix(2016, 1, 10, 10, 0, 0),
+				unix(2016, 1, 10, 10, 59, 59),
+				unix(2016, 1, 10, 11, 0, 0),
+				unix(2016, 1, 10, 11, 40, 0),
+			},
+			want: []int64{
+				unix(2016, 1, 10, 10, 59, 59),
+				unix(2016, 1, 10, 11, 40, 0),
+			},
+		},
+		{
+			name: "daily boundary at midnight",
+			r:    Retention{Daily: 2},
+			backups: []int64{
+				unix(2016, 1, 8, 23, 0, 0),
+				unix(2016, 1, 9, 0, 0, 0),
+				unix(2016, 1, 9, 23, 59, 59),
+				unix(2016, 1, 10, 0, 0, 0),
+			},
+			want: []int64{
+				unix(2016, 1, 9, 23, 59, 59),
+				unix(2016, 1, 10, 0, 0, 0),
+			},
+		},
+		{
+			name: "daily skips days without backups",
+			r:    Retention{Daily: 2},
+			backups: []int64{
+				unix(2015, 12, 1, 8, 0, 0),
+				unix(2015, 12, 20, 8, 0, 0),
+				unix(2016, 1, 10, 8, 0, 0),
+			},
+			want: []int64{
+				unix(2015, 12, 20, 8, 0, 0),
+				unix(2016, 1, 10, 8, 0, 0),
+			},
+		},
+		{
+			name: "weekly boundary between sunday and monday",
+			r:    Retention{Weekly: 2},
+			backups: []int64{
+				unix(2015, 12, 27, 12, 0, 0), // Sunday, week 52
+				unix(2016, 1, 3, 23, 59, 59), // Sunday, week 53 of 2015
+				unix(2016, 1, 4, 0, 0, 0),    // Monday, week 1 of 2016
+				unix(2016, 1, 10, 8, 0, 0),   // Sunday, week 1 of 2016
+			},
+			want: []int64{
+				unix(2016, 1, 3, 23, 59, 59),
+				unix(2016, 1, 10, 8, 0, 0),
+			},
+		},
+		{
+			name: "weekly ISO week spans the year change",
+			r:    Retention{Weekly: 2},
+			backups: []int64{
+				unix(2015, 12, 25, 12, 0, 0), // Friday, week 52
+				unix(2015, 12, 31, 12, 0, 0), // Thursday, week 53
+				unix(2016, 1, 2, 12, 0, 0),   // Saturday, week 53 of 2015
+			},
+			want: []int64{
+				unix(2015, 12, 25, 12, 0, 0),
+				unix(2016, 1, 2, 12, 0, 0),
+			},
+		},
+		{
+			name: "monthly boundary",
+			r:    Retention{Monthly: 2},
+			backups: []int64{
+				unix(2015, 11, 15, 12, 0, 0),
+				unix(2015, 11, 30, 23, 59, 59),
+				unix(2015, 12, 1, 0, 0, 0),
+				unix(2015, 12, 31, 23, 59, 59),
+				unix(2016, 1, 1, 0, 0, 0),
+			},
+			want: []int64{
+				unix(2015, 12, 31, 23, 59, 59),
+				unix(2016, 1, 1, 0, 0, 0),
+			},
+		},
+		{
+			name: "yearly",
+			r:    Retention{Yearly: 1},
+			backups: []int64{
+				unix(2015, 12, 31, 23, 59, 59),
+				unix(2016, 1, 1, 0, 0, 0),
+			},
+			want: []int64{
+				unix(2016, 1, 1, 0, 0, 0),
+			},
+		},
+		{
+			name: "rules are combined",
+			r:    Retention{Daily: 1, Monthly: 2},
+			backups: []int64{
+				unix(2015, 11, 20, 12, 0, 0),
+				unix(2015, 12, 20, 12, 0, 0),
+				unix(2015, 12, 21, 12, 0, 0),
+				unix(2016, 1, 9, 12, 0, 0),
+				unix(2016, 1, 10, 8, 0, 0),
+			},
+			want: []int64{
+				unix(2015, 12, 21, 12, 0, 0),
+				unix(2016, 1, 10, 8, 0, 0),
+			},
+		},
+		{
+			name: "the newest backup is kept by any interval rule",
+			r:    Retention{Yearly: 1},
+			backups: []int64{
+				unix(2016, 1, 10, 8, 0, 0),
+				unix(2016, 1, 10, 11, 0, 0),
+				unix(2016, 1, 1, 0, 0, 0),
+			},
+			want: []int64{
+				unix(2016, 1, 10, 11, 0, 0),
+			},
+		},
+		{
+			name: "no backups",
+			r:    Retention{All: time.Hour, Daily: 7},
+			want: []int64{},
+		},
+	}
+
+	for _, test := range tests {
+		got := sortedKeys(test.r.Keep(test.backups, now))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRetentionExpired(t *testing.T) {
+	defer useUTC()()
+
+	now := time.Date(2016, 1, 10, 12, 0, 0, 0, time.UTC)
+	r := Retention{Daily: 1}
+
+	backups := []int64{
+		unix(2016, 1, 10, 11, 0, 0),
+		unix(2016, 1, 7, 8, 0, 0),
+		unix(2016, 1, 9, 8, 0, 0),
+		unix(2016, 1, 8, 8, 0, 0),
+	}
+
+	// The expired backups are sorted.
+	got := r.Expired(backups, now, nil)
+	want := []int64{
+		unix(2016, 1, 7, 8, 0, 0),
+		unix(2016, 1, 8, 8, 0, 0),
+		unix(2016, 1, 9, 8, 0, 0),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Pinned backups are never expired.
+	protected := map[int64]bool{
+		unix(2016, 1, 8, 8, 0, 0): true,
+	}
+	got = r.Expired(backups, now, protected)
+	want = []int64{
+		unix(2016, 1, 7, 8, 0, 0),
+		unix(2016, 1, 9, 8, 0, 0),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("protected: got %v, want %v", got, want)
+	}
+
+	// The protected newest backup is kept, even if the policy keeps no backup.
+	old := Retention{All: 30 * time.Minute}
+	protected = map[int64]bool{
+		unix(2016, 1, 10, 11, 0, 0): true,
+	}
+	got = old.Expired(backups, now, protected)
+	want = []int64{
+		unix(2016, 1, 7, 8, 0, 0),
+		unix(2016, 1, 8, 8, 0, 0),
+		unix(2016, 1, 9, 8, 0, 0),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newest: got %v, want %v", got, want)
+	}
+}
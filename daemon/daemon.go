@@ -19,6 +19,7 @@
 package main
 
 import (
+	"math/rand"
 	"net/http"
 	"os"
 	"os/signal"
@@ -37,6 +38,9 @@ import (
 
 const (
 	InterruptExitCode = -1
+
+	autoUpdateCheckInterval     = 30 * time.Second
+	backupScheduleCheckInterval = 30 * time.Second
 )
 
 func onInterrupt() {
@@ -61,6 +65,9 @@ func onInterrupt() {
 
 // release is called as soon as the daemon application is terminating.
 func release() {
+	// End all open streams. Otherwise the requests lock would block.
+	close(stopStreams)
+
 	// Block the requests http handler method.
 	// Don't handle any further requests,
 	requestRWLock.Lock()
@@ -68,6 +75,9 @@ func release() {
 	// Block the remove old backups job.
 	removeOldBackupsMutex.Lock()
 
+	// Block the replication job.
+	replicationMutex.Lock()
+
 	// Save the current state of all running apps...
 	err := saveCurrentState()
 	if err != nil {
@@ -76,6 +86,11 @@ func release() {
 
 	// Release the app package.
 	apps.Release()
+
+	// Remove the unix socket file.
+	if err = removeSocket(); err != nil {
+		log.Errorln(err)
+	}
 }
 
 // prepareEnv prepares the turtle environment.
@@ -85,6 +100,7 @@ func prepareEnv() (err error) {
 		config.Config.AppPath,
 		config.Config.BackupPath,
 		config.Config.TurtlePath,
+		config.Config.LogPath,
 	}
 
 	for _, dir := range createDirs {
@@ -109,7 +125,9 @@ func btrfsBalanceJob() {
 		log.Infof("Balancing btrfs path '%s'...", config.TurtleRoot)
 
 		// Balance the turtle root btrfs partition.
+		start := time.Now()
 		err := btrfs.Balance(config.TurtleRoot)
+		recordBalance(err, time.Since(start))
 		if err != nil {
 			log.Errorf("Balancing of btrfs path '%s' failed: %v", config.TurtleRoot, err)
 		} else {
@@ -121,6 +139,52 @@ func btrfsBalanceJob() {
 	}
 }
 
+// metricsJob samples the resource usage of all apps.
+func metricsJob() {
+	for {
+		// Sleep.
+		time.Sleep(config.Config.MetricsInterval)
+
+		// Collect the metrics of all apps.
+		apps.CollectMetrics()
+	}
+}
+
+// autoUpdateJob triggers the automatic updates of apps polling their source.
+func autoUpdateJob() {
+	for {
+		// Sleep.
+		time.Sleep(autoUpdateCheckInterval)
+
+		// Check the apps with elapsed poll intervals.
+		apps.CheckAutoUpdates()
+	}
+}
+
+// backupScheduleJob creates the scheduled backups of all apps.
+func backupScheduleJob() {
+	for {
+		// Sleep.
+		time.Sleep(backupScheduleCheckInterval)
+
+		// Backup the apps with due schedules.
+		apps.CheckBackupSchedules()
+	}
+}
+
+// replicationJob replicates the app backups to the replication target.
+func replicationJob() {
+	for {
+		// Sleep.
+		time.Sleep(config.Config.ReplicationInterval)
+
+		// Replicate the new backups of all apps.
+		if err := replicate(); err != nil {
+			log.Errorf("failed to replicate some app backups:\n%v", err)
+		}
+	}
+}
+
 func main() {
 	// Set the maximum number of CPUs that can be executing simultaneously.
 	runtime.GOMAXPROCS(runtime.NumCPU())
@@ -128,8 +192,25 @@ func main() {
 
 	log.Infof("Initializing...")
 
+	// Seed the random number generator used for the restart backoff jitter.
+	rand.Seed(time.Now().UnixNano())
+
+	// Load the daemon configuration.
+	args, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("failed to load turtle configuration: %v", err)
+	}
+
+	// Run the daemon command if passed and exit.
+	if len(args) > 0 {
+		if err = runCommand(args); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+
 	// Prepare the turtle environment.
-	err := prepareEnv()
+	err = prepareEnv()
 	if err != nil {
 		log.Fatalf("failed to prepare turtle environment: %v", err)
 	}
@@ -152,12 +233,75 @@ func main() {
 		log.Warningf("failed to restore previous turtle state: %v", err)
 	}
 
+	// Start the resource usage metrics job.
+	go metricsJob()
+
+	// Start the automatic update job.
+	go autoUpdateJob()
+
+	// Start the automatic backup job.
+	go backupScheduleJob()
+
 	// Start the loop to remove old backups.
 	go autoRemoveOldBackupsLoop()
 
-	// Log
-	log.Infof("Turtle server listening on '%s'", config.Config.ListenAddress)
+	// Start the replication job if a target is configured.
+	if len(config.Config.ReplicationTarget) > 0 {
+		go replicationJob()
+	}
 
 	// Start the http server.
-	log.Fatal(http.ListenAndServe(config.Config.ListenAddress, nil))
+	log.Fatal(listenAndServe())
+}
+
+// listenAndServe starts the http servers on the unix socket and TCP address.
+// The TCP server is served over TLS if enabled.
+func listenAndServe() error {
+	errChan := make(chan error, 2)
+
+	// Start the unix socket server if enabled.
+	if len(config.Config.SocketPath) > 0 {
+		go func() {
+			errChan <- serveSocket()
+		}()
+	}
+
+	// Start the TCP server if enabled.
+	if len(config.Config.ListenAddress) > 0 {
+		go func() {
+			errChan <- serveTCP()
+		}()
+	}
+
+	return <-errChan
+}
+
+// serveTCP starts the http server on the TCP listen address.
+func serveTCP() error {
+	// Warn if no credential is configured, because all requests will be rejected.
+	t, err := loadTokens()
+	if err != nil {
+		return err
+	} else if len(t.Tokens) == 0 && len(config.Config.TLSClientCAFile) == 0 {
+		log.Warningf("no API tokens or TLS client CA configured: all TCP requests will be rejected. Create a token with 'add-token NAME'.")
+	}
+
+	if !config.Config.TLSEnabled() {
+		log.Infof("Turtle server listening on '%s'", config.Config.ListenAddress)
+		return http.ListenAndServe(config.Config.ListenAddress, nil)
+	}
+
+	// Create the TLS config.
+	tlsConfig, err := newTLSConfig()
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:      config.Config.ListenAddress,
+		TLSConfig: tlsConfig,
+	}
+
+	log.Infof("Turtle server listening on '%s' (TLS)", config.Config.ListenAddress)
+	return server.ListenAndServeTLS(config.Config.TLSCertFile, config.Config.TLSKeyFile)
 }
@@ -20,13 +20,20 @@ package docker
 
 import (
 	"archive/tar"
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/desertbit/turtle/daemon/config"
 
@@ -38,9 +45,16 @@ const (
 	TurtlePrefix = "turtle."
 
 	killAfterTimeout = 10 // in seconds
+	statsTimeout     = 10 * time.Second
 
-	imageBuildTag = "turtle-build"
-	imageOldTag   = "turtle-old"
+	// MaxLogLineSize is the maximum size of a single log line in bytes.
+	MaxLogLineSize = 1024 * 1024
+
+	imageBuildTag  = "turtle-build"
+	imageOldTag    = "turtle-old"
+	imageUpdateTag = "turtle-update"
+
+	maxImageTagLength = 128
 )
 
 type StdStream int
@@ -54,6 +68,13 @@ const (
 var (
 	Client *docker.Client
 
+	// Characters which are not allowed in docker image tags.
+	invalidImageTagChars = regexp.MustCompile("[^A-Za-z0-9_.-]")
+
+	// The ID of the container running this daemon.
+	// Empty if the daemon is not running within a container.
+	daemonContainerID string
+
 	eventFuncs        = make(map[int64]func(*docker.APIEvents))
 	eventFuncsCounter int64
 	eventFuncsMutex   sync.Mutex
@@ -75,11 +96,23 @@ func Init() error {
 		return fmt.Errorf("failed to create docker client: %v", err)
 	}
 
+	// Obtain the container of this daemon.
+	// It has to be attached to the app networks to reach the app containers.
+	daemonContainerID, err = getDaemonContainerID()
+	if err != nil {
+		return fmt.Errorf("failed to obtain the daemon container: %v", err)
+	}
+
 	// Stop and remove all turtle containers.
 	if err = CleanupTurtleContainers(); err != nil {
 		return fmt.Errorf("failed to cleanup turtle containers: %v", err)
 	}
 
+	// Remove all unused turtle networks.
+	if err = CleanupTurtleNetworks(); err != nil {
+		return fmt.Errorf("failed to cleanup turtle networks: %v", err)
+	}
+
 	// Start the event listener.
 	if err = startEventListener(); err != nil {
 		return fmt.Errorf("failed to start docker event listener: %v", err)
@@ -125,6 +158,29 @@ func CleanupTurtleContainers() error {
 	return nil
 }
 
+// CleanupTurtleNetworks removes all turtle networks without attached containers.
+func CleanupTurtleNetworks() error {
+	// Get all networks.
+	networks, err := Client.ListNetworks()
+	if err != nil {
+		return err
+	}
+
+	// Remove all networks which start with the turtle prefix.
+	for _, n := range networks {
+		if !strings.HasPrefix(n.Name, TurtlePrefix) {
+			continue
+		}
+
+		err = RemoveNetwork(n.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // StopAndDeleteContainer stops the container and deletes it.
 func StopAndDeleteContainer(id string) error {
 	// Inspect the container.
@@ -285,12 +341,531 @@ func Logs(containerID string, stream StdStream) (string, error) {
 	return strings.TrimSpace(buf.String()), nil
 }
 
+// StreamLogsOptions defines the options of a container log stream.
+type StreamLogsOptions struct {
+	Stream StdStream
+	Follow bool  // Keep the stream open and send new log lines.
+	Tail   int   // Only send the last x lines. 0 sends all lines.
+	Since  int64 // Unix timestamp. Only send lines since this time. 0 sends all lines.
+
+	// Optional: the stream stops as soon as this channel is closed.
+	Stop <-chan struct{}
+}
+
+// LogLine is a single log line of a container.
+type LogLine struct {
+	Stream    StdStream // StdStreamOutput or StdStreamError.
+	Timestamp time.Time
+	Message   string
+}
+
+// StreamLogs streams the container logs line by line to the function f.
+// The stream stops if f returns false or if the logs end.
+// f is never called concurrently.
+func StreamLogs(containerID string, opts StreamLogsOptions, f func(LogLine) bool) error {
+	stdoutR, stdoutW := io.Pipe()
+	stderrR, stderrW := io.Pipe()
+
+	// The context cancels the logs request, which might be idle while following.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Create the logs options.
+	// Timestamps are always requested to fill the log line value.
+	logsOpts := docker.LogsOptions{
+		Context:      ctx,
+		Container:    containerID,
+		Follow:       opts.Follow,
+		Timestamps:   true,
+		Since:        opts.Since,
+		Tail:         "all",
+		OutputStream: stdoutW,
+		ErrorStream:  stderrW,
+	}
+
+	if opts.Tail > 0 {
+		logsOpts.Tail = strconv.Itoa(opts.Tail)
+	}
+
+	if opts.Stream == StdStreamCombined {
+		logsOpts.Stdout = true
+		logsOpts.Stderr = true
+	} else if opts.Stream == StdStreamOutput {
+		logsOpts.Stdout = true
+	} else if opts.Stream == StdStreamError {
+		logsOpts.Stderr = true
+	} else {
+		return fmt.Errorf("invalid stream option!")
+	}
+
+	var mutex sync.Mutex
+	var stopped bool
+	var readErr error
+
+	// stop cancels the logs request and closes the pipes.
+	// Pending writes of the docker client fail and the logs request returns.
+	stop := func() {
+		stopped = true
+		cancel()
+		stdoutR.Close()
+		stderrR.Close()
+	}
+
+	// Stop the stream if requested.
+	if opts.Stop != nil {
+		go func() {
+			select {
+			case <-opts.Stop:
+				mutex.Lock()
+				if !stopped {
+					stop()
+				}
+				mutex.Unlock()
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	// Read the lines of a pipe and pass them to the function.
+	var wg sync.WaitGroup
+	readLines := func(r *io.PipeReader, stream StdStream) {
+		defer wg.Done()
+
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(nil, MaxLogLineSize)
+
+		for scanner.Scan() {
+			line := parseLogLine(scanner.Text())
+			line.Stream = stream
+
+			mutex.Lock()
+			if !stopped && !f(line) {
+				stop()
+			}
+			mutex.Unlock()
+		}
+
+		if err := scanner.Err(); err != nil {
+			// Unblock the pending writes of the docker client.
+			r.CloseWithError(err)
+
+			mutex.Lock()
+			if readErr == nil {
+				readErr = err
+			}
+			mutex.Unlock()
+		}
+	}
+
+	wg.Add(2)
+	go readLines(stdoutR, StdStreamOutput)
+	go readLines(stderrR, StdStreamError)
+
+	// Obtain the logs. This blocks until all logs are written.
+	err := Client.Logs(logsOpts)
+
+	// Close the writers to end the line readers.
+	stdoutW.Close()
+	stderrW.Close()
+	wg.Wait()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	// The error is expected if the stream was stopped.
+	if stopped {
+		return nil
+	} else if readErr != nil {
+		return fmt.Errorf("failed to read container '%s' logs: %v", containerID, readErr)
+	} else if err != nil {
+		return fmt.Errorf("failed to stream container '%s' logs: %v", containerID, err)
+	}
+
+	return nil
+}
+
+// ContainerStats is a single resource usage sample of a container.
+type ContainerStats struct {
+	CPUUsage       uint64 // Total CPU time of the container in nanoseconds.
+	SystemCPUUsage uint64 // Total CPU time of the host in nanoseconds.
+	NumCPUs        int
+
+	MemoryUsage uint64
+	MemoryLimit uint64
+
+	NetworkRx uint64 // Received bytes since the container started.
+	NetworkTx uint64 // Transmitted bytes since the container started.
+}
+
+// Stats obtains a single resource usage sample of the container.
+func Stats(containerID string) (*ContainerStats, error) {
+	statsChan := make(chan *docker.Stats, 1)
+	errChan := make(chan error, 1)
+
+	// Request a single sample. The channel is closed by the client.
+	go func() {
+		errChan <- Client.Stats(docker.StatsOptions{
+			ID:      containerID,
+			Stats:   statsChan,
+			Stream:  false,
+			Timeout: statsTimeout,
+		})
+	}()
+
+	var stats *docker.Stats
+	for st := range statsChan {
+		stats = st
+	}
+
+	if err := <-errChan; err != nil {
+		return nil, fmt.Errorf("failed to obtain container '%s' stats: %v", containerID, err)
+	} else if stats == nil {
+		return nil, fmt.Errorf("failed to obtain container '%s' stats: no sample received", containerID)
+	}
+
+	s := &ContainerStats{
+		CPUUsage:       stats.CPUStats.CPUUsage.TotalUsage,
+		SystemCPUUsage: stats.CPUStats.SystemCPUUsage,
+		NumCPUs:        len(stats.CPUStats.CPUUsage.PercpuUsage),
+		MemoryUsage:    stats.MemoryStats.Usage,
+		MemoryLimit:    stats.MemoryStats.Limit,
+	}
+
+	// Sum the traffic of all container networks.
+	for _, n := range stats.Networks {
+		s.NetworkRx += n.RxBytes
+		s.NetworkTx += n.TxBytes
+	}
+
+	return s, nil
+}
+
+// Exec runs a command in the running container and returns its exit code
+// and the combined output. An error is returned if the timeout is reached.
+// The attached streams of the command are closed on timeout. Docker has no
+// API to kill the exec process, but it fails as soon as it writes output.
+func Exec(containerID string, cmd []string, timeout time.Duration) (int, string, error) {
+	// Create the exec instance.
+	exec, err := Client.CreateExec(docker.CreateExecOptions{
+		Container:    containerID,
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create exec instance: %v", err)
+	}
+
+	// Start the command.
+	var output bytes.Buffer
+	cw, err := Client.StartExecNonBlocking(exec.ID, docker.StartExecOptions{
+		OutputStream: &output,
+		ErrorStream:  &output,
+	})
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to run command: %v", err)
+	}
+
+	// Wait for the command to exit.
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- cw.Wait()
+	}()
+
+	select {
+	case err = <-errChan:
+		if err != nil {
+			return 0, "", fmt.Errorf("failed to run command: %v", err)
+		}
+	case <-time.After(timeout):
+		// Close the attached connection and wait for the goroutine to exit.
+		cw.Close()
+		<-errChan
+		return 0, "", fmt.Errorf("command timeout reached after %v", timeout)
+	}
+
+	// Obtain the exit code.
+	inspect, err := Client.InspectExec(exec.ID)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to inspect exec instance: %v", err)
+	}
+
+	return inspect.ExitCode, output.String(), nil
+}
+
+// PauseContainer suspends all processes of the running container.
+func PauseContainer(id string) error {
+	return Client.PauseContainer(id)
+}
+
+// UnpauseContainer resumes all processes of the paused container.
+func UnpauseContainer(id string) error {
+	return Client.UnpauseContainer(id)
+}
+
+// CreateNetwork creates a user-defined bridge network if not present.
+func CreateNetwork(name string) error {
+	// Check if the network already exists.
+	n, err := getNetworkByName(name)
+	if err != nil {
+		return err
+	} else if n != nil {
+		return nil
+	}
+
+	// Create the network.
+	_, err = Client.CreateNetwork(docker.CreateNetworkOptions{
+		Name:           name,
+		Driver:         "bridge",
+		CheckDuplicate: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create network '%s': %v", name, err)
+	}
+
+	return nil
+}
+
+// RemoveNetwork removes the network if present.
+// Networks with attached containers are not removed.
+func RemoveNetwork(name string) error {
+	// Obtain the network.
+	n, err := getNetworkByName(name)
+	if err != nil {
+		return err
+	} else if n == nil || len(n.Containers) > 0 {
+		return nil
+	}
+
+	// Remove the network.
+	err = Client.RemoveNetwork(n.ID)
+	if err != nil {
+		return fmt.Errorf("failed to remove network '%s': %v", name, err)
+	}
+
+	return nil
+}
+
+// ConnectNetwork connects the container to the network.
+// The container is reachable by the DNS aliases within the network.
+func ConnectNetwork(name string, containerID string, aliases ...string) error {
+	err := Client.ConnectNetwork(name, docker.NetworkConnectionOptions{
+		Container: containerID,
+		EndpointConfig: &docker.EndpointConfig{
+			Aliases: aliases,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect container '%s' to network '%s': %v", containerID, name, err)
+	}
+
+	return nil
+}
+
+// ConnectDaemon connects the container of this daemon to the network.
+// Nothing is done if the daemon is not running within a container
+// or if the container is already connected.
+func ConnectDaemon(name string) error {
+	if len(daemonContainerID) == 0 {
+		return nil
+	}
+
+	// Obtain the network.
+	n, err := getNetworkByName(name)
+	if err != nil {
+		return err
+	} else if n == nil {
+		return fmt.Errorf("network '%s' does not exist!", name)
+	} else if _, ok := n.Containers[daemonContainerID]; ok {
+		return nil
+	}
+
+	return ConnectNetwork(name, daemonContainerID)
+}
+
+// DisconnectDaemon disconnects the container of this daemon from the network if connected.
+func DisconnectDaemon(name string) error {
+	if len(daemonContainerID) == 0 {
+		return nil
+	}
+
+	// Obtain the network.
+	n, err := getNetworkByName(name)
+	if err != nil {
+		return err
+	} else if n == nil {
+		return nil
+	} else if _, ok := n.Containers[daemonContainerID]; !ok {
+		return nil
+	}
+
+	err = Client.DisconnectNetwork(n.ID, docker.NetworkConnectionOptions{
+		Container: daemonContainerID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to disconnect daemon container from network '%s': %v", name, err)
+	}
+
+	return nil
+}
+
 // Build a docker image from a local directory.
 func Build(imageName, tag, dir string) error {
 	if len(imageName) == 0 || len(tag) == 0 || len(dir) == 0 {
 		return fmt.Errorf("build docker image: invalid arguments!")
 	}
 
+	// Build the image with the temporary build tag.
+	err := buildImage(imageName, dir)
+	if err != nil {
+		return err
+	}
+
+	// Remove the build image on defer.
+	defer removeBuildImage(imageName)
+
+	// Replace the current image with the build image.
+	return replaceImage(imageName, imageBuildTag, tag, imageOldTag)
+}
+
+// UpdateImageTag returns the tag of images, which are tested by a safe update
+// of the app. The tag is unique for each app and original image tag.
+func UpdateImageTag(appName, tag string) string {
+	return appImageTag(imageUpdateTag, appName, tag)
+}
+
+// BuildUpdateImage builds a docker image from a local directory and tags it
+// with the update tag of the app. The current image is not touched.
+func BuildUpdateImage(appName, imageName, tag, dir string) error {
+	if len(appName) == 0 || len(imageName) == 0 || len(tag) == 0 || len(dir) == 0 {
+		return fmt.Errorf("build docker image: invalid arguments!")
+	}
+
+	// Build the image with the temporary build tag.
+	err := buildImage(imageName, dir)
+	if err != nil {
+		return err
+	}
+
+	// Remove the build image on defer.
+	defer removeBuildImage(imageName)
+
+	return tagImage(imageName+":"+imageBuildTag, imageName, UpdateImageTag(appName, tag))
+}
+
+// PullUpdateImage pulls the docker image and tags it with the update tag of the app.
+// The current image keeps its tag.
+func PullUpdateImage(appName, imageName, tag string) error {
+	image := imageName + ":" + tag
+
+	// Remember the current image if present.
+	current, err := Client.InspectImage(image)
+	if err != nil {
+		current = nil
+	}
+
+	// Pull the image.
+	err = PullImage(imageName, tag)
+	if err != nil {
+		return fmt.Errorf("failed to pull docker image '%s': %v", image, err)
+	}
+
+	// Tag the pulled image with the update tag.
+	err = tagImage(image, imageName, UpdateImageTag(appName, tag))
+	if err != nil {
+		return err
+	}
+
+	// Restore the tag of the current image.
+	if current != nil {
+		return tagImage(current.ID, imageName, tag)
+	}
+
+	return nil
+}
+
+// ApplyUpdateImage replaces the current image with the update image of the app.
+// The current image is kept with the old tag of the app.
+func ApplyUpdateImage(appName, imageName, tag string) error {
+	updateTag := UpdateImageTag(appName, tag)
+
+	err := replaceImage(imageName, updateTag, tag, appImageTag(imageOldTag, appName, tag))
+	if err != nil {
+		return err
+	}
+
+	return removeImage(imageName + ":" + updateTag)
+}
+
+// RemoveUpdateImage removes the update image of the app if present.
+func RemoveUpdateImage(appName, imageName, tag string) error {
+	return removeImage(imageName + ":" + UpdateImageTag(appName, tag))
+}
+
+// RestoreOldImage replaces the current image with the old image of the app,
+// which was replaced by the last update.
+// Nothing is done if no old image exists.
+func RestoreOldImage(appName, imageName, tag string) error {
+	oldImage := imageName + ":" + appImageTag(imageOldTag, appName, tag)
+	_, err := Client.InspectImage(oldImage)
+	if err != nil {
+		return nil
+	}
+
+	return tagImage(oldImage, imageName, tag)
+}
+
+// OnEvent adds the function to the events map and returns its unique ID.
+// Use this ID to remove the event again.
+func OnEvent(f func(*docker.APIEvents)) int64 {
+	// Lock the mutex.
+	eventFuncsMutex.Lock()
+	defer eventFuncsMutex.Unlock()
+
+	// Increment the counter.
+	eventFuncsCounter++
+
+	// Add the event function to the map.
+	eventFuncs[eventFuncsCounter] = f
+
+	return eventFuncsCounter
+}
+
+// OffEvent removes the event function with the specific ID.
+func OffEvent(id int64) {
+	// Lock the mutex.
+	eventFuncsMutex.Lock()
+	defer eventFuncsMutex.Unlock()
+
+	// Remove the event again.
+	delete(eventFuncs, id)
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// parseLogLine splits the docker timestamp from the log line.
+func parseLogLine(l string) LogLine {
+	pos := strings.Index(l, " ")
+	if pos < 0 {
+		return LogLine{Message: l}
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, l[:pos])
+	if err != nil {
+		return LogLine{Message: l}
+	}
+
+	return LogLine{
+		Timestamp: t,
+		Message:   l[pos+1:],
+	}
+}
+
+// buildImage builds a docker image from a local directory
+// and tags it with the build tag.
+func buildImage(imageName, dir string) error {
 	// Create a buffer to write our archive to.
 	buf := bytes.NewBuffer(nil)
 
@@ -361,28 +936,16 @@ func Build(imageName, tag, dir string) error {
 	}
 
 	// Create the build image name with tag.
-	buildImage := imageName + ":" + imageBuildTag
-
-	// Function to remove the build image if present.
-	removeBuildImage := func() error {
-		// Check if a previous build image exists.
-		// Remove it if present.
-		_, err := Client.InspectImage(buildImage)
-		if err == nil {
-			opts := docker.RemoveImageOptions{
-				Force: true,
-			}
+	image := imageName + ":" + imageBuildTag
 
-			err = Client.RemoveImageExtended(buildImage, opts)
-			if err != nil {
-				return fmt.Errorf("failed to remove previous build image '%s': %v", buildImage, err)
-			}
-		}
-		return nil
+	// Remove the build image if present.
+	err = removeImage(image)
+	if err != nil {
+		return err
 	}
 
-	// Remove the build image if present.
-	err = removeBuildImage()
+	// Obtain the registry credentials to pull the base images.
+	auths, err := registryAuths()
 	if err != nil {
 		return err
 	}
@@ -392,13 +955,14 @@ func Build(imageName, tag, dir string) error {
 
 	// Create the build options.
 	opts := docker.BuildImageOptions{
-		Name:                buildImage,
+		Name:                image,
 		NoCache:             true,
 		Pull:                true,
 		RmTmpContainer:      true,
 		ForceRmTmpContainer: true,
 		InputStream:         buf,
 		OutputStream:        outputbuf,
+		AuthConfigs:         auths,
 	}
 
 	// Build the image.
@@ -406,90 +970,136 @@ func Build(imageName, tag, dir string) error {
 		return fmt.Errorf("%v\nbuild output:\n%s", err, outputbuf.String())
 	}
 
-	// Remove the build image on defer.
-	// The error is not important.
-	defer func() {
-		err := removeBuildImage()
-		if err != nil {
-			log.Errorf("failed to remove temporary build image '%s'!", buildImage)
-		}
-	}()
+	return nil
+}
 
-	// Check if the old image exists.
-	// Remove it if present.
-	oldImage := imageName + ":" + imageOldTag
-	_, err = Client.InspectImage(oldImage)
-	if err == nil {
-		opts := docker.RemoveImageOptions{
-			Force: true,
-		}
+// removeBuildImage removes the temporary build image.
+// The error is not important.
+func removeBuildImage(imageName string) {
+	buildImage := imageName + ":" + imageBuildTag
+	if err := removeImage(buildImage); err != nil {
+		log.Errorf("failed to remove temporary build image '%s'!", buildImage)
+	}
+}
 
-		err = Client.RemoveImageExtended(oldImage, opts)
-		if err != nil {
-			return fmt.Errorf("failed to remove old image '%s': %v", oldImage, err)
-		}
+// removeImage removes the image if present.
+func removeImage(image string) error {
+	// Check if the image exists.
+	_, err := Client.InspectImage(image)
+	if err != nil {
+		return nil
 	}
 
-	// Tag the current image to the old image tag if it exists.
-	image := imageName + ":" + tag
-	_, err = Client.InspectImage(image)
-	if err == nil {
-		opts := docker.TagImageOptions{
-			Repo:  imageName,
-			Tag:   imageOldTag,
-			Force: true,
-		}
+	opts := docker.RemoveImageOptions{
+		Force: true,
+	}
 
-		err = Client.TagImage(image, opts)
-		if err != nil {
-			return fmt.Errorf("failed to tag current image '%s': %v", image, err)
-		}
+	err = Client.RemoveImageExtended(image, opts)
+	if err != nil {
+		return fmt.Errorf("failed to remove image '%s': %v", image, err)
 	}
 
-	// Tag the build image to the new current image.
-	optsT := docker.TagImageOptions{
-		Repo:  imageName,
+	return nil
+}
+
+// tagImage tags the image with the repository and tag.
+func tagImage(image, repo, tag string) error {
+	opts := docker.TagImageOptions{
+		Repo:  repo,
 		Tag:   tag,
 		Force: true,
 	}
 
-	err = Client.TagImage(buildImage, optsT)
+	err := Client.TagImage(image, opts)
 	if err != nil {
-		return fmt.Errorf("failed to tag build image '%s': %v", buildImage, err)
+		return fmt.Errorf("failed to tag image '%s' as '%s:%s': %v", image, repo, tag, err)
 	}
 
 	return nil
 }
 
-// OnEvent adds the function to the events map and returns its unique ID.
-// Use this ID to remove the event again.
-func OnEvent(f func(*docker.APIEvents)) int64 {
-	// Lock the mutex.
-	eventFuncsMutex.Lock()
-	defer eventFuncsMutex.Unlock()
+// replaceImage tags the source image as the current image.
+// The previous current image is kept with the old tag.
+func replaceImage(imageName, srcTag, tag, oldTag string) error {
+	// Remove the old image if present.
+	err := removeImage(imageName + ":" + oldTag)
+	if err != nil {
+		return err
+	}
 
-	// Increment the counter.
-	eventFuncsCounter++
+	// Tag the current image to the old image tag if it exists.
+	image := imageName + ":" + tag
+	_, err = Client.InspectImage(image)
+	if err == nil {
+		err = tagImage(image, imageName, oldTag)
+		if err != nil {
+			return err
+		}
+	}
 
-	// Add the event function to the map.
-	eventFuncs[eventFuncsCounter] = f
+	// Tag the source image to the new current image.
+	return tagImage(imageName+":"+srcTag, imageName, tag)
+}
 
-	return eventFuncsCounter
+// appImageTag returns a valid docker image tag, which is unique for the
+// prefix, app name and original tag. The hash suffix keeps the tag unique,
+// even if invalid characters are replaced or the tag is shortened.
+func appImageTag(prefix, appName, tag string) string {
+	h := sha256.Sum256([]byte(appName + "\x00" + tag))
+	suffix := "-" + hex.EncodeToString(h[:4])
+
+	t := prefix + "-" + invalidImageTagChars.ReplaceAllString(appName+"-"+tag, "_")
+	if len(t)+len(suffix) > maxImageTagLength {
+		t = t[:maxImageTagLength-len(suffix)]
+	}
+
+	return t + suffix
 }
 
-// OffEvent removes the event function with the specific ID.
-func OffEvent(id int64) {
-	// Lock the mutex.
-	eventFuncsMutex.Lock()
-	defer eventFuncsMutex.Unlock()
+// getDaemonContainerID returns the ID of the container running this daemon.
+// Docker sets the container hostname to the short container ID.
+// An empty ID is returned if the daemon is not running within a container
+// or if the container shares the host network.
+func getDaemonContainerID() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
 
-	// Remove the event again.
-	delete(eventFuncs, id)
+	c, err := Client.InspectContainer(hostname)
+	if err != nil {
+		if _, ok := err.(*docker.NoSuchContainer); ok {
+			return "", nil
+		}
+		return "", err
+	} else if !strings.HasPrefix(c.ID, hostname) ||
+		(c.HostConfig != nil && c.HostConfig.NetworkMode == "host") {
+		return "", nil
+	}
+
+	return c.ID, nil
 }
 
-//###############//
-//### Private ###//
-//###############//
+// getNetworkByName obtains a network by its name.
+// If no network was found, no error will be returned. Just a nil pointer.
+func getNetworkByName(name string) (*docker.Network, error) {
+	// Get all networks.
+	networks, err := Client.ListNetworks()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, n := range networks {
+		if n.Name != name {
+			continue
+		}
+
+		// Inspect the network to obtain the attached containers.
+		return Client.NetworkInfo(n.ID)
+	}
+
+	return nil, nil
+}
 
 func startEventListener() error {
 	// Create a docker event listener.
@@ -0,0 +1,374 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package docker
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/desertbit/turtle/daemon/config"
+	"github.com/desertbit/turtle/utils"
+
+	"github.com/BurntSushi/toml"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+const (
+	// DockerHubRegistry is the registry host of images without a registry host.
+	DockerHubRegistry = "docker.io"
+
+	dockerHubServerAddress = "https://index.docker.io/v1/"
+	registryKeyLength      = 32 // AES-256
+)
+
+var (
+	registriesMutex sync.Mutex
+)
+
+//#####################//
+//### Registry type ###//
+//#####################//
+
+// Registry contains the credentials of a docker registry.
+type Registry struct {
+	Host     string
+	Username string
+	Password string
+	Email    string
+}
+
+// serverAddress returns the server address passed to the docker daemon.
+func (r *Registry) serverAddress() string {
+	if r.Host == DockerHubRegistry {
+		return dockerHubServerAddress
+	}
+	return r.Host
+}
+
+// authConfiguration returns the docker authentication configuration.
+func (r *Registry) authConfiguration() docker.AuthConfiguration {
+	return docker.AuthConfiguration{
+		Username:      r.Username,
+		Password:      r.Password,
+		Email:         r.Email,
+		ServerAddress: r.serverAddress(),
+	}
+}
+
+type registries struct {
+	Registries []*Registry `toml:"Registry"`
+}
+
+//##############//
+//### Public ###//
+//##############//
+
+// AddRegistry stores the credentials of a docker registry.
+// Existing credentials of the registry host are replaced.
+func AddRegistry(r Registry) error {
+	r.Host = NormalizeRegistryHost(r.Host)
+	if len(r.Host) == 0 || strings.ContainsAny(r.Host, " /") {
+		return fmt.Errorf("invalid registry host '%s'!", r.Host)
+	} else if len(r.Username) == 0 {
+		return fmt.Errorf("the registry username is missing!")
+	}
+
+	// Lock the mutex.
+	registriesMutex.Lock()
+	defer registriesMutex.Unlock()
+
+	regs, err := loadRegistries()
+	if err != nil {
+		return err
+	}
+
+	// Replace or add the credentials.
+	replaced := false
+	for i, rr := range regs.Registries {
+		if rr.Host == r.Host {
+			regs.Registries[i] = &r
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		regs.Registries = append(regs.Registries, &r)
+	}
+
+	return saveRegistries(regs)
+}
+
+// RemoveRegistry removes the credentials of a docker registry.
+func RemoveRegistry(host string) error {
+	host = NormalizeRegistryHost(host)
+
+	// Lock the mutex.
+	registriesMutex.Lock()
+	defer registriesMutex.Unlock()
+
+	regs, err := loadRegistries()
+	if err != nil {
+		return err
+	}
+
+	for i, r := range regs.Registries {
+		if r.Host == host {
+			regs.Registries = append(regs.Registries[:i], regs.Registries[i+1:]...)
+			return saveRegistries(regs)
+		}
+	}
+
+	return fmt.Errorf("no credentials for registry '%s' found!", host)
+}
+
+// Registries returns the stored registries. The passwords are not included.
+func Registries() ([]Registry, error) {
+	// Lock the mutex.
+	registriesMutex.Lock()
+	defer registriesMutex.Unlock()
+
+	regs, err := loadRegistries()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Registry, len(regs.Registries))
+	for i, r := range regs.Registries {
+		list[i] = *r
+		list[i].Password = ""
+	}
+
+	return list, nil
+}
+
+// RegistryHost returns the registry host of the image name.
+// Images without a registry host are pulled from the Docker Hub.
+func RegistryHost(imageName string) string {
+	i := strings.Index(imageName, "/")
+	if i < 0 {
+		return DockerHubRegistry
+	}
+
+	// The first name component is a registry host if it contains
+	// a domain or port separator or if it is localhost.
+	host := imageName[:i]
+	if !strings.ContainsAny(host, ".:") && host != "localhost" {
+		return DockerHubRegistry
+	}
+
+	return NormalizeRegistryHost(host)
+}
+
+// NormalizeRegistryHost strips the URL scheme and path from the registry
+// host and maps the Docker Hub addresses to a single host.
+func NormalizeRegistryHost(host string) string {
+	host = strings.TrimSpace(strings.ToLower(host))
+	host = strings.TrimPrefix(host, "https://")
+	host = strings.TrimPrefix(host, "http://")
+	if i := strings.Index(host, "/"); i >= 0 {
+		host = host[:i]
+	}
+
+	switch host {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return DockerHubRegistry
+	}
+
+	return host
+}
+
+// PullImage pulls the docker image with the credentials of its registry.
+func PullImage(imageName, tag string) error {
+	auth, err := registryAuth(imageName)
+	if err != nil {
+		return err
+	}
+
+	return Client.PullImage(docker.PullImageOptions{
+		Repository: imageName,
+		Tag:        tag,
+	}, auth)
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// registryAuth returns the credentials of the image registry.
+// Empty credentials are returned if none are stored.
+func registryAuth(imageName string) (docker.AuthConfiguration, error) {
+	host := RegistryHost(imageName)
+
+	// Lock the mutex.
+	registriesMutex.Lock()
+	defer registriesMutex.Unlock()
+
+	regs, err := loadRegistries()
+	if err != nil {
+		return docker.AuthConfiguration{}, err
+	}
+
+	for _, r := range regs.Registries {
+		if r.Host == host {
+			return r.authConfiguration(), nil
+		}
+	}
+
+	return docker.AuthConfiguration{}, nil
+}
+
+// registryAuths returns the credentials of all stored registries.
+// They are passed to image builds to pull the base images.
+func registryAuths() (docker.AuthConfigurations, error) {
+	// Lock the mutex.
+	registriesMutex.Lock()
+	defer registriesMutex.Unlock()
+
+	regs, err := loadRegistries()
+	if err != nil {
+		return docker.AuthConfigurations{}, err
+	}
+
+	auths := docker.AuthConfigurations{
+		Configs: make(map[string]docker.AuthConfiguration),
+	}
+	for _, r := range regs.Registries {
+		auths.Configs[r.serverAddress()] = r.authConfiguration()
+	}
+
+	return auths, nil
+}
+
+// loadRegistries loads and decrypts the registries file.
+func loadRegistries() (*registries, error) {
+	var regs registries
+
+	path := config.Config.RegistriesFilePath()
+
+	// Skip if it does not exists.
+	e, err := utils.Exists(path)
+	if err != nil {
+		return nil, err
+	} else if !e {
+		return &regs, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load registries file '%s': %v", path, err)
+	}
+
+	// Decrypt the file content.
+	gcm, err := registriesCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	nonceSize := gcm.NonceSize()
+	if len(data) < nonceSize {
+		return nil, fmt.Errorf("failed to decrypt registries file '%s': invalid size", path)
+	}
+
+	data, err = gcm.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt registries file '%s': %v", path, err)
+	}
+
+	// Decode the registries.
+	_, err = toml.Decode(string(data), &regs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load registries file '%s': %v", path, err)
+	}
+
+	return &regs, nil
+}
+
+// saveRegistries encrypts and saves the registries to the registries file.
+func saveRegistries(regs *registries) error {
+	// Encode the registries value to TOML.
+	buf := new(bytes.Buffer)
+	err := toml.NewEncoder(buf).Encode(regs)
+	if err != nil {
+		return fmt.Errorf("failed to encode registries to toml: %v", err)
+	}
+
+	// Encrypt the data. The random nonce is prepended.
+	gcm, err := registriesCipher()
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to create random nonce: %v", err)
+	}
+
+	data := gcm.Seal(nonce, nonce, buf.Bytes(), nil)
+
+	// Write the result to the registries file.
+	err = ioutil.WriteFile(config.Config.RegistriesFilePath(), data, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to save registries file: %v", err)
+	}
+
+	return nil
+}
+
+// registriesCipher returns the AES-GCM cipher of the registries file.
+// A new random key is created if the key file does not exist.
+func registriesCipher() (cipher.AEAD, error) {
+	path := config.Config.RegistriesKeyFilePath()
+
+	e, err := utils.Exists(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var key []byte
+	if e {
+		key, err = ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load registries key file '%s': %v", path, err)
+		} else if len(key) != registryKeyLength {
+			return nil, fmt.Errorf("invalid registries key file '%s': invalid key length", path)
+		}
+	} else {
+		key = make([]byte, registryKeyLength)
+		if _, err = rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to create random registries key: %v", err)
+		}
+
+		if err = ioutil.WriteFile(path, key, 0600); err != nil {
+			return nil, fmt.Errorf("failed to save registries key file: %v", err)
+		}
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,339 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/desertbit/turtle/api"
+	"github.com/desertbit/turtle/daemon/apps"
+	"github.com/desertbit/turtle/utils"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	prometheusContentType = "text/plain; version=0.0.4"
+)
+
+var (
+	// The request statistics. The key is the request type.
+	requestStats      = make(map[string]*requestStat)
+	requestStatsMutex sync.Mutex
+
+	// The btrfs balance job results.
+	balanceStats      balanceStat
+	balanceStatsMutex sync.Mutex
+
+	// All app task names which are exported.
+	prometheusTasks = []string{"none", "clone-source", "run", "update", "backup"}
+)
+
+type requestStat struct {
+	Success     int64
+	Failed      int64
+	DurationSum float64 // In seconds
+}
+
+type balanceStat struct {
+	Success      int64
+	Failed       int64
+	LastRun      time.Time
+	LastDuration time.Duration
+	LastFailed   bool
+}
+
+func init() {
+	// Set the prometheus HTTP handler.
+	http.HandleFunc("/metrics", handlePrometheus)
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// recordRequest adds a handled request to the request statistics.
+// Requests with an empty type are recorded as invalid.
+func recordRequest(requestType api.Type, failed bool, duration time.Duration) {
+	t := "invalid"
+	if len(requestType) > 0 {
+		t = string(requestType)
+	}
+
+	// Lock the mutex.
+	requestStatsMutex.Lock()
+	defer requestStatsMutex.Unlock()
+
+	s, ok := requestStats[t]
+	if !ok {
+		s = &requestStat{}
+		requestStats[t] = s
+	}
+
+	if failed {
+		s.Failed++
+	} else {
+		s.Success++
+	}
+	s.DurationSum += duration.Seconds()
+}
+
+// recordBalance adds a btrfs balance job result to the statistics.
+func recordBalance(err error, duration time.Duration) {
+	// Lock the mutex.
+	balanceStatsMutex.Lock()
+	defer balanceStatsMutex.Unlock()
+
+	if err != nil {
+		balanceStats.Failed++
+	} else {
+		balanceStats.Success++
+	}
+
+	balanceStats.LastRun = time.Now()
+	balanceStats.LastDuration = duration
+	balanceStats.LastFailed = err != nil
+}
+
+// handlePrometheus exports the daemon metrics in the prometheus text format.
+// Remote scrapers authenticate with a bearer API token or a client certificate.
+func handlePrometheus(rw http.ResponseWriter, req *http.Request) {
+	// Lock the mutex in read mode.
+	requestRWLock.RLock()
+	defer requestRWLock.RUnlock()
+
+	// Get the remote address from the client.
+	remoteAddr, _ := utils.RemoteAddress(req)
+
+	// Authenticate the request with the bearer token.
+	request := api.NewRequest(api.TypePrometheus, nil)
+	request.Token = strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+
+	user, err := authenticate(req, request)
+	if err != nil {
+		log.Warningf("Metrics request from client '%s': %v", remoteAddr, err)
+		http.Error(rw, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	// Check the permissions of the user.
+	userAccess, err := getAccess(user)
+	if err == nil {
+		err = userAccess.check(request)
+	}
+	if err != nil {
+		log.Warningf("Metrics request from client '%s' (user '%s'): %v", remoteAddr, user, err)
+		http.Error(rw, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	w := &prometheusWriter{}
+
+	writeAppMetrics(w, userAccess)
+	writeRequestMetrics(w)
+	writeBalanceMetrics(w)
+
+	rw.Header().Set("Content-Type", prometheusContentType)
+	rw.Write(w.buf.Bytes())
+}
+
+// writeAppMetrics writes the task, restart, backup and resource usage metrics
+// of all apps the user can access.
+func writeAppMetrics(w *prometheusWriter, userAccess *access) {
+	w.describe("turtle_app_task", "gauge", "The current task of the app. 1 for the active task.")
+	w.describe("turtle_app_error", "gauge", "1 if the last app task failed.")
+	w.describe("turtle_app_restarts_total", "counter", "Automatic app restarts since the daemon started.")
+	w.describe("turtle_app_backups", "gauge", "Number of app backups.")
+	w.describe("turtle_app_last_backup_timestamp_seconds", "gauge", "Unix timestamp of the latest app backup.")
+	w.describe("turtle_app_last_backup_duration_seconds", "gauge", "Duration of the last app backup since the daemon started.")
+	w.describe("turtle_app_cpu_percent", "gauge", "Summed CPU usage of the app containers. 100 equals one CPU.")
+	w.describe("turtle_app_memory_usage_bytes", "gauge", "Memory usage of the app containers.")
+	w.describe("turtle_app_network_receive_bytes_per_second", "gauge", "Received bytes per second of the app containers.")
+	w.describe("turtle_app_network_transmit_bytes_per_second", "gauge", "Transmitted bytes per second of the app containers.")
+	w.describe("turtle_app_storage_bytes", "gauge", "Bytes referenced by the app subvolume.")
+	w.describe("turtle_app_backups_storage_bytes", "gauge", "Bytes only used by the app backups.")
+	w.describe("turtle_container_cpu_percent", "gauge", "CPU usage of the app container. 100 equals one CPU.")
+	w.describe("turtle_container_memory_usage_bytes", "gauge", "Memory usage of the app container.")
+	w.describe("turtle_container_network_receive_bytes_total", "counter", "Received bytes since the container started.")
+	w.describe("turtle_container_network_transmit_bytes_total", "counter", "Transmitted bytes since the container started.")
+
+	for _, a := range apps.Apps() {
+		name := a.Name()
+
+		// Skip apps the user is not allowed to access.
+		if !userAccess.isAppAllowed(name) {
+			continue
+		}
+
+		// Task state.
+		task := a.Task()
+		for _, t := range prometheusTasks {
+			w.value("turtle_app_task", boolValue(t == task), "app", name, "task", t)
+		}
+		w.value("turtle_app_error", boolValue(a.Error() != nil), "app", name)
+		w.value("turtle_app_restarts_total", float64(a.RestartCount()), "app", name)
+
+		// Backups.
+		backups, err := a.Backups()
+		if err != nil {
+			log.Warningf("metrics: failed to get backups of app '%s': %v", name, err)
+		} else {
+			var latest int64
+			for _, b := range backups {
+				unix, err := strconv.ParseInt(b, 10, 64)
+				if err == nil && unix > latest {
+					latest = unix
+				}
+			}
+
+			w.value("turtle_app_backups", float64(len(backups)), "app", name)
+			if latest > 0 {
+				w.value("turtle_app_last_backup_timestamp_seconds", float64(latest), "app", name)
+			}
+		}
+		if d := a.LastBackupDuration(); d > 0 {
+			w.value("turtle_app_last_backup_duration_seconds", d.Seconds(), "app", name)
+		}
+
+		// Resource usage.
+		history, containers, err := a.Metrics()
+		if err != nil {
+			log.Warningf("metrics: failed to get metrics of app '%s': %v", name, err)
+			continue
+		}
+
+		if len(history) > 0 {
+			s := history[len(history)-1]
+			w.value("turtle_app_cpu_percent", s.CPUPercent, "app", name)
+			w.value("turtle_app_memory_usage_bytes", float64(s.MemoryUsage), "app", name)
+			w.value("turtle_app_network_receive_bytes_per_second", s.NetworkRxRate, "app", name)
+			w.value("turtle_app_network_transmit_bytes_per_second", s.NetworkTxRate, "app", name)
+			w.value("turtle_app_storage_bytes", float64(s.Storage), "app", name)
+			w.value("turtle_app_backups_storage_bytes", float64(s.BackupsStorage), "app", name)
+		}
+
+		for _, c := range containers {
+			w.value("turtle_container_cpu_percent", c.CPUPercent, "app", name, "container", c.Name)
+			w.value("turtle_container_memory_usage_bytes", float64(c.MemoryUsage), "app", name, "container", c.Name)
+			w.value("turtle_container_network_receive_bytes_total", float64(c.NetworkRx), "app", name, "container", c.Name)
+			w.value("turtle_container_network_transmit_bytes_total", float64(c.NetworkTx), "app", name, "container", c.Name)
+		}
+	}
+}
+
+// writeRequestMetrics writes the API request counts and latencies.
+func writeRequestMetrics(w *prometheusWriter) {
+	// Lock the mutex.
+	requestStatsMutex.Lock()
+	defer requestStatsMutex.Unlock()
+
+	w.describe("turtle_requests_total", "counter", "Handled API requests by type and status.")
+	w.describe("turtle_request_duration_seconds", "summary", "API request latencies by type.")
+
+	// Sort the types for a stable output.
+	types := make([]string, 0, len(requestStats))
+	for t := range requestStats {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	for _, t := range types {
+		s := requestStats[t]
+		w.value("turtle_requests_total", float64(s.Success), "type", t, "status", "success")
+		w.value("turtle_requests_total", float64(s.Failed), "type", t, "status", "error")
+		w.value("turtle_request_duration_seconds_sum", s.DurationSum, "type", t)
+		w.value("turtle_request_duration_seconds_count", float64(s.Success+s.Failed), "type", t)
+	}
+}
+
+// writeBalanceMetrics writes the btrfs balance job results.
+func writeBalanceMetrics(w *prometheusWriter) {
+	// Lock the mutex.
+	balanceStatsMutex.Lock()
+	defer balanceStatsMutex.Unlock()
+
+	w.describe("turtle_btrfs_balance_runs_total", "counter", "Btrfs balance job runs by result.")
+	w.value("turtle_btrfs_balance_runs_total", float64(balanceStats.Success), "result", "success")
+	w.value("turtle_btrfs_balance_runs_total", float64(balanceStats.Failed), "result", "error")
+
+	if balanceStats.LastRun.IsZero() {
+		return
+	}
+
+	w.describe("turtle_btrfs_balance_last_run_timestamp_seconds", "gauge", "Unix timestamp of the last btrfs balance job run.")
+	w.value("turtle_btrfs_balance_last_run_timestamp_seconds", float64(balanceStats.LastRun.Unix()))
+
+	w.describe("turtle_btrfs_balance_last_duration_seconds", "gauge", "Duration of the last btrfs balance job run.")
+	w.value("turtle_btrfs_balance_last_duration_seconds", balanceStats.LastDuration.Seconds())
+
+	w.describe("turtle_btrfs_balance_last_failed", "gauge", "1 if the last btrfs balance job run failed.")
+	w.value("turtle_btrfs_balance_last_failed", boolValue(balanceStats.LastFailed))
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+//##############################//
+//### Prometheus writer type ###//
+//##############################//
+
+// prometheusWriter writes metrics in the prometheus text exposition format.
+type prometheusWriter struct {
+	buf bytes.Buffer
+}
+
+// describe writes the help and type lines of a metric.
+func (w *prometheusWriter) describe(name, metricType, help string) {
+	fmt.Fprintf(&w.buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// value writes a metric sample. The labels are passed as name and value pairs.
+func (w *prometheusWriter) value(name string, v float64, labels ...string) {
+	w.buf.WriteString(name)
+
+	if len(labels) > 0 {
+		w.buf.WriteString("{")
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				w.buf.WriteString(",")
+			}
+			fmt.Fprintf(&w.buf, "%s=\"%s\"", labels[i], escapeLabelValue(labels[i+1]))
+		}
+		w.buf.WriteString("}")
+	}
+
+	w.buf.WriteString(" " + strconv.FormatFloat(v, 'g', -1, 64) + "\n")
+}
+
+// escapeLabelValue escapes backslashes, double quotes and newlines.
+func escapeLabelValue(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	return strings.Replace(s, "\n", `\n`, -1)
+}
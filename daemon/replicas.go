@@ -0,0 +1,223 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/desertbit/turtle/daemon/btrfs"
+	"github.com/desertbit/turtle/daemon/config"
+	"github.com/desertbit/turtle/utils"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//##################################//
+//### Replicas of remote daemons ###//
+//##################################//
+
+// replicaDirPath returns the directory of the app replicas received from the user.
+func replicaDirPath(user, app string) (string, error) {
+	// The names are used as directory names.
+	for _, name := range []string{user, app} {
+		if !isValidReplicaName(name) {
+			return "", fmt.Errorf("invalid replica name '%s'!", name)
+		}
+	}
+
+	return filepath.Join(config.Config.ReplicasDirPath(), user, app), nil
+}
+
+// receiveReplica receives the btrfs send stream of an app backup replicated by the user.
+func receiveReplica(user, app, timestamp, parent string, r io.Reader) error {
+	dir, err := replicaDirPath(user, app)
+	if err != nil {
+		return err
+	}
+
+	if err = receiveReplicaDir(dir, timestamp, parent, r); err != nil {
+		return err
+	}
+
+	log.Infof("received replica '%s' of app '%s' from user '%s'", timestamp, app, user)
+
+	return nil
+}
+
+// listReplicas returns the sorted replica timestamps of the app replicated by the user.
+func listReplicas(user, app string) ([]string, error) {
+	dir, err := replicaDirPath(user, app)
+	if err != nil {
+		return nil, err
+	}
+
+	return listReplicaDir(dir)
+}
+
+// sendReplica writes the btrfs send stream of the replica to w.
+func sendReplica(user, app, timestamp string, w io.Writer) error {
+	dir, err := replicaDirPath(user, app)
+	if err != nil {
+		return err
+	}
+
+	return sendReplicaDir(dir, timestamp, w)
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// receiveReplicaDir receives the replica stream in the replica directory.
+// The parent replica of incremental streams has to exist.
+// Expired replicas are removed afterwards.
+func receiveReplicaDir(dir, timestamp, parent string, r io.Reader) error {
+	if !isValidTimestamp(timestamp) {
+		return fmt.Errorf("invalid replica timestamp '%s'!", timestamp)
+	} else if len(parent) > 0 && (!isValidTimestamp(parent) || !btrfs.IsSubvolume(dir+"/"+parent)) {
+		return fmt.Errorf("no parent replica '%s' found!", parent)
+	}
+
+	// Create the replica directory if not present.
+	if err := utils.MkDirIfNotExists(dir, 0700); err != nil {
+		return err
+	}
+
+	if e, err := utils.Exists(dir + "/" + timestamp); err != nil {
+		return err
+	} else if e {
+		return fmt.Errorf("replica '%s' already exists!", timestamp)
+	}
+
+	// Receive the replica.
+	if err := btrfs.Receive(dir, timestamp, r); err != nil {
+		return err
+	}
+
+	// The received replica is kept even if the cleanup fails.
+	if err := pruneReplicaDir(dir); err != nil {
+		log.Errorf("failed to remove expired replicas: %v", err)
+	}
+
+	return nil
+}
+
+// sendReplicaDir writes the full btrfs send stream of the replica to w.
+func sendReplicaDir(dir, timestamp string, w io.Writer) error {
+	path := dir + "/" + timestamp
+
+	// Check if the replica exists.
+	if !isValidTimestamp(timestamp) || !btrfs.IsSubvolume(path) {
+		return fmt.Errorf("no replica '%s' found!", timestamp)
+	}
+
+	return btrfs.Send(path, "", w)
+}
+
+// listReplicaDir returns the sorted replica timestamps in the directory.
+func listReplicaDir(dir string) ([]string, error) {
+	// If the directory does not exists, then return nil.
+	e, err := utils.Exists(dir)
+	if err != nil {
+		return nil, err
+	} else if !e {
+		return nil, nil
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var replicas []string
+	for _, f := range files {
+		if f.IsDir() && isValidTimestamp(f.Name()) {
+			replicas = append(replicas, f.Name())
+		}
+	}
+
+	sort.Sort(byTimestamp(replicas))
+
+	return replicas, nil
+}
+
+// pruneReplicaDir removes the replicas expired by the default retention policy.
+// The newest replica is always kept, because it is the parent
+// of the next incremental stream.
+func pruneReplicaDir(dir string) error {
+	replicas, err := listReplicaDir(dir)
+	if err != nil || len(replicas) == 0 {
+		return err
+	}
+
+	timestamps := make([]int64, len(replicas))
+	names := make(map[int64]string)
+	for i, r := range replicas {
+		timestamps[i], _ = strconv.ParseInt(r, 10, 64)
+		names[timestamps[i]] = r
+	}
+
+	// Obtain the replicas expired by the retention policy.
+	// The replicas are sorted and the newest replica is protected.
+	protected := map[int64]bool{
+		timestamps[len(timestamps)-1]: true,
+	}
+	expired := config.Config.DefaultRetention().Expired(timestamps, time.Now(), protected)
+
+	for _, u := range expired {
+		r := names[u]
+
+		log.Infof("Removing expired replica '%s'.", dir+"/"+r)
+
+		if err = btrfs.DeleteSubvolume(dir + "/" + r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isValidReplicaName checks if the name can be used as replica directory name.
+func isValidReplicaName(name string) bool {
+	return len(name) > 0 && !strings.ContainsRune(name, '/') && name != "." && name != ".."
+}
+
+// isValidTimestamp checks if the value is a valid unix timestamp.
+func isValidTimestamp(timestamp string) bool {
+	_, err := strconv.ParseInt(timestamp, 10, 64)
+	return err == nil
+}
+
+// byTimestamp implements sort.Interface to sort unix timestamps in increasing order.
+type byTimestamp []string
+
+func (s byTimestamp) Len() int      { return len(s) }
+func (s byTimestamp) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byTimestamp) Less(i, j int) bool {
+	a, _ := strconv.ParseInt(s[i], 10, 64)
+	b, _ := strconv.ParseInt(s[j], 10, 64)
+	return a < b
+}
@@ -0,0 +1,584 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/desertbit/turtle/api"
+	"github.com/desertbit/turtle/daemon/apps"
+	"github.com/desertbit/turtle/daemon/config"
+	"github.com/desertbit/turtle/utils"
+
+	"github.com/BurntSushi/toml"
+	log "github.com/Sirupsen/logrus"
+)
+
+var (
+	// This mutex is locked during a replication run.
+	replicationMutex sync.Mutex
+
+	// This mutex is locked while the replication state file is accessed.
+	replicationStateMutex sync.Mutex
+)
+
+//##############################//
+//### Replication state type ###//
+//##############################//
+
+type replicationState struct {
+	Target string                 // The replication target of the app states.
+	Apps   []*replicationAppState `toml:"App"`
+}
+
+type replicationAppState struct {
+	Name   string
+	Parent string // The last replicated backup.
+	Time   int64  // Unix timestamp of the last replication.
+	Error  string // The error of the last replication.
+}
+
+// app returns the state of the app. It is created if not present.
+func (s *replicationState) app(name string) *replicationAppState {
+	for _, a := range s.Apps {
+		if a.Name == name {
+			return a
+		}
+	}
+
+	a := &replicationAppState{Name: name}
+	s.Apps = append(s.Apps, a)
+
+	return a
+}
+
+//###############################//
+//### Replication target type ###//
+//###############################//
+
+// replicationTarget stores the replicated app backups.
+type replicationTarget interface {
+	// Replicas returns the sorted replica timestamps of the app.
+	Replicas(app string) ([]string, error)
+
+	// Receive stores the btrfs send stream of the app backup.
+	// The stream is incremental if the parent is set.
+	Receive(app, timestamp, parent string, r io.Reader) error
+
+	// Open returns the full btrfs send stream of the replica.
+	// The stream has to be closed by the caller.
+	Open(app, timestamp string) (io.ReadCloser, error)
+}
+
+// newReplicationTarget creates the configured replication target.
+func newReplicationTarget() (replicationTarget, error) {
+	if len(config.Config.ReplicationTarget) == 0 {
+		return nil, fmt.Errorf("no replication target configured!")
+	} else if config.Config.IsRemoteReplication() {
+		return newRemoteTarget()
+	}
+
+	return &localTarget{path: config.Config.ReplicationTarget}, nil
+}
+
+// localTarget stores the replicas in a local btrfs directory.
+type localTarget struct {
+	path string
+}
+
+func (t *localTarget) Replicas(app string) ([]string, error) {
+	return listReplicaDir(filepath.Join(t.path, app))
+}
+
+func (t *localTarget) Receive(app, timestamp, parent string, r io.Reader) error {
+	return receiveReplicaDir(filepath.Join(t.path, app), timestamp, parent, r)
+}
+
+func (t *localTarget) Open(app, timestamp string) (io.ReadCloser, error) {
+	dir := filepath.Join(t.path, app)
+
+	return pipeStream(func(w io.Writer) error {
+		return sendReplicaDir(dir, timestamp, w)
+	}), nil
+}
+
+// remoteTarget stores the replicas on a remote turtle daemon.
+type remoteTarget struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// newRemoteTarget creates the remote target with the configured credentials.
+func newRemoteTarget() (*remoteTarget, error) {
+	// Read the API token.
+	data, err := ioutil.ReadFile(config.Config.ReplicationTokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replication token file: %v", err)
+	}
+
+	t := &remoteTarget{
+		url:    config.Config.ReplicationTarget,
+		token:  strings.TrimSpace(string(data)),
+		client: &http.Client{},
+	}
+
+	// Verify the remote daemon with the custom CA if set.
+	if len(config.Config.ReplicationCAFile) > 0 {
+		pem, err := ioutil.ReadFile(config.Config.ReplicationCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read replication CA file: %v", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("failed to parse replication CA file '%s'", config.Config.ReplicationCAFile)
+		}
+
+		t.client.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		}
+	}
+
+	return t, nil
+}
+
+func (t *remoteTarget) Replicas(app string) ([]string, error) {
+	response, err := t.sendRequest(api.TypeReplicaList, api.RequestReplicaList{Name: app}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var res api.ResponseReplicaList
+	if err = response.MapTo(&res); err != nil {
+		return nil, err
+	}
+
+	return res.Replicas, nil
+}
+
+func (t *remoteTarget) Receive(app, timestamp, parent string, r io.Reader) error {
+	request := api.RequestReplicaReceive{
+		Name:   app,
+		Unix:   timestamp,
+		Parent: parent,
+	}
+
+	_, err := t.sendRequest(api.TypeReplicaReceive, request, r)
+	return err
+}
+
+func (t *remoteTarget) Open(app, timestamp string) (io.ReadCloser, error) {
+	request := api.RequestReplicaSend{
+		Name: app,
+		Unix: timestamp,
+	}
+
+	httpResponse, err := t.post(api.TypeReplicaSend, request, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The daemon sends a normal response if the stream was not started.
+	if httpResponse.Header.Get("Content-Type") != api.ReplicaContentType {
+		defer httpResponse.Body.Close()
+
+		if _, err = readRemoteResponse(httpResponse); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("the replication target did not send the replica!")
+	}
+
+	return httpResponse.Body, nil
+}
+
+// sendRequest sends the request to the remote daemon and returns the response.
+// The upload data is sent after the request in the request body.
+func (t *remoteTarget) sendRequest(requestType api.Type, data interface{}, upload io.Reader) (*api.Response, error) {
+	httpResponse, err := t.post(requestType, data, upload)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+
+	return readRemoteResponse(httpResponse)
+}
+
+// post posts a new request to the remote daemon.
+func (t *remoteTarget) post(requestType api.Type, data interface{}, upload io.Reader) (*http.Response, error) {
+	request := api.NewRequest(requestType, data)
+	request.Token = t.token
+
+	json, err := request.ToJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader = bytes.NewReader(json)
+	if upload != nil {
+		body = io.MultiReader(body, upload)
+	}
+
+	req, err := http.NewRequest("POST", t.url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	httpResponse, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("replication target request failed: %v", err)
+	}
+
+	return httpResponse, nil
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// replicate replicates the backups of all apps to the replication target.
+func replicate() error {
+	var allErr string
+
+	for _, a := range apps.Apps() {
+		if _, err := replicateApp(a); err != nil {
+			allErr += err.Error() + "\n"
+		}
+	}
+
+	// Trim the all error messages string.
+	allErr = strings.TrimSpace(allErr)
+
+	// Return the error(s) if present.
+	if len(allErr) > 0 {
+		return fmt.Errorf(allErr)
+	}
+
+	return nil
+}
+
+// replicateApp replicates all backups of the app, which are newer than the
+// last replicated backup. Each backup is sent incrementally to its predecessor.
+// Only the latest backup is sent if no common parent exists.
+// The timestamps of the replicated backups are returned.
+func replicateApp(a *apps.App) (replicated []string, err error) {
+	// Lock the mutex.
+	replicationMutex.Lock()
+	defer replicationMutex.Unlock()
+
+	// Record the replication result.
+	parent := replicationParent(a.Name())
+	defer func() {
+		if errS := saveReplicationResult(a.Name(), parent, err); errS != nil {
+			log.Errorf("failed to save replication state: %v", errS)
+		}
+	}()
+
+	// Wrap the error with the app name.
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("failed to replicate app '%s': %v", a.Name(), err)
+		}
+	}()
+
+	target, err := newReplicationTarget()
+	if err != nil {
+		return nil, err
+	}
+
+	backups, err := a.Backups()
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(byTimestamp(backups))
+
+	replicas, err := target.Replicas(a.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	isReplicated := make(map[string]bool)
+	for _, r := range replicas {
+		isReplicated[r] = true
+	}
+
+	// The parent has to exist locally and on the target.
+	// Otherwise use the latest backup which is available on both sides.
+	if !a.BackupExists(parent) || !isReplicated[parent] {
+		parent = ""
+		for _, b := range backups {
+			if isReplicated[b] {
+				parent = b
+			}
+		}
+	}
+
+	// Obtain the backups which have to be replicated.
+	var pending []string
+	for _, b := range backups {
+		if len(b) > 0 && !isReplicated[b] && (len(parent) == 0 || newerTimestamp(b, parent)) {
+			pending = append(pending, b)
+		}
+	}
+	if len(parent) == 0 && len(pending) > 1 {
+		pending = pending[len(pending)-1:]
+	}
+
+	for _, b := range pending {
+		log.Infof("replicating backup '%s' of app '%s' (parent '%s')", b, a.Name(), parent)
+
+		if err = replicateBackup(target, a, b, parent); err != nil {
+			return replicated, err
+		}
+
+		parent = b
+		replicated = append(replicated, b)
+	}
+
+	return replicated, nil
+}
+
+// replicateBackup sends the backup to the target.
+func replicateBackup(target replicationTarget, a *apps.App, timestamp, parent string) error {
+	errChan := make(chan error, 1)
+
+	pr, pw := io.Pipe()
+	go func() {
+		err := a.SendBackup(timestamp, parent, pw)
+		pw.CloseWithError(err)
+		errChan <- err
+	}()
+
+	err := target.Receive(a.Name(), timestamp, parent, pr)
+
+	// Abort the send if the target failed.
+	pr.Close()
+
+	// The send error is more meaningful.
+	if errS := <-errChan; errS != nil {
+		return errS
+	}
+
+	return err
+}
+
+// restoreReplica receives the replica as backup of the app.
+// The app is created if it does not exist.
+// The boolean is true if the app was created.
+func restoreReplica(name, timestamp string) (bool, error) {
+	if !isValidReplicaName(name) {
+		return false, fmt.Errorf("invalid app name '%s'!", name)
+	}
+
+	target, err := newReplicationTarget()
+	if err != nil {
+		return false, err
+	}
+
+	r, err := target.Open(name, timestamp)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+
+	// Create the app if it does not exist.
+	a, err := apps.Get(name)
+	if err != nil {
+		if err = apps.ReceiveApp(name, timestamp, r); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	return false, a.ReceiveBackup(timestamp, r)
+}
+
+// replicationParent returns the last replicated backup of the app.
+func replicationParent(name string) string {
+	s, err := loadReplicationState()
+	if err != nil {
+		log.Errorf("failed to load replication state: %v", err)
+		return ""
+	}
+
+	return s.app(name).Parent
+}
+
+// replicationParents returns the last replicated backups of all apps.
+func replicationParents() (map[string]string, error) {
+	s, err := loadReplicationState()
+	if err != nil {
+		return nil, err
+	}
+
+	parents := make(map[string]string)
+	for _, a := range s.Apps {
+		parents[a.Name] = a.Parent
+	}
+
+	return parents, nil
+}
+
+// replicationInfo returns the last replication state of the app.
+func replicationInfo(name string) (*replicationAppState, error) {
+	s, err := loadReplicationState()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.app(name), nil
+}
+
+// saveReplicationResult saves the replicated parent and the error of the app.
+func saveReplicationResult(name, parent string, err error) error {
+	replicationStateMutex.Lock()
+	defer replicationStateMutex.Unlock()
+
+	s, errL := loadReplicationStateFile()
+	if errL != nil {
+		return errL
+	}
+
+	a := s.app(name)
+	a.Parent = parent
+	a.Time = time.Now().Unix()
+	a.Error = ""
+	if err != nil {
+		a.Error = err.Error()
+	}
+
+	return saveReplicationStateFile(s)
+}
+
+// loadReplicationState loads the replication state.
+func loadReplicationState() (*replicationState, error) {
+	replicationStateMutex.Lock()
+	defer replicationStateMutex.Unlock()
+
+	return loadReplicationStateFile()
+}
+
+// loadReplicationStateFile loads the replication state file.
+// The app states are reset if the replication target changed.
+// This won't lock the state mutex. You have to handle it!
+func loadReplicationStateFile() (*replicationState, error) {
+	s := &replicationState{
+		Target: config.Config.ReplicationTarget,
+	}
+
+	path := config.Config.ReplicationStateFilePath()
+
+	// Skip if it does not exists.
+	e, err := utils.Exists(path)
+	if err != nil {
+		return nil, err
+	} else if !e {
+		return s, nil
+	}
+
+	// Load and decode the file.
+	var f replicationState
+	_, err = toml.DecodeFile(path, &f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load replication state file '%s': %v", path, err)
+	}
+
+	// The replicated parents are unknown to a new target.
+	if f.Target != s.Target {
+		return s, nil
+	}
+
+	return &f, nil
+}
+
+// saveReplicationStateFile saves the replication state file.
+// This won't lock the state mutex. You have to handle it!
+func saveReplicationStateFile(s *replicationState) error {
+	// Encode the state to TOML.
+	buf := new(bytes.Buffer)
+	err := toml.NewEncoder(buf).Encode(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode replication state to toml: %v", err)
+	}
+
+	// Write the result to the state file.
+	err = ioutil.WriteFile(config.Config.ReplicationStateFilePath(), buf.Bytes(), 0600)
+	if err != nil {
+		return fmt.Errorf("failed to save replication state file: %v", err)
+	}
+
+	return nil
+}
+
+// readRemoteResponse reads the response value of the remote daemon.
+// Remote errors are returned as error.
+func readRemoteResponse(httpResponse *http.Response) (*api.Response, error) {
+	response, err := api.NewResponseFromJSON(httpResponse.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// The API versions have to match.
+	if response.Version != api.Version {
+		return nil, fmt.Errorf("API Versions don't match: daemon=%s replication target=%s", api.Version, response.Version)
+	}
+
+	// Check if an error occurred.
+	if response.Status == api.StatusError {
+		var rErr api.ResponseError
+		if err = response.MapTo(&rErr); err != nil {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("replication target: %s", rErr.ErrorMessage)
+	}
+
+	return response, nil
+}
+
+// pipeStream runs the write function in a new goroutine and returns
+// a reader of the written data. The reader returns the write error.
+func pipeStream(write func(w io.Writer) error) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(write(pw))
+	}()
+
+	return pr
+}
+
+// newerTimestamp returns a boolean whenever the unix timestamp a is newer than b.
+func newerTimestamp(a, b string) bool {
+	ua, _ := strconv.ParseInt(a, 10, 64)
+	ub, _ := strconv.ParseInt(b, 10, 64)
+	return ua > ub
+}
@@ -19,7 +19,9 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -28,6 +30,8 @@ import (
 
 	"github.com/desertbit/turtle/api"
 	"github.com/desertbit/turtle/daemon/apps"
+	"github.com/desertbit/turtle/daemon/btrfs"
+	"github.com/desertbit/turtle/daemon/config"
 	"github.com/desertbit/turtle/daemon/docker"
 	"github.com/desertbit/turtle/utils"
 
@@ -39,6 +43,10 @@ var (
 	// A write lock is perfomed to block the requests method
 	// during application shutdown.
 	requestRWLock sync.RWMutex
+
+	// This channel is closed during application shutdown
+	// to end all open streams, which would block the shutdown otherwise.
+	stopStreams = make(chan struct{})
 )
 
 func init() {
@@ -59,17 +67,36 @@ func handleRequest(rw http.ResponseWriter, req *http.Request) {
 	var err error
 	var request *api.Request
 
+	// Record the request statistics.
+	// The type is only set for permitted requests. Otherwise the request is
+	// recorded as invalid to not grow the statistics by client-supplied types.
+	start := time.Now()
+	failed := false
+	var statType api.Type
+	defer func() {
+		recordRequest(statType, failed, time.Since(start))
+	}()
+
 	// Get the remote address from the client.
 	remoteAddr, _ := utils.RemoteAddress(req)
 
 	// The error function.
 	handleError := func(err error) {
+		failed = true
+
 		// Construct a new response value.
 		response := api.NewResponse()
 
+		// Obtain the error code if present.
+		code := api.ErrorCodeGeneric
+		if apiErr, ok := err.(*api.Error); ok {
+			code = apiErr.Code
+		}
+
 		// Set the status and the error data.
 		response.Status = api.StatusError
 		response.Data = api.ResponseError{
+			ErrorCode:    code,
 			ErrorMessage: err.Error(),
 		}
 
@@ -88,6 +115,11 @@ func handleRequest(rw http.ResponseWriter, req *http.Request) {
 			return
 		}
 
+		// Set the HTTP status code for authentication errors.
+		if code == api.ErrorCodeAuthFailed {
+			rw.WriteHeader(http.StatusUnauthorized)
+		}
+
 		// Send the result to the client.
 		rw.Write(resJSON)
 	}
@@ -100,7 +132,9 @@ func handleRequest(rw http.ResponseWriter, req *http.Request) {
 	}()
 
 	// Create the request value from the http JSON body.
-	request, err = api.NewRequestFromJSON(req.Body)
+	// Imported archives follow the JSON value in the body.
+	var body io.Reader
+	request, body, err = api.NewRequestFromStream(req.Body)
 	if err != nil {
 		handleError(err)
 		return
@@ -112,8 +146,29 @@ func handleRequest(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// Authenticate the request.
+	user, err := authenticate(req, request)
+	if err != nil {
+		handleError(err)
+		return
+	}
+
 	// Log the request.
-	log.Infof("Request from client '%s': %s: %+v", remoteAddr, request.Type, request.Data)
+	log.Infof("Request from client '%s' (user '%s'): %s: %+v", remoteAddr, user, request.Type, redactData(request.Data))
+
+	// Check the permissions of the user.
+	userAccess, err := getAccess(user)
+	if err != nil {
+		handleError(err)
+		return
+	}
+	if err = userAccess.check(request); err != nil {
+		handleError(err)
+		return
+	}
+
+	// The request is recorded by its type, if it is a known request type.
+	statType = request.Type
 
 	// The response data interface.
 	var data interface{}
@@ -127,7 +182,7 @@ func handleRequest(rw http.ResponseWriter, req *http.Request) {
 	case api.TypeInfo:
 		data, err = handleInfo(request)
 	case api.TypeList:
-		data, err = handleList(request)
+		data, err = handleList(request, userAccess)
 	case api.TypeStart:
 		data, err = handleStart(request)
 	case api.TypeStop:
@@ -142,6 +197,36 @@ func handleRequest(rw http.ResponseWriter, req *http.Request) {
 		data, err = handleErrorMsg(request)
 	case api.TypeLogs:
 		data, err = handleLogs(request)
+	case api.TypeStreamLogs:
+		// Streams write their response directly.
+		if err = handleStreamLogs(rw, request); err != nil {
+			handleError(err)
+		}
+		return
+	case api.TypeExportBackup:
+		// Streams write their response directly.
+		if err = handleExportBackup(rw, request); err != nil {
+			handleError(err)
+		}
+		return
+	case api.TypeImportBackup:
+		data, err = handleImportBackup(request, body)
+	case api.TypeReplicate:
+		data, err = handleReplicate(request)
+	case api.TypeListReplicas:
+		data, err = handleListReplicas(request)
+	case api.TypeRestoreReplica:
+		data, err = handleRestoreReplica(request)
+	case api.TypeReplicaList:
+		data, err = handleReplicaList(request, userAccess.user)
+	case api.TypeReplicaReceive:
+		data, err = handleReplicaReceive(request, body, userAccess.user)
+	case api.TypeReplicaSend:
+		// Streams write their response directly.
+		if err = handleReplicaSend(rw, request, userAccess.user); err != nil {
+			handleError(err)
+		}
+		return
 	case api.TypeUpdate:
 		data, err = handleUpdate(request)
 	case api.TypeBackup:
@@ -152,11 +237,42 @@ func handleRequest(rw http.ResponseWriter, req *http.Request) {
 		data, err = handleRemoveBackup(request)
 	case api.TypeRestoreBackup:
 		data, err = handleRestoreBackup(request)
+	case api.TypePinBackup:
+		data, err = handlePinBackup(request)
+	case api.TypeSetRetention:
+		data, err = handleSetRetention(request)
+	case api.TypePruneBackups:
+		data, err = handlePruneBackups(request)
+	case api.TypeBackupHookLog:
+		data, err = handleBackupHookLog(request)
+	case api.TypeSetBackupSchedule:
+		data, err = handleSetBackupSchedule(request)
 	case api.TypeAddHostFingerprint:
 		data, err = handleAddHostFingerprint(request)
 	case api.TypeHostFingerprintInfo:
 		data, err = handleHostFingerprintInfo(request)
+	case api.TypeConfig:
+		data, err = handleConfig(request)
+	case api.TypeAddRegistry:
+		data, err = handleAddRegistry(request)
+	case api.TypeListRegistries:
+		data, err = handleListRegistries(request)
+	case api.TypeRemoveRegistry:
+		data, err = handleRemoveRegistry(request)
+	case api.TypePermissions:
+		data, err = handlePermissions(request, userAccess)
+	case api.TypeMetrics:
+		data, err = handleMetrics(request)
+	case api.TypeListRevisions:
+		data, err = handleListRevisions(request)
+	case api.TypeRollbackSource:
+		data, err = handleRollbackSource(request)
+	case api.TypeUpdatePreview:
+		data, err = handleUpdatePreview(request)
+	case api.TypeSetAutoUpdate:
+		data, err = handleSetAutoUpdate(request)
 	default:
+		statType = ""
 		handleError(fmt.Errorf("unkown request type '%v'", request.Type))
 		return
 	}
@@ -249,17 +365,84 @@ func handleInfo(request *api.Request) (interface{}, error) {
 		State:      a.State(),
 		SourceURL:  a.SourceURL(),
 		Branch:     a.Branch(),
+		Pin:        a.Pin(),
+		Commit:     a.Commit(),
 
 		Setup: setup,
 	}
 
+	// Add the restart policy and state.
+	policy, err := a.RestartPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	attempts, next := a.RestartState()
+	res.RestartPolicy = apps.NewAPIRestartPolicy(policy)
+	res.RestartAttempts = attempts
+	if !next.IsZero() {
+		res.NextRestart = next.Unix()
+	}
+
+	// Add the safe update state.
+	updateState, updateErr := a.UpdateState()
+	res.UpdateState = updateState
+	if updateErr != nil {
+		res.UpdateError = updateErr.Error()
+	}
+
+	// Add the automatic update state.
+	res.AutoUpdate, _, _ = a.AutoUpdate()
+	autoUpdateState, autoUpdateErr := a.AutoUpdateState()
+	res.AutoUpdateState = autoUpdateState
+	if autoUpdateErr != nil {
+		res.AutoUpdateError = autoUpdateErr.Error()
+	}
+
+	// Add the automatic backup schedule.
+	res.BackupSchedule = backupScheduleString(a)
+	if next := a.NextBackup(); !next.IsZero() {
+		res.NextBackup = next.Unix()
+	}
+
+	// Add the backup retention policy.
+	retention, isAppRetention := a.Retention()
+	res.Retention = retention.String()
+	res.DefaultRetention = !isAppRetention
+
+	// Add the replication state.
+	if len(config.Config.ReplicationTarget) > 0 {
+		r, err := replicationInfo(a.Name())
+		if err != nil {
+			log.Warningf("failed to get replication state of app '%s': %v", a.Name(), err)
+		} else {
+			res.ReplicatedBackup = r.Parent
+			res.LastReplication = r.Time
+			res.ReplicationError = r.Error
+		}
+	}
+
+	// Add the latest resource usage sample.
+	history, _, err := a.Metrics()
+	if err != nil {
+		log.Warningf("failed to get metrics of app '%s': %v", a.Name(), err)
+	} else if len(history) > 0 {
+		sample := newMetricsSample(history[len(history)-1])
+		res.Metrics = &sample
+	}
+
 	return res, nil
 }
 
-// handleList sends a list of all Apps.
-func handleList(request *api.Request) (interface{}, error) {
-	// Get all apps.
-	curApps := apps.Apps()
+// handleList sends a list of all Apps the user can access.
+func handleList(request *api.Request, userAccess *access) (interface{}, error) {
+	// Get all apps which the user is allowed to access.
+	var curApps []*apps.App
+	for _, app := range apps.Apps() {
+		if userAccess.isAppAllowed(app.Name()) {
+			curApps = append(curApps, app)
+		}
+	}
 
 	// Create the response value.
 	res := api.ResponseList{
@@ -553,10 +736,174 @@ func handleLogs(request *api.Request) (interface{}, error) {
 	return res, nil
 }
 
-// handleUpdate handles the update App request.
-func handleUpdate(request *api.Request) (interface{}, error) {
+// handleStreamLogs streams the log lines of the app containers.
+// Errors are only returned if the stream was not started yet.
+func handleStreamLogs(rw http.ResponseWriter, request *api.Request) error {
 	// Map the data to the custom type.
-	var data api.RequestUpdate
+	var data api.RequestStreamLogs
+	err := request.MapTo(&data)
+	if err != nil {
+		return err
+	}
+
+	// Validate.
+	if len(data.Name) == 0 || data.Tail < 0 || data.Since < 0 || data.Until < 0 {
+		return fmt.Errorf("missing or invalid data: %+v", data)
+	} else if data.Until > 0 && data.Since > data.Until {
+		return fmt.Errorf("invalid time range: since is after until!")
+	}
+
+	// Obtain the app with the given name.
+	a, err := apps.Get(data.Name)
+	if err != nil {
+		return fmt.Errorf("failed to stream logs: %v", err)
+	}
+
+	// Get the stream option.
+	var streamType docker.StdStream = docker.StdStreamCombined
+	if len(data.Stream) > 0 {
+		if data.Stream == "stderr" {
+			streamType = docker.StdStreamError
+		} else if data.Stream == "stdout" {
+			streamType = docker.StdStreamOutput
+		} else {
+			return fmt.Errorf("failed to stream logs: invalid stream option.")
+		}
+	}
+
+	// Stream all app containers if no specific containers are passed.
+	containers := data.Containers
+	if len(containers) == 0 {
+		containers, err = a.Containers()
+		if err != nil {
+			return fmt.Errorf("failed to get app containers: %v", err)
+		}
+	}
+
+	// The response writer must support flushing to stream the lines.
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		return fmt.Errorf("failed to stream logs: streaming is not supported by the connection!")
+	}
+
+	// Closed as soon as the stream should stop.
+	stop := make(chan struct{})
+	lines := make(chan api.ResponseLogLine)
+
+	opts := docker.StreamLogsOptions{
+		Stream: streamType,
+		Follow: data.Follow,
+		Tail:   data.Tail,
+		Since:  data.Since,
+		Stop:   stop,
+	}
+
+	// Start a log stream for each container.
+	var wg sync.WaitGroup
+	for _, container := range containers {
+		wg.Add(1)
+		go func(container string) {
+			defer wg.Done()
+
+			// Pass each log line to the stream.
+			f := func(l docker.LogLine) bool {
+				// Stop the container stream if the until time is reached.
+				if data.Until > 0 && l.Timestamp.Unix() > data.Until {
+					return false
+				}
+
+				line := api.ResponseLogLine{
+					Container: container,
+					Stream:    "stdout",
+					Message:   l.Message,
+				}
+
+				if l.Stream == docker.StdStreamError {
+					line.Stream = "stderr"
+				}
+				if data.Timestamps {
+					line.Timestamp = l.Timestamp.Format(time.RFC3339Nano)
+				}
+
+				select {
+				case lines <- line:
+					return true
+				case <-stop:
+					return false
+				}
+			}
+
+			// Read the captured log files if requested or if the container was removed.
+			history := data.History
+			if !history {
+				c, err := docker.GetContainerByName(a.ContainerNamePrefix() + container)
+				if err != nil {
+					log.Warningf("failed to get container '%s': %v", container, err)
+				}
+				history = c == nil
+			}
+
+			var err error
+			if history {
+				err = a.ReadLogs(container, opts, f)
+			} else {
+				err = docker.StreamLogs(a.ContainerNamePrefix()+container, opts, f)
+			}
+			if err != nil {
+				select {
+				case lines <- api.ResponseLogLine{Container: container, Error: err.Error()}:
+				case <-stop:
+				}
+			}
+		}(container)
+	}
+
+	// Close the lines channel as soon as all container streams are done.
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+
+	// Stop all container streams on return.
+	defer close(stop)
+
+	// Get notified if the client closes the connection.
+	var closed <-chan bool
+	if notifier, ok := rw.(http.CloseNotifier); ok {
+		closed = notifier.CloseNotify()
+	}
+
+	// Start the stream.
+	rw.Header().Set("Content-Type", api.StreamContentType)
+	rw.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	encoder := json.NewEncoder(rw)
+
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return nil
+			}
+
+			if err = encoder.Encode(line); err != nil {
+				log.Warningf("failed to send log line: %v", err)
+				return nil
+			}
+			flusher.Flush()
+		case <-closed:
+			return nil
+		case <-stopStreams:
+			return nil
+		}
+	}
+}
+
+// handleMetrics sends the resource usage history of an app.
+func handleMetrics(request *api.Request) (interface{}, error) {
+	// Map the data to the custom type.
+	var data api.RequestMetrics
 	err := request.MapTo(&data)
 	if err != nil {
 		return nil, err
@@ -570,22 +917,59 @@ func handleUpdate(request *api.Request) (interface{}, error) {
 	// Obtain the app with the given name.
 	a, err := apps.Get(data.Name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update app: %v", err)
+		return nil, fmt.Errorf("failed to get app metrics: %v", err)
 	}
 
-	// Update the app.
-	err = a.Update()
+	history, containers, err := a.Metrics()
 	if err != nil {
-		return nil, fmt.Errorf("failed to update app: %v", err)
+		return nil, fmt.Errorf("failed to get app metrics: %v", err)
 	}
 
-	return nil, nil
+	// Create the response value.
+	res := api.ResponseMetrics{
+		Name:       a.Name(),
+		Interval:   config.Config.MetricsInterval.String(),
+		Containers: make([]api.ResponseContainerMetrics, len(containers)),
+		History:    make([]api.ResponseMetricsSample, len(history)),
+	}
+
+	for i, c := range containers {
+		res.Containers[i] = api.ResponseContainerMetrics{
+			Name:        c.Name,
+			CPUPercent:  c.CPUPercent,
+			MemoryUsage: c.MemoryUsage,
+			MemoryLimit: c.MemoryLimit,
+			NetworkRx:   c.NetworkRx,
+			NetworkTx:   c.NetworkTx,
+		}
+	}
+
+	for i, s := range history {
+		res.History[i] = newMetricsSample(s)
+	}
+
+	return res, nil
 }
 
-// handleBackup creates a hot backup.
-func handleBackup(request *api.Request) (interface{}, error) {
+// newMetricsSample converts the app sample to an API sample.
+func newMetricsSample(s apps.MetricsSample) api.ResponseMetricsSample {
+	return api.ResponseMetricsSample{
+		Time:             s.Time.Unix(),
+		CPUPercent:       s.CPUPercent,
+		MemoryUsage:      s.MemoryUsage,
+		MemoryLimit:      s.MemoryLimit,
+		NetworkRxRate:    s.NetworkRxRate,
+		NetworkTxRate:    s.NetworkTxRate,
+		Storage:          s.Storage,
+		StorageExclusive: s.StorageExclusive,
+		BackupsStorage:   s.BackupsStorage,
+	}
+}
+
+// handleUpdate handles the update App request.
+func handleUpdate(request *api.Request) (interface{}, error) {
 	// Map the data to the custom type.
-	var data api.RequestBackup
+	var data api.RequestUpdate
 	err := request.MapTo(&data)
 	if err != nil {
 		return nil, err
@@ -599,193 +983,1039 @@ func handleBackup(request *api.Request) (interface{}, error) {
 	// Obtain the app with the given name.
 	a, err := apps.Get(data.Name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to backup app: %v", err)
+		return nil, fmt.Errorf("failed to update app: %v", err)
 	}
 
-	// Backup the app.
-	err = a.Backup()
+	// Update the app. Pin the app to the revision or follow the branch again if requested.
+	if len(data.Revision) > 0 || data.Unpin {
+		err = a.UpdatePin(data.Revision, data.Safe)
+	} else if data.Safe {
+		err = a.SafeUpdate()
+	} else {
+		err = a.Update()
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to backup app: %v", err)
+		return nil, fmt.Errorf("failed to update app: %v", err)
 	}
 
 	return nil, nil
 }
 
-// handleListBackups returns a list of all app backups.
-func handleListBackups(request *api.Request) (interface{}, error) {
+// handleListRevisions lists the upstream commits and tags of an app.
+func handleListRevisions(request *api.Request) (interface{}, error) {
 	// Map the data to the custom type.
-	var data api.RequestListBackups
+	var data api.RequestListRevisions
 	err := request.MapTo(&data)
 	if err != nil {
 		return nil, err
 	}
 
 	// Validate.
-	if len(data.Name) == 0 {
+	if len(data.Name) == 0 || data.Limit < 0 {
 		return nil, fmt.Errorf("missing or invalid data: %+v", data)
 	}
 
 	// Obtain the app with the given name.
 	a, err := apps.Get(data.Name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list backups: %v", err)
+		return nil, fmt.Errorf("failed to list revisions: %v", err)
 	}
 
-	// Get all backup timestamps of the app.
-	list, err := a.Backups()
+	// Fetch and obtain the upstream revisions.
+	revisions, tags, err := a.Revisions(data.Limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list backups: %v", err)
+		return nil, fmt.Errorf("failed to list revisions: %v", err)
 	}
 
 	// Create the response value.
-	res := api.ResponseListBackups{
-		Backups: make([]api.ResponseListBackup, len(list)),
-	}
-
-	// Add all the backup timestamps to the response value.
-	for i, u := range list {
-		unix, err := strconv.ParseInt(u, 10, 64)
-		if err != nil {
-			return nil, fmt.Errorf("failed to list backups: failed to parse unix timestamp: %v", err)
+	res := api.ResponseListRevisions{
+		Branch:  a.Branch(),
+		Pin:     a.Pin(),
+		Commit:  a.Commit(),
+		Commits: make([]api.ResponseRevision, len(revisions)),
+		Tags:    make([]api.ResponseTag, len(tags)),
+	}
+
+	for i, r := range revisions {
+		res.Commits[i] = api.ResponseRevision{
+			Commit:  r.Commit,
+			Time:    r.Time.Unix(),
+			Author:  r.Author,
+			Subject: r.Subject,
 		}
+	}
 
-		res.Backups[i] = api.ResponseListBackup{
-			Unix: u,
-			Date: time.Unix(unix, 0).String(),
+	for i, t := range tags {
+		res.Tags[i] = api.ResponseTag{
+			Name:   t.Name,
+			Commit: t.Commit,
 		}
 	}
 
 	return res, nil
 }
 
-// handleRemoveBackup handles the remove backup request.
-func handleRemoveBackup(request *api.Request) (interface{}, error) {
+// handleRollbackSource pins the app to a previous source revision and updates it.
+// The volume data is not restored.
+func handleRollbackSource(request *api.Request) (interface{}, error) {
 	// Map the data to the custom type.
-	var data api.RequestRemoveBackup
+	var data api.RequestRollbackSource
 	err := request.MapTo(&data)
 	if err != nil {
 		return nil, err
 	}
 
 	// Validate.
-	if len(data.Name) == 0 || len(data.Unix) == 0 {
+	if len(data.Name) == 0 {
 		return nil, fmt.Errorf("missing or invalid data: %+v", data)
 	}
 
 	// Obtain the app with the given name.
 	a, err := apps.Get(data.Name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to remove backup: %v", err)
+		return nil, fmt.Errorf("failed to rollback app source: %v", err)
 	}
 
-	// Remove the backup.
-	err = a.RemoveBackup(data.Unix)
+	// Use the previously deployed commit by default.
+	revision := data.Revision
+	if len(revision) == 0 {
+		revision = a.PreviousCommit()
+		if len(revision) == 0 {
+			return nil, fmt.Errorf("failed to rollback app source: no previously deployed commit recorded!")
+		}
+	}
+
+	// Pin the app to the revision and update it.
+	err = a.UpdatePin(revision, data.Safe)
 	if err != nil {
-		return nil, fmt.Errorf("failed to remove backup: %v", err)
+		return nil, fmt.Errorf("failed to rollback app source: %v", err)
 	}
 
 	return nil, nil
 }
 
-// handleRestoreBackup handles the restore backup request.
-func handleRestoreBackup(request *api.Request) (interface{}, error) {
+// handleUpdatePreview fetches the app source and returns the changes an update would apply.
+func handleUpdatePreview(request *api.Request) (interface{}, error) {
 	// Map the data to the custom type.
-	var data api.RequestRestoreBackup
+	var data api.RequestUpdatePreview
 	err := request.MapTo(&data)
 	if err != nil {
 		return nil, err
 	}
 
 	// Validate.
-	if len(data.Name) == 0 || len(data.Unix) == 0 {
+	if len(data.Name) == 0 {
 		return nil, fmt.Errorf("missing or invalid data: %+v", data)
 	}
 
 	// Obtain the app with the given name.
 	a, err := apps.Get(data.Name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to restore backup: %v", err)
+		return nil, fmt.Errorf("failed to preview update: %v", err)
 	}
 
-	// Restore the backup.
-	err = a.RestoreBackup(data.Unix)
+	// Obtain the update preview.
+	p, err := a.UpdatePreview(data.Revision)
 	if err != nil {
-		return nil, fmt.Errorf("failed to restore backup: %v", err)
+		return nil, fmt.Errorf("failed to preview update: %v", err)
 	}
 
-	return nil, nil
+	// Create the response value.
+	res := api.ResponseUpdatePreview{
+		Commit:   p.Commit,
+		Target:   p.Target,
+		Commits:  make([]api.ResponseRevision, len(p.Commits)),
+		Reverted: make([]api.ResponseRevision, len(p.Reverted)),
+		Warnings: p.Warnings,
+	}
+
+	for i, r := range p.Commits {
+		res.Commits[i] = api.ResponseRevision{
+			Commit:  r.Commit,
+			Time:    r.Time.Unix(),
+			Author:  r.Author,
+			Subject: r.Subject,
+		}
+	}
+
+	for i, r := range p.Reverted {
+		res.Reverted[i] = api.ResponseRevision{
+			Commit:  r.Commit,
+			Time:    r.Time.Unix(),
+			Author:  r.Author,
+			Subject: r.Subject,
+		}
+	}
+
+	if c := p.Changes; c != nil {
+		res.Turtlefile = &api.ResponseTurtlefileChanges{
+			AddedContainers:   c.AddedContainers,
+			RemovedContainers: c.RemovedContainers,
+			ImageChanges:      make([]api.ResponseImageChange, len(c.ImageChanges)),
+			AddedEnv:          c.AddedEnv,
+			RemovedEnv:        c.RemovedEnv,
+			RequiredEnv:       c.RequiredEnv,
+			AddedPorts:        c.AddedPorts,
+			RemovedPorts:      c.RemovedPorts,
+		}
+
+		for i, ic := range c.ImageChanges {
+			res.Turtlefile.ImageChanges[i] = api.ResponseImageChange{
+				Container: ic.Container,
+				OldImage:  ic.OldImage,
+				NewImage:  ic.NewImage,
+			}
+		}
+	}
+
+	return res, nil
 }
 
-// handleAddHostFingerprint adds a new host fingerprint.
-func handleAddHostFingerprint(request *api.Request) (interface{}, error) {
+// handleSetAutoUpdate sets the automatic update mode of an app.
+func handleSetAutoUpdate(request *api.Request) (interface{}, error) {
 	// Map the data to the custom type.
-	var data api.RequestAddHostFingerprint
+	var data api.RequestSetAutoUpdate
 	err := request.MapTo(&data)
 	if err != nil {
 		return nil, err
 	}
 
 	// Validate.
-	if len(data.Fingerprint) == 0 {
+	if len(data.Name) == 0 || len(data.Mode) == 0 {
 		return nil, fmt.Errorf("missing or invalid data: %+v", data)
 	}
 
-	// Add the fingerprint.
-	err = addHostFingerprint(data.Fingerprint)
+	// Parse the poll interval.
+	var interval time.Duration
+	if len(data.Interval) > 0 {
+		interval, err = time.ParseDuration(data.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid poll interval '%s': %v", data.Interval, err)
+		}
+	}
+
+	// Obtain the app with the given name.
+	a, err := apps.Get(data.Name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to add host fingerprint: %v", err)
+		return nil, fmt.Errorf("failed to set automatic updates: %v", err)
 	}
 
-	return nil, nil
+	err = a.SetAutoUpdate(data.Mode, interval, data.Safe, data.NewSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set automatic updates: %v", err)
+	}
+
+	// Create the response value.
+	mode, interval, safe := a.AutoUpdate()
+	res := api.ResponseSetAutoUpdate{
+		Mode:     mode,
+		Interval: interval.String(),
+		Safe:     safe,
+		Secret:   a.WebhookSecret(),
+	}
+
+	if len(res.Secret) > 0 {
+		res.WebhookPath = webhookPath + a.Name()
+	}
+
+	return res, nil
 }
 
-// handleHostFingerprintInfo obtains the host fingerprint and some information about it.
-func handleHostFingerprintInfo(request *api.Request) (interface{}, error) {
+// handleSetBackupSchedule sets the automatic backup schedule of an app.
+func handleSetBackupSchedule(request *api.Request) (interface{}, error) {
 	// Map the data to the custom type.
-	var data api.RequestHostFingerprintInfo
+	var data api.RequestSetBackupSchedule
 	err := request.MapTo(&data)
 	if err != nil {
 		return nil, err
 	}
 
-	// Be sure it is a valid stripped host.
-	data.Host = utils.GetHostFromUrl(data.Host)
-
 	// Validate.
-	if len(data.Host) == 0 {
-		return nil, fmt.Errorf("invalid host: '%s'", data.Host)
+	if len(data.Name) == 0 || len(data.Mode) == 0 {
+		return nil, fmt.Errorf("missing or invalid data: %+v", data)
 	}
 
-	// Skip host fingerprint checking if this is a local repository.
-	if strings.HasPrefix(data.Host, "file://") {
-		// Create the response value.
-		response := &api.ResponseHostFingerprintInfo{
-			Host:        data.Host,
-			Trusted:     true,
-			Fingerprint: "",
+	// Parse the backup interval.
+	var interval time.Duration
+	if len(data.Interval) > 0 {
+		interval, err = time.ParseDuration(data.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid backup interval '%s': %v", data.Interval, err)
 		}
-
-		return response, nil
 	}
 
-	// Check if the fingerprint exists for the host.
-	exists, err := hostFingerprintExists(data.Host)
+	// Obtain the app with the given name.
+	a, err := apps.Get(data.Name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get information about host fingerprint: %v", err)
+		return nil, fmt.Errorf("failed to set backup schedule: %v", err)
 	}
 
-	// Obtain the fingerprint of the host.
-	fingerprint, err := getSshHostFingerprint(data.Host)
+	err = a.SetBackupSchedule(data.Mode, interval, data.Cron, data.Stopped)
 	if err != nil {
-		return nil, fmt.Errorf("failed to obtain host fingerprint: %v", err)
+		return nil, fmt.Errorf("failed to set backup schedule: %v", err)
 	}
 
 	// Create the response value.
-	response := &api.ResponseHostFingerprintInfo{
-		Host:        data.Host,
-		Trusted:     exists,
-		Fingerprint: fingerprint,
+	res := api.ResponseSetBackupSchedule{
+		Schedule: backupScheduleString(a),
+	}
+	if next := a.NextBackup(); !next.IsZero() {
+		res.NextBackup = next.Unix()
 	}
 
-	return response, nil
+	return res, nil
+}
+
+// handleBackup creates a hot backup.
+func handleBackup(request *api.Request) (interface{}, error) {
+	// Map the data to the custom type.
+	var data api.RequestBackup
+	err := request.MapTo(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate.
+	if len(data.Name) == 0 {
+		return nil, fmt.Errorf("missing or invalid data: %+v", data)
+	}
+
+	// Obtain the app with the given name.
+	a, err := apps.Get(data.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to backup app: %v", err)
+	}
+
+	// Backup the app.
+	err = a.Backup()
+	if err != nil {
+		return nil, fmt.Errorf("failed to backup app: %v", err)
+	}
+
+	return nil, nil
+}
+
+// handleListBackups returns a list of all app backups.
+func handleListBackups(request *api.Request) (interface{}, error) {
+	// Map the data to the custom type.
+	var data api.RequestListBackups
+	err := request.MapTo(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate.
+	if len(data.Name) == 0 {
+		return nil, fmt.Errorf("missing or invalid data: %+v", data)
+	}
+
+	// Obtain the app with the given name.
+	a, err := apps.Get(data.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %v", err)
+	}
+
+	// Get all backup timestamps of the app.
+	list, err := a.Backups()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %v", err)
+	}
+
+	// Get the pinned backups.
+	pinned, err := a.PinnedBackups()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %v", err)
+	}
+
+	// Create the response value.
+	res := api.ResponseListBackups{
+		Backups: make([]api.ResponseListBackup, len(list)),
+	}
+
+	// Add all the backup timestamps to the response value.
+	for i, u := range list {
+		res.Backups[i], err = newResponseListBackup(u)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list backups: %v", err)
+		}
+		res.Backups[i].Pinned = pinned[u]
+	}
+
+	return res, nil
+}
+
+// handlePinBackup pins or unpins a backup.
+func handlePinBackup(request *api.Request) (interface{}, error) {
+	// Map the data to the custom type.
+	var data api.RequestPinBackup
+	err := request.MapTo(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate.
+	if len(data.Name) == 0 || len(data.Unix) == 0 {
+		return nil, fmt.Errorf("missing or invalid data: %+v", data)
+	}
+
+	// Obtain the app with the given name.
+	a, err := apps.Get(data.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.PinBackup(data.Unix, !data.Unpin)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// handleBackupHookLog returns the recorded backup hooks output of a backup.
+func handleBackupHookLog(request *api.Request) (interface{}, error) {
+	// Map the data to the custom type.
+	var data api.RequestBackupHookLog
+	err := request.MapTo(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate.
+	if len(data.Name) == 0 || len(data.Unix) == 0 {
+		return nil, fmt.Errorf("missing or invalid data: %+v", data)
+	}
+
+	// Obtain the app with the given name.
+	a, err := apps.Get(data.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := a.BackupHookLog(data.Unix)
+	if err != nil {
+		return nil, err
+	}
+
+	res := api.ResponseBackupHookLog{
+		Log: l,
+	}
+
+	return res, nil
+}
+
+// handleSetRetention sets the backup retention policy of an app.
+func handleSetRetention(request *api.Request) (interface{}, error) {
+	// Map the data to the custom type.
+	var data api.RequestSetRetention
+	err := request.MapTo(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate.
+	if len(data.Name) == 0 {
+		return nil, fmt.Errorf("missing or invalid data: %+v", data)
+	}
+
+	// Obtain the app with the given name.
+	a, err := apps.Get(data.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.SetRetention(data.Retention)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set retention policy: %v", err)
+	}
+
+	r, _ := a.Retention()
+	res := api.ResponseSetRetention{
+		Retention: r.String(),
+	}
+
+	return res, nil
+}
+
+// handlePruneBackups removes the backups expired by the retention policy of an app.
+func handlePruneBackups(request *api.Request) (interface{}, error) {
+	// Map the data to the custom type.
+	var data api.RequestPruneBackups
+	err := request.MapTo(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate.
+	if len(data.Name) == 0 {
+		return nil, fmt.Errorf("missing or invalid data: %+v", data)
+	}
+
+	// Obtain the app with the given name.
+	a, err := apps.Get(data.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	removed, err := pruneBackups(a, data.DryRun)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prune backups: %v", err)
+	}
+
+	r, _ := a.Retention()
+	res := api.ResponsePruneBackups{
+		Retention: r.String(),
+		DryRun:    data.DryRun,
+		Removed:   make([]api.ResponseListBackup, len(removed)),
+	}
+
+	for i, u := range removed {
+		res.Removed[i], err = newResponseListBackup(u)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prune backups: %v", err)
+		}
+	}
+
+	return res, nil
+}
+
+// handleRemoveBackup handles the remove backup request.
+func handleRemoveBackup(request *api.Request) (interface{}, error) {
+	// Map the data to the custom type.
+	var data api.RequestRemoveBackup
+	err := request.MapTo(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate.
+	if len(data.Name) == 0 || len(data.Unix) == 0 {
+		return nil, fmt.Errorf("missing or invalid data: %+v", data)
+	}
+
+	// Obtain the app with the given name.
+	a, err := apps.Get(data.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove backup: %v", err)
+	}
+
+	// Remove the backup.
+	err = a.RemoveBackup(data.Unix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove backup: %v", err)
+	}
+
+	return nil, nil
+}
+
+// handleRestoreBackup handles the restore backup request.
+func handleRestoreBackup(request *api.Request) (interface{}, error) {
+	// Map the data to the custom type.
+	var data api.RequestRestoreBackup
+	err := request.MapTo(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate.
+	if len(data.Name) == 0 || len(data.Unix) == 0 {
+		return nil, fmt.Errorf("missing or invalid data: %+v", data)
+	}
+
+	// Obtain the app with the given name.
+	a, err := apps.Get(data.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore backup: %v", err)
+	}
+
+	// Restore the backup.
+	err = a.RestoreBackup(data.Unix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore backup: %v", err)
+	}
+
+	return nil, nil
+}
+
+// handleExportBackup streams the encrypted and compressed backup archive.
+// Errors are only returned if the stream was not started yet.
+func handleExportBackup(rw http.ResponseWriter, request *api.Request) error {
+	// Map the data to the custom type.
+	var data api.RequestExportBackup
+	err := request.MapTo(&data)
+	if err != nil {
+		return err
+	}
+
+	// Validate.
+	if len(data.Name) == 0 || len(data.Unix) == 0 || len(data.Password) == 0 {
+		return fmt.Errorf("missing or invalid data: name, backup and password are required")
+	}
+
+	// Obtain the app with the given name.
+	a, err := apps.Get(data.Name)
+	if err != nil {
+		return fmt.Errorf("failed to export backup: %v", err)
+	} else if !a.BackupExists(data.Unix) {
+		return fmt.Errorf("failed to export backup: no backup '%s' found!", data.Unix)
+	}
+
+	// Start the stream.
+	rw.Header().Set("Content-Type", api.ArchiveContentType)
+	rw.WriteHeader(http.StatusOK)
+
+	// The client detects the incomplete archive on error.
+	if err = a.ExportBackup(data.Unix, data.Password, rw); err != nil {
+		log.Errorf("failed to export backup '%s' of app '%s': %v", data.Unix, data.Name, err)
+	}
+
+	return nil
+}
+
+// handleImportBackup imports the archive in the request body
+// as a new backup of an existing app or as a new app.
+func handleImportBackup(request *api.Request, body io.Reader) (interface{}, error) {
+	// Map the data to the custom type.
+	var data api.RequestImportBackup
+	err := request.MapTo(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate.
+	if len(data.Name) == 0 || len(data.Password) == 0 {
+		return nil, fmt.Errorf("missing or invalid data: name and password are required")
+	}
+
+	res := api.ResponseImportBackup{
+		Name: data.Name,
+	}
+
+	// Create a new app from the archive.
+	if data.NewApp {
+		m, err := apps.Import(data.Name, body, data.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import app: %v", err)
+		}
+
+		res.SourceApp = m.App
+		res.SourceUnix = m.Timestamp
+
+		return res, nil
+	}
+
+	// Obtain the app with the given name.
+	a, err := apps.Get(data.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import backup: %v", err)
+	}
+
+	// Import the archive as new backup.
+	unix, m, err := a.ImportBackup(body, data.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import backup: %v", err)
+	}
+
+	res.Unix = unix
+	res.SourceApp = m.App
+	res.SourceUnix = m.Timestamp
+
+	return res, nil
+}
+
+// handleReplicate replicates the new backups of the app to the replication target.
+func handleReplicate(request *api.Request) (interface{}, error) {
+	// Map the data to the custom type.
+	var data api.RequestReplicate
+	err := request.MapTo(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate.
+	if len(data.Name) == 0 {
+		return nil, fmt.Errorf("missing or invalid data: %+v", data)
+	}
+
+	// Obtain the app with the given name.
+	a, err := apps.Get(data.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replicate the backups.
+	replicated, err := replicateApp(a)
+	if err != nil {
+		return nil, err
+	}
+
+	res := api.ResponseReplicate{
+		Replicated: replicated,
+	}
+
+	return res, nil
+}
+
+// handleListReplicas lists the replicas of the app on the replication target.
+func handleListReplicas(request *api.Request) (interface{}, error) {
+	// Map the data to the custom type.
+	var data api.RequestListReplicas
+	err := request.MapTo(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate.
+	if len(data.Name) == 0 || !isValidReplicaName(data.Name) {
+		return nil, fmt.Errorf("missing or invalid data: %+v", data)
+	}
+
+	target, err := newReplicationTarget()
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := target.Replicas(data.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replicas: %v", err)
+	}
+
+	// Create the response value.
+	res := api.ResponseListReplicas{
+		Target:   config.Config.ReplicationTarget,
+		Parent:   replicationParent(data.Name),
+		Replicas: make([]api.ResponseListBackup, len(list)),
+	}
+
+	// Add all the replica timestamps to the response value.
+	for i, u := range list {
+		res.Replicas[i], err = newResponseListBackup(u)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list replicas: %v", err)
+		}
+	}
+
+	return res, nil
+}
+
+// handleRestoreReplica receives the replica from the replication target
+// as backup of the app. The app is created if it does not exist.
+func handleRestoreReplica(request *api.Request) (interface{}, error) {
+	// Map the data to the custom type.
+	var data api.RequestRestoreReplica
+	err := request.MapTo(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate.
+	if len(data.Name) == 0 || len(data.Unix) == 0 {
+		return nil, fmt.Errorf("missing or invalid data: %+v", data)
+	}
+
+	newApp, err := restoreReplica(data.Name, data.Unix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore replica: %v", err)
+	}
+
+	res := api.ResponseRestoreReplica{
+		Name:   data.Name,
+		Unix:   data.Unix,
+		NewApp: newApp,
+	}
+
+	return res, nil
+}
+
+// handleReplicaList lists the app replicas received from the user.
+func handleReplicaList(request *api.Request, user string) (interface{}, error) {
+	// Map the data to the custom type.
+	var data api.RequestReplicaList
+	err := request.MapTo(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	replicas, err := listReplicas(user, data.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replicas: %v", err)
+	}
+
+	res := api.ResponseReplicaList{
+		Replicas: replicas,
+	}
+
+	return res, nil
+}
+
+// handleReplicaReceive receives the replica stream in the request body.
+func handleReplicaReceive(request *api.Request, body io.Reader, user string) (interface{}, error) {
+	// Map the data to the custom type.
+	var data api.RequestReplicaReceive
+	err := request.MapTo(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = receiveReplica(user, data.Name, data.Unix, data.Parent, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive replica: %v", err)
+	}
+
+	return nil, nil
+}
+
+// handleReplicaSend streams the replica received from the user.
+// Errors are only returned if the stream was not started yet.
+func handleReplicaSend(rw http.ResponseWriter, request *api.Request, user string) error {
+	// Map the data to the custom type.
+	var data api.RequestReplicaSend
+	err := request.MapTo(&data)
+	if err != nil {
+		return err
+	}
+
+	// Check if the replica exists.
+	dir, err := replicaDirPath(user, data.Name)
+	if err != nil {
+		return err
+	} else if !isValidTimestamp(data.Unix) || !btrfs.IsSubvolume(dir+"/"+data.Unix) {
+		return fmt.Errorf("failed to send replica: no replica '%s' found!", data.Unix)
+	}
+
+	// Start the stream.
+	rw.Header().Set("Content-Type", api.ReplicaContentType)
+	rw.WriteHeader(http.StatusOK)
+
+	// The receiver detects the incomplete stream on error.
+	if err = sendReplica(user, data.Name, data.Unix, rw); err != nil {
+		log.Errorf("failed to send replica '%s' of app '%s' to user '%s': %v", data.Unix, data.Name, user, err)
+	}
+
+	return nil
+}
+
+// handleAddHostFingerprint adds a new host fingerprint.
+func handleAddHostFingerprint(request *api.Request) (interface{}, error) {
+	// Map the data to the custom type.
+	var data api.RequestAddHostFingerprint
+	err := request.MapTo(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate.
+	if len(data.Fingerprint) == 0 {
+		return nil, fmt.Errorf("missing or invalid data: %+v", data)
+	}
+
+	// Add the fingerprint.
+	err = addHostFingerprint(data.Fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add host fingerprint: %v", err)
+	}
+
+	return nil, nil
+}
+
+// handleHostFingerprintInfo obtains the host fingerprint and some information about it.
+func handleHostFingerprintInfo(request *api.Request) (interface{}, error) {
+	// Map the data to the custom type.
+	var data api.RequestHostFingerprintInfo
+	err := request.MapTo(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Be sure it is a valid stripped host.
+	data.Host = utils.GetHostFromUrl(data.Host)
+
+	// Validate.
+	if len(data.Host) == 0 {
+		return nil, fmt.Errorf("invalid host: '%s'", data.Host)
+	}
+
+	// Skip host fingerprint checking if this is a local repository.
+	if strings.HasPrefix(data.Host, "file://") {
+		// Create the response value.
+		response := &api.ResponseHostFingerprintInfo{
+			Host:        data.Host,
+			Trusted:     true,
+			Fingerprint: "",
+		}
+
+		return response, nil
+	}
+
+	// Check if the fingerprint exists for the host.
+	exists, err := hostFingerprintExists(data.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get information about host fingerprint: %v", err)
+	}
+
+	// Obtain the fingerprint of the host.
+	fingerprint, err := getSshHostFingerprint(data.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain host fingerprint: %v", err)
+	}
+
+	// Create the response value.
+	response := &api.ResponseHostFingerprintInfo{
+		Host:        data.Host,
+		Trusted:     exists,
+		Fingerprint: fingerprint,
+	}
+
+	return response, nil
+}
+
+// handleConfig sends the effective daemon configuration.
+func handleConfig(request *api.Request) (interface{}, error) {
+	// Get all current config options.
+	options := config.Options()
+
+	// Create the response value.
+	res := api.ResponseConfig{
+		FilePath: config.FilePath,
+		Options:  make([]api.ResponseConfigOption, len(options)),
+	}
+
+	for i, o := range options {
+		res.Options[i] = api.ResponseConfigOption{
+			Name:   o.Name,
+			Value:  o.Value,
+			Source: o.Source,
+		}
+	}
+
+	return res, nil
+}
+
+// handleAddRegistry stores the credentials of a docker registry.
+func handleAddRegistry(request *api.Request) (interface{}, error) {
+	// Map the data to the custom type.
+	var data api.RequestAddRegistry
+	err := request.MapTo(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate.
+	if len(data.Host) == 0 || len(data.Username) == 0 || len(data.Password) == 0 {
+		return nil, fmt.Errorf("missing or invalid data: host, username and password are required")
+	}
+
+	// Add the registry credentials.
+	err = docker.AddRegistry(docker.Registry{
+		Host:     data.Host,
+		Username: data.Username,
+		Password: data.Password,
+		Email:    data.Email,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add registry: %v", err)
+	}
+
+	return nil, nil
+}
+
+// handleListRegistries lists the docker registries with stored credentials.
+// The passwords are not sent.
+func handleListRegistries(request *api.Request) (interface{}, error) {
+	registries, err := docker.Registries()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list registries: %v", err)
+	}
+
+	// Create the response value.
+	res := api.ResponseListRegistries{
+		Registries: make([]api.ResponseRegistry, len(registries)),
+	}
+
+	for i, r := range registries {
+		res.Registries[i] = api.ResponseRegistry{
+			Host:     r.Host,
+			Username: r.Username,
+			Email:    r.Email,
+		}
+	}
+
+	return res, nil
+}
+
+// handleRemoveRegistry removes the credentials of a docker registry.
+func handleRemoveRegistry(request *api.Request) (interface{}, error) {
+	// Map the data to the custom type.
+	var data api.RequestRemoveRegistry
+	err := request.MapTo(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate.
+	if len(data.Host) == 0 {
+		return nil, fmt.Errorf("missing or invalid data: %+v", data)
+	}
+
+	// Remove the registry credentials.
+	if err = docker.RemoveRegistry(data.Host); err != nil {
+		return nil, fmt.Errorf("failed to remove registry: %v", err)
+	}
+
+	return nil, nil
+}
+
+// backupScheduleString returns a description of the app's backup schedule.
+func backupScheduleString(a *apps.App) string {
+	mode, interval, cron, stopped := a.BackupSchedule()
+
+	var s string
+	switch mode {
+	case apps.BackupScheduleDisabled:
+		return "disabled"
+	case apps.BackupScheduleCron:
+		s = "cron " + cron
+	case apps.BackupScheduleDefault:
+		s = "every " + interval.String() + " (default)"
+	default:
+		s = "every " + interval.String()
+	}
+
+	if stopped {
+		s += ", also if stopped"
+	}
+
+	return s
+}
+
+// newResponseListBackup creates the list value of the backup timestamp.
+func newResponseListBackup(u string) (api.ResponseListBackup, error) {
+	unix, err := strconv.ParseInt(u, 10, 64)
+	if err != nil {
+		return api.ResponseListBackup{}, fmt.Errorf("failed to parse unix timestamp: %v", err)
+	}
+
+	return api.ResponseListBackup{
+		Unix: u,
+		Date: time.Unix(unix, 0).String(),
+	}, nil
+}
+
+// redactData returns the request data with the passwords replaced.
+// It is used to log requests.
+func redactData(data interface{}) interface{} {
+	m, ok := data.(map[string]interface{})
+	if !ok {
+		return data
+	}
+
+	r := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if k == "Password" {
+			v = "<redacted>"
+		}
+		r[k] = v
+	}
+
+	return r
 }
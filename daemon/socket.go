@@ -0,0 +1,125 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/user"
+	"strconv"
+
+	"github.com/desertbit/turtle/daemon/config"
+	"github.com/desertbit/turtle/utils"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	// The remote address and user name of requests from the unix socket.
+	// Access is granted by the file permissions of the socket.
+	localRemoteAddr = "unix"
+	localUser       = "local"
+)
+
+//#####################//
+//### Local handler ###//
+//#####################//
+
+// localHandler marks all requests as local requests and passes
+// them to the default http handlers.
+type localHandler struct{}
+
+func (h localHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	req.RemoteAddr = localRemoteAddr
+	http.DefaultServeMux.ServeHTTP(rw, req)
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// isLocalRequest returns a boolean whenever the request was received on the unix socket.
+func isLocalRequest(req *http.Request) bool {
+	return req.RemoteAddr == localRemoteAddr
+}
+
+// serveSocket listens on the unix socket and serves local requests.
+func serveSocket() error {
+	path := config.Config.SocketPath
+
+	// Remove a previous socket file if present.
+	if err := removeSocket(); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on unix socket '%s': %v", path, err)
+	}
+	defer listener.Close()
+
+	// Set the socket file permissions.
+	if err = os.Chmod(path, config.Config.SocketMode); err != nil {
+		return fmt.Errorf("failed to set unix socket permissions: %v", err)
+	}
+
+	// Set the socket group if defined.
+	if len(config.Config.SocketGroup) > 0 {
+		g, err := user.LookupGroup(config.Config.SocketGroup)
+		if err != nil {
+			return fmt.Errorf("failed to lookup unix socket group: %v", err)
+		}
+
+		gid, err := strconv.Atoi(g.Gid)
+		if err != nil {
+			return fmt.Errorf("invalid unix socket group id '%s': %v", g.Gid, err)
+		}
+
+		if err = os.Chown(path, -1, gid); err != nil {
+			return fmt.Errorf("failed to set unix socket group: %v", err)
+		}
+	}
+
+	log.Infof("Turtle server listening on unix socket '%s'", path)
+
+	return http.Serve(listener, localHandler{})
+}
+
+// removeSocket removes the unix socket file if present.
+func removeSocket() error {
+	path := config.Config.SocketPath
+	if len(path) == 0 {
+		return nil
+	}
+
+	e, err := utils.Exists(path)
+	if err != nil {
+		return err
+	} else if !e {
+		return nil
+	}
+
+	if err = os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove unix socket '%s': %v", path, err)
+	}
+
+	return nil
+}
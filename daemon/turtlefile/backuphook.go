@@ -0,0 +1,87 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+package turtlefile
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	BackupHookPause = "pause"
+	BackupHookExec  = "exec"
+
+	BackupHookOnFailureAbort    = "abort"
+	BackupHookOnFailureContinue = "continue"
+
+	defaultBackupHookTimeout = time.Minute
+)
+
+//#######################//
+//### BackupHook type ###//
+//#######################//
+
+// BackupHook prepares a running container for the backup snapshot.
+// The pre hook runs before and the post hook after the app subvolume snapshot.
+type BackupHook struct {
+	Type string // pause or exec.
+
+	// exec
+	Pre  []string // Executed in the container before the snapshot.
+	Post []string // Executed in the container after the snapshot. Also executed if the snapshot failed.
+
+	// Optional
+	Timeout   Duration // Timeout of each command. Default: 1m
+	OnFailure string   // abort or continue. Abort skips the backup if the pre hook fails. Default: abort
+}
+
+// IsValid checks if required values are missing or invalid.
+func (h *BackupHook) IsValid() error {
+	switch h.Type {
+	case BackupHookPause:
+		if len(h.Pre) > 0 || len(h.Post) > 0 {
+			return fmt.Errorf("pause backup hook: Pre and Post commands are not supported!")
+		}
+	case BackupHookExec:
+		if len(h.Pre) == 0 && len(h.Post) == 0 {
+			return fmt.Errorf("exec backup hook: Pre and Post commands are empty!")
+		}
+	default:
+		return fmt.Errorf("invalid backup hook type '%s'!", h.Type)
+	}
+
+	if h.Timeout.Duration < 0 {
+		return fmt.Errorf("backup hook: Timeout must not be negative!")
+	} else if len(h.OnFailure) > 0 &&
+		h.OnFailure != BackupHookOnFailureAbort &&
+		h.OnFailure != BackupHookOnFailureContinue {
+		return fmt.Errorf("backup hook: invalid OnFailure policy '%s'!", h.OnFailure)
+	}
+
+	return nil
+}
+
+// prepare sets the default values.
+func (h *BackupHook) prepare() {
+	if h.Timeout.Duration == 0 {
+		h.Timeout.Duration = defaultBackupHookTimeout
+	}
+	if len(h.OnFailure) == 0 {
+		h.OnFailure = BackupHookOnFailureAbort
+	}
+}
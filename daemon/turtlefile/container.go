@@ -58,6 +58,30 @@ func (cc Containers) IsValid() error {
 				return fmt.Errorf("Container '%s': volume '%s' contains invalid character '..'!", c.Name, v)
 			}
 		}
+
+		for _, n := range c.Networks {
+			if !isValidNetworkName(n) {
+				return fmt.Errorf("Container '%s': shared network name '%s' is invalid!", c.Name, n)
+			} else if len(c.NetworkMode) > 0 && c.NetworkMode != DefaultNetworkMode {
+				return fmt.Errorf("Container '%s': shared networks require the '%s' network mode!", c.Name, DefaultNetworkMode)
+			}
+		}
+
+		if err := c.Resources.IsValid(); err != nil {
+			return fmt.Errorf("Container '%s': %v", c.Name, err)
+		}
+
+		for _, h := range c.HealthChecks {
+			if err := h.IsValid(); err != nil {
+				return fmt.Errorf("Container '%s': %v", c.Name, err)
+			}
+		}
+
+		for _, h := range c.BackupHooks {
+			if err := h.IsValid(); err != nil {
+				return fmt.Errorf("Container '%s': %v", c.Name, err)
+			}
+		}
 	}
 
 	return nil
@@ -72,6 +96,16 @@ func (cc Containers) Prepare() error {
 		if len(c.NetworkMode) == 0 {
 			c.NetworkMode = DefaultNetworkMode
 		}
+
+		// Set the health check default values.
+		for _, h := range c.HealthChecks {
+			h.prepare()
+		}
+
+		// Set the backup hook default values.
+		for _, h := range c.BackupHooks {
+			h.prepare()
+		}
 	}
 
 	return nil
@@ -134,6 +168,36 @@ func (cc Containers) Sort() error {
 	return nil
 }
 
+// Dependents returns the passed containers and all containers which link
+// to them directly or indirectly. The containers slice has to be sorted.
+// The returned containers keep the startup order.
+func (cc Containers) Dependents(names ...string) Containers {
+	affected := make(map[string]bool)
+	for _, n := range names {
+		affected[n] = true
+	}
+
+	// Linked containers are always sorted before the linking containers.
+	// Therefore a single pass is enough.
+	var dependents Containers
+	for _, c := range cc {
+		if !affected[c.Name] {
+			for _, l := range c.Links {
+				if affected[l] {
+					affected[c.Name] = true
+					break
+				}
+			}
+		}
+
+		if affected[c.Name] {
+			dependents = append(dependents, c)
+		}
+	}
+
+	return dependents
+}
+
 //######################//
 //### Container type ###//
 //######################//
@@ -145,7 +209,7 @@ type Container struct {
 	// Optional
 	Tag              string   // The image tag.
 	WaitAfterStartup int      // Wait x milliseconds after the container started. This delays the next container startup.
-	Links            []string // List of linked container names.
+	Links            []string // List of linked container names. Links are only used as a startup order hint.
 	Volumes          []string // List of volume mount points. A valid suffix for read-only mount is ':ro'.
 	StaticVolumes    []string // List of static predefined volume mount points.
 	Env              []string // A list of static predefined environment variables in the form of VAR=value.
@@ -156,7 +220,18 @@ type Container struct {
 	Hostname         string   // A string value containing the desired hostname to use for the container.
 	Domainname       string   // A string value containing the desired domain name to use for the container.
 	NetworkDisabled  bool     // Boolean value, when true disables neworking for the container
-	NetworkMode      string   `toml:"Net"` // Set the Network mode for the container. Default: bridge
+	NetworkMode      string   `toml:"Net"` // Set the Network mode for the container. Default: bridge, which attaches the container to the app network.
+	Networks         []string // List of shared networks. Containers of different apps are reachable within the same shared network.
+
+	// Optional health checks of the running container.
+	HealthChecks []*HealthCheck `toml:"HealthCheck"`
+
+	// Optional hooks which run before and after the app is backed up.
+	BackupHooks []*BackupHook `toml:"BackupHook"`
+
+	// Optional resource limits. The keys are set directly in the container section.
+	// They might be overwritten by the app settings.
+	Resources
 }
 
 // IsLocalBuild returns a boolean whenever this container image should be build from the local source.
@@ -257,3 +332,19 @@ func topSortDFS(g map[string][]string) (order, cyclic []string) {
 	}
 	return L, nil
 }
+
+// isValidNetworkName checks if the shared network name contains only
+// letters, digits, '-' and '_'.
+func isValidNetworkName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,138 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package turtlefile
+
+//####################//
+//### Changes type ###//
+//####################//
+
+// Changes describes the differences between two turtlefiles.
+type Changes struct {
+	AddedContainers   []string
+	RemovedContainers []string
+	ImageChanges      []ImageChange // Changed images of the remaining containers.
+
+	AddedEnv    []string
+	RemovedEnv  []string
+	RequiredEnv []string // Previously optional environment variables which became required.
+
+	AddedPorts   []string // In the form of CONTAINER:PORT/PROTOCOL.
+	RemovedPorts []string
+}
+
+// ImageChange describes a changed container image.
+type ImageChange struct {
+	Container string
+	OldImage  string // In the form of IMAGE:TAG.
+	NewImage  string
+}
+
+// IsEmpty returns a boolean whenever no changes are present.
+func (c *Changes) IsEmpty() bool {
+	return len(c.AddedContainers) == 0 && len(c.RemovedContainers) == 0 &&
+		len(c.ImageChanges) == 0 && len(c.AddedEnv) == 0 && len(c.RemovedEnv) == 0 &&
+		len(c.RequiredEnv) == 0 && len(c.AddedPorts) == 0 && len(c.RemovedPorts) == 0
+}
+
+//##############//
+//### Public ###//
+//##############//
+
+// Compare returns the changes from the old to the new turtlefile.
+func Compare(old, new *Turtlefile) *Changes {
+	c := &Changes{}
+
+	// Compare the containers.
+	oldContainers := make(map[string]*Container)
+	for _, oc := range old.Containers {
+		oldContainers[oc.Name] = oc
+	}
+
+	newContainers := make(map[string]bool)
+	for _, nc := range new.Containers {
+		newContainers[nc.Name] = true
+
+		oc, ok := oldContainers[nc.Name]
+		if !ok {
+			c.AddedContainers = append(c.AddedContainers, nc.Name)
+			continue
+		}
+
+		if oc.Image != nc.Image || oc.Tag != nc.Tag {
+			c.ImageChanges = append(c.ImageChanges, ImageChange{
+				Container: nc.Name,
+				OldImage:  oc.Image + ":" + oc.Tag,
+				NewImage:  nc.Image + ":" + nc.Tag,
+			})
+		}
+	}
+
+	for _, oc := range old.Containers {
+		if !newContainers[oc.Name] {
+			c.RemovedContainers = append(c.RemovedContainers, oc.Name)
+		}
+	}
+
+	// Compare the environment variables.
+	oldEnv := make(map[string]*EnvValue)
+	for _, oe := range old.Env {
+		oldEnv[oe.Name] = oe
+	}
+
+	newEnv := make(map[string]bool)
+	for _, ne := range new.Env {
+		newEnv[ne.Name] = true
+
+		oe, ok := oldEnv[ne.Name]
+		if !ok {
+			c.AddedEnv = append(c.AddedEnv, ne.Name)
+		} else if ne.Required && !oe.Required {
+			c.RequiredEnv = append(c.RequiredEnv, ne.Name)
+		}
+	}
+
+	for _, oe := range old.Env {
+		if !newEnv[oe.Name] {
+			c.RemovedEnv = append(c.RemovedEnv, oe.Name)
+		}
+	}
+
+	// Compare the ports.
+	oldPorts := make(map[string]bool)
+	for _, op := range old.Ports {
+		oldPorts[op.String()] = true
+	}
+
+	newPorts := make(map[string]bool)
+	for _, np := range new.Ports {
+		newPorts[np.String()] = true
+
+		if !oldPorts[np.String()] {
+			c.AddedPorts = append(c.AddedPorts, np.String())
+		}
+	}
+
+	for _, op := range old.Ports {
+		if !newPorts[op.String()] {
+			c.RemovedPorts = append(c.RemovedPorts, op.String())
+		}
+	}
+
+	return c
+}
@@ -0,0 +1,121 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package turtlefile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	base := func() *Turtlefile {
+		return &Turtlefile{
+			Name: "app",
+			Env: Env{
+				{Name: "A"},
+				{Name: "B"},
+			},
+			Containers: Containers{
+				{Name: "db", Image: "postgres", Tag: "9"},
+				{Name: "web", Image: "nginx", Tag: "latest"},
+			},
+			Ports: Ports{
+				{Container: "web", Port: 80, Protocol: "tcp"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(t *Turtlefile)
+		want   Changes
+	}{
+		{
+			name:   "unchanged",
+			modify: func(t *Turtlefile) {},
+			want:   Changes{},
+		},
+		{
+			name: "added and removed containers",
+			modify: func(t *Turtlefile) {
+				t.Containers = Containers{
+					t.Containers[0],
+					{Name: "cache", Image: "redis", Tag: "latest"},
+				}
+			},
+			want: Changes{
+				AddedContainers:   []string{"cache"},
+				RemovedContainers: []string{"web"},
+			},
+		},
+		{
+			name: "changed image and tag",
+			modify: func(t *Turtlefile) {
+				t.Containers[0].Tag = "10"
+				t.Containers[1].Image = "caddy"
+			},
+			want: Changes{
+				ImageChanges: []ImageChange{
+					{Container: "db", OldImage: "postgres:9", NewImage: "postgres:10"},
+					{Container: "web", OldImage: "nginx:latest", NewImage: "caddy:latest"},
+				},
+			},
+		},
+		{
+			name: "environment variables",
+			modify: func(t *Turtlefile) {
+				t.Env = Env{
+					{Name: "A", Required: true},
+					{Name: "C"},
+				}
+			},
+			want: Changes{
+				AddedEnv:    []string{"C"},
+				RemovedEnv:  []string{"B"},
+				RequiredEnv: []string{"A"},
+			},
+		},
+		{
+			name: "ports",
+			modify: func(t *Turtlefile) {
+				t.Ports = Ports{
+					{Container: "web", Port: 80, Protocol: "udp"},
+					{Container: "web", Port: 443, Protocol: "tcp"},
+				}
+			},
+			want: Changes{
+				AddedPorts:   []string{"web:80/udp", "web:443/tcp"},
+				RemovedPorts: []string{"web:80/tcp"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		old, new := base(), base()
+		test.modify(new)
+
+		c := Compare(old, new)
+		if !reflect.DeepEqual(*c, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, *c, test.want)
+		}
+		if c.IsEmpty() != reflect.DeepEqual(test.want, Changes{}) {
+			t.Errorf("%s: IsEmpty() = %v", test.name, c.IsEmpty())
+		}
+	}
+}
@@ -0,0 +1,125 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+package turtlefile
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	HealthCheckTCP  = "tcp"
+	HealthCheckHTTP = "http"
+	HealthCheckExec = "exec"
+
+	defaultHealthCheckInterval = 30 * time.Second
+	defaultHealthCheckTimeout  = 5 * time.Second
+	defaultHealthCheckRetries  = 3
+	defaultHealthCheckPath     = "/"
+)
+
+//#####################//
+//### Duration type ###//
+//#####################//
+
+// Duration is a time duration which is decoded from a string like 30s or 5m.
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (d *Duration) UnmarshalText(text []byte) (err error) {
+	d.Duration, err = time.ParseDuration(string(text))
+	return err
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.Duration.String()), nil
+}
+
+//########################//
+//### HealthCheck type ###//
+//########################//
+
+// HealthCheck probes a running container.
+// The container is restarted if the probe fails multiple times.
+type HealthCheck struct {
+	Type string // tcp, http or exec.
+
+	// tcp and http
+	Port int // The container port.
+
+	// http
+	Path         string // The request path. Default: /
+	HTTPS        bool   // Use https instead of http.
+	Insecure     bool   // Skip the TLS certificate verification.
+	ExpectStatus int    // The expected status code. Default: any 2xx or 3xx status.
+	ExpectBody   string // The response body has to contain this string.
+
+	// exec
+	Command []string // Executed in the container. A zero exit code is healthy.
+
+	// Optional
+	Interval    Duration // Probe interval. Default: 30s
+	Timeout     Duration // Probe timeout. Default: 5s
+	StartPeriod Duration // Wait before the first probe after the container started.
+	Retries     int      // Consecutive failures until the container is unhealthy. Default: 3
+}
+
+// IsValid checks if required values are missing or invalid.
+func (h *HealthCheck) IsValid() error {
+	switch h.Type {
+	case HealthCheckTCP, HealthCheckHTTP:
+		if h.Port <= 0 || h.Port > maxPort {
+			return fmt.Errorf("%s health check: invalid port: %v", h.Type, h.Port)
+		}
+	case HealthCheckExec:
+		if len(h.Command) == 0 {
+			return fmt.Errorf("exec health check: command is empty!")
+		}
+	default:
+		return fmt.Errorf("invalid health check type '%s'!", h.Type)
+	}
+
+	if h.ExpectStatus != 0 && (h.ExpectStatus < 100 || h.ExpectStatus > 599) {
+		return fmt.Errorf("health check: invalid ExpectStatus: %v", h.ExpectStatus)
+	} else if h.Interval.Duration < 0 || h.Timeout.Duration < 0 || h.StartPeriod.Duration < 0 {
+		return fmt.Errorf("health check: durations must not be negative!")
+	} else if h.Retries < 0 {
+		return fmt.Errorf("health check: Retries must not be negative!")
+	}
+
+	return nil
+}
+
+// prepare sets the default values.
+func (h *HealthCheck) prepare() {
+	if h.Interval.Duration == 0 {
+		h.Interval.Duration = defaultHealthCheckInterval
+	}
+	if h.Timeout.Duration == 0 {
+		h.Timeout.Duration = defaultHealthCheckTimeout
+	}
+	if h.Retries == 0 {
+		h.Retries = defaultHealthCheckRetries
+	}
+	if len(h.Path) == 0 {
+		h.Path = defaultHealthCheckPath
+	}
+}
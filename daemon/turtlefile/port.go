@@ -71,3 +71,8 @@ type Port struct {
 	Protocol    string // tcp or udp. Default is tcp.
 	Description string
 }
+
+// String returns the port in the form of CONTAINER:PORT/PROTOCOL.
+func (p *Port) String() string {
+	return fmt.Sprintf("%s:%v/%s", p.Container, p.Port, p.Protocol)
+}
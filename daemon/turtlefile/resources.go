@@ -0,0 +1,194 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+package turtlefile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	minMemory      = 4 * 1024 * 1024 // Docker requires at least 4 MB.
+	minCPUQuota    = 1000
+	minCPUPeriod   = 1000
+	maxCPUPeriod   = 1000000
+	minBlkioWeight = 10
+	maxBlkioWeight = 1000
+
+	unlimitedMemorySwap = "-1"
+)
+
+//######################//
+//### Resources type ###//
+//######################//
+
+// Resources defines the resource limits of a container.
+// Empty values don't limit the resource.
+type Resources struct {
+	Memory      string // Memory limit with an optional unit suffix (b, k, m or g), e.g. 512m.
+	MemorySwap  string // Total limit of memory and swap. Requires Memory. -1 enables unlimited swap.
+	CPUShares   int64  // Relative CPU weight compared to other containers.
+	CPUSet      string // CPUs in which the execution is allowed, e.g. 0-3 or 0,1.
+	CPUQuota    int64  // CPU time in microseconds per CPUPeriod.
+	CPUPeriod   int64  // CPU CFS period in microseconds.
+	PidsLimit   int64  // Maximum number of processes.
+	BlkioWeight int64  // Relative block IO weight between 10 and 1000.
+}
+
+// IsValid checks if the resource limits are invalid.
+func (r *Resources) IsValid() error {
+	memory, err := r.MemoryBytes()
+	if err != nil {
+		return err
+	} else if memory != 0 && memory < minMemory {
+		return fmt.Errorf("Memory '%s' has to be at least 4m!", r.Memory)
+	}
+
+	memorySwap, err := r.MemorySwapBytes()
+	if err != nil {
+		return err
+	} else if memorySwap != 0 && memory == 0 {
+		return fmt.Errorf("MemorySwap requires Memory!")
+	} else if memorySwap > 0 && memorySwap < memory {
+		return fmt.Errorf("MemorySwap '%s' has to be greater than Memory '%s'!", r.MemorySwap, r.Memory)
+	}
+
+	if r.CPUShares < 0 {
+		return fmt.Errorf("CPUShares '%v' must not be negative!", r.CPUShares)
+	} else if r.CPUQuota != 0 && r.CPUQuota < minCPUQuota {
+		return fmt.Errorf("CPUQuota '%v' has to be at least %v!", r.CPUQuota, minCPUQuota)
+	} else if r.CPUPeriod != 0 && (r.CPUPeriod < minCPUPeriod || r.CPUPeriod > maxCPUPeriod) {
+		return fmt.Errorf("CPUPeriod '%v' has to be between %v and %v!", r.CPUPeriod, minCPUPeriod, maxCPUPeriod)
+	} else if r.PidsLimit < 0 {
+		return fmt.Errorf("PidsLimit '%v' must not be negative!", r.PidsLimit)
+	} else if r.BlkioWeight != 0 && (r.BlkioWeight < minBlkioWeight || r.BlkioWeight > maxBlkioWeight) {
+		return fmt.Errorf("BlkioWeight '%v' has to be between %v and %v!", r.BlkioWeight, minBlkioWeight, maxBlkioWeight)
+	}
+
+	// The CPU set is a list of CPU numbers and ranges.
+	for _, s := range strings.Split(r.CPUSet, ",") {
+		if len(r.CPUSet) == 0 {
+			break
+		}
+
+		for _, n := range strings.SplitN(s, "-", 2) {
+			if _, err := strconv.ParseUint(n, 10, 16); err != nil {
+				return fmt.Errorf("CPUSet '%s' is invalid!", r.CPUSet)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Merge returns the resource limits overwritten by all set values of o.
+func (r Resources) Merge(o *Resources) Resources {
+	if o == nil {
+		return r
+	}
+
+	if len(o.Memory) > 0 {
+		r.Memory = o.Memory
+	}
+	if len(o.MemorySwap) > 0 {
+		r.MemorySwap = o.MemorySwap
+	}
+	if o.CPUShares != 0 {
+		r.CPUShares = o.CPUShares
+	}
+	if len(o.CPUSet) > 0 {
+		r.CPUSet = o.CPUSet
+	}
+	if o.CPUQuota != 0 {
+		r.CPUQuota = o.CPUQuota
+	}
+	if o.CPUPeriod != 0 {
+		r.CPUPeriod = o.CPUPeriod
+	}
+	if o.PidsLimit != 0 {
+		r.PidsLimit = o.PidsLimit
+	}
+	if o.BlkioWeight != 0 {
+		r.BlkioWeight = o.BlkioWeight
+	}
+
+	return r
+}
+
+// MemoryBytes returns the memory limit in bytes. 0 if not limited.
+func (r *Resources) MemoryBytes() (int64, error) {
+	b, err := ParseMemory(r.Memory)
+	if err != nil {
+		return 0, fmt.Errorf("Memory: %v", err)
+	}
+
+	return b, nil
+}
+
+// MemorySwapBytes returns the memory and swap limit in bytes.
+// 0 if not limited and -1 for unlimited swap.
+func (r *Resources) MemorySwapBytes() (int64, error) {
+	if r.MemorySwap == unlimitedMemorySwap {
+		return -1, nil
+	}
+
+	b, err := ParseMemory(r.MemorySwap)
+	if err != nil {
+		return 0, fmt.Errorf("MemorySwap: %v", err)
+	}
+
+	return b, nil
+}
+
+//##############//
+//### Public ###//
+//##############//
+
+// ParseMemory parses a memory value with an optional unit suffix (b, k, m or g).
+// An empty value returns 0.
+func ParseMemory(s string) (int64, error) {
+	value := s
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) == 0 {
+		return 0, nil
+	}
+
+	// Obtain the unit multiplier.
+	var multiplier int64 = 1
+	switch s[len(s)-1] {
+	case 'b':
+		s = s[:len(s)-1]
+	case 'k':
+		multiplier = 1024
+		s = s[:len(s)-1]
+	case 'm':
+		multiplier = 1024 * 1024
+		s = s[:len(s)-1]
+	case 'g':
+		multiplier = 1024 * 1024 * 1024
+		s = s[:len(s)-1]
+	}
+
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid memory value '%s'!", value)
+	}
+
+	return v * multiplier, nil
+}
@@ -0,0 +1,136 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+package turtlefile
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+const (
+	RestartNever     = "never"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+
+	// UnlimitedRestarts disables the restart budget.
+	UnlimitedRestarts = -1
+
+	defaultRestartPolicy     = RestartOnFailure
+	defaultRestartMaxRetries = 5
+	defaultRestartWindow     = 10 * time.Minute
+	defaultRestartBackoff    = time.Second
+	defaultRestartMaxBackoff = 5 * time.Minute
+)
+
+//##########################//
+//### RestartPolicy type ###//
+//##########################//
+
+// RestartPolicy defines when and how fast stopped app containers are restarted.
+// Empty values use the default values.
+type RestartPolicy struct {
+	Policy     string   // never, on-failure or always. Default: on-failure
+	MaxRetries int      // Maximum restarts within the window. -1 for unlimited restarts. Default: 5
+	Window     Duration // The time window of the restart budget. Default: 10m
+	Backoff    Duration // The delay before the first restart. It is doubled for each further restart. Default: 1s
+	MaxBackoff Duration // The maximum delay between restarts. Default: 5m
+}
+
+// IsValid checks if the restart policy values are invalid.
+func (r *RestartPolicy) IsValid() error {
+	switch r.Policy {
+	case "", RestartNever, RestartOnFailure, RestartAlways:
+	default:
+		return fmt.Errorf("invalid restart policy '%s'!", r.Policy)
+	}
+
+	if r.MaxRetries < UnlimitedRestarts {
+		return fmt.Errorf("restart policy: invalid MaxRetries: %v", r.MaxRetries)
+	} else if r.Window.Duration < 0 || r.Backoff.Duration < 0 || r.MaxBackoff.Duration < 0 {
+		return fmt.Errorf("restart policy: durations must not be negative!")
+	}
+
+	return nil
+}
+
+// Merge returns the restart policy overwritten by all set values of o.
+func (r RestartPolicy) Merge(o *RestartPolicy) RestartPolicy {
+	if o == nil {
+		return r
+	}
+
+	if len(o.Policy) > 0 {
+		r.Policy = o.Policy
+	}
+	if o.MaxRetries != 0 {
+		r.MaxRetries = o.MaxRetries
+	}
+	if o.Window.Duration != 0 {
+		r.Window = o.Window
+	}
+	if o.Backoff.Duration != 0 {
+		r.Backoff = o.Backoff
+	}
+	if o.MaxBackoff.Duration != 0 {
+		r.MaxBackoff = o.MaxBackoff
+	}
+
+	return r
+}
+
+// WithDefaults returns the restart policy with the default values set for all empty values.
+func (r RestartPolicy) WithDefaults() RestartPolicy {
+	if len(r.Policy) == 0 {
+		r.Policy = defaultRestartPolicy
+	}
+	if r.MaxRetries == 0 {
+		r.MaxRetries = defaultRestartMaxRetries
+	}
+	if r.Window.Duration == 0 {
+		r.Window.Duration = defaultRestartWindow
+	}
+	if r.Backoff.Duration == 0 {
+		r.Backoff.Duration = defaultRestartBackoff
+	}
+	if r.MaxBackoff.Duration == 0 {
+		r.MaxBackoff.Duration = defaultRestartMaxBackoff
+	}
+
+	return r
+}
+
+// Delay returns the exponential backoff delay of the restart attempt with a random jitter.
+// The first attempt is 1. The delay is between the half and the full backoff value.
+func (r *RestartPolicy) Delay(attempt int) time.Duration {
+	d := r.Backoff.Duration
+	for i := 1; i < attempt && d < r.MaxBackoff.Duration; i++ {
+		d *= 2
+	}
+	if d > r.MaxBackoff.Duration {
+		d = r.MaxBackoff.Duration
+	}
+
+	// Add the jitter to spread the restarts.
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -0,0 +1,152 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package turtlefile
+
+import (
+	"testing"
+	"time"
+)
+
+func newRestartPolicy(backoff, maxBackoff time.Duration) *RestartPolicy {
+	return &RestartPolicy{
+		Backoff:    Duration{backoff},
+		MaxBackoff: Duration{maxBackoff},
+	}
+}
+
+func TestRestartPolicyDelay(t *testing.T) {
+	r := newRestartPolicy(time.Second, time.Minute)
+
+	// The backoff is doubled for each attempt and capped by the maximum backoff.
+	// The delay is between the half and the full backoff.
+	tests := map[int]time.Duration{
+		1:   time.Second,
+		2:   2 * time.Second,
+		3:   4 * time.Second,
+		6:   32 * time.Second,
+		7:   time.Minute,
+		100: time.Minute,
+	}
+
+	for attempt, max := range tests {
+		for i := 0; i < 100; i++ {
+			d := r.Delay(attempt)
+			if d < max/2 || d > max {
+				t.Fatalf("attempt %v: delay %v is not within [%v, %v]", attempt, d, max/2, max)
+			}
+		}
+	}
+}
+
+func TestRestartPolicyDelayJitter(t *testing.T) {
+	r := newRestartPolicy(time.Minute, time.Hour)
+
+	// The jitter spreads the delays.
+	delays := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		delays[r.Delay(1)] = true
+	}
+
+	if len(delays) < 2 {
+		t.Errorf("expected random delays, got %v", delays)
+	}
+}
+
+func TestRestartPolicyDelayZero(t *testing.T) {
+	r := newRestartPolicy(0, 0)
+
+	if d := r.Delay(3); d != 0 {
+		t.Errorf("got delay %v, want 0", d)
+	}
+}
+
+func TestRestartPolicyDefaults(t *testing.T) {
+	r := RestartPolicy{}.WithDefaults()
+
+	want := RestartPolicy{
+		Policy:     defaultRestartPolicy,
+		MaxRetries: defaultRestartMaxRetries,
+		Window:     Duration{defaultRestartWindow},
+		Backoff:    Duration{defaultRestartBackoff},
+		MaxBackoff: Duration{defaultRestartMaxBackoff},
+	}
+	if r != want {
+		t.Errorf("got %+v, want %+v", r, want)
+	}
+
+	// Set values are kept.
+	r = RestartPolicy{Policy: RestartAlways, MaxRetries: UnlimitedRestarts}.WithDefaults()
+	if r.Policy != RestartAlways || r.MaxRetries != UnlimitedRestarts {
+		t.Errorf("set values were overwritten: %+v", r)
+	}
+}
+
+func TestRestartPolicyMerge(t *testing.T) {
+	r := RestartPolicy{
+		Policy:     RestartAlways,
+		MaxRetries: 3,
+		Backoff:    Duration{time.Second},
+	}
+
+	merged := r.Merge(&RestartPolicy{
+		MaxRetries: 10,
+		MaxBackoff: Duration{time.Minute},
+	})
+
+	want := RestartPolicy{
+		Policy:     RestartAlways,
+		MaxRetries: 10,
+		Backoff:    Duration{time.Second},
+		MaxBackoff: Duration{time.Minute},
+	}
+	if merged != want {
+		t.Errorf("got %+v, want %+v", merged, want)
+	}
+
+	if merged = r.Merge(nil); merged != r {
+		t.Errorf("merge with nil: got %+v, want %+v", merged, r)
+	}
+}
+
+func TestRestartPolicyIsValid(t *testing.T) {
+	valid := []RestartPolicy{
+		{},
+		{Policy: RestartNever},
+		{Policy: RestartOnFailure, MaxRetries: 1},
+		{Policy: RestartAlways, MaxRetries: UnlimitedRestarts},
+	}
+	for _, r := range valid {
+		if err := r.IsValid(); err != nil {
+			t.Errorf("%+v: %v", r, err)
+		}
+	}
+
+	invalid := []RestartPolicy{
+		{Policy: "sometimes"},
+		{MaxRetries: -2},
+		{Window: Duration{-time.Second}},
+		{Backoff: Duration{-time.Second}},
+		{MaxBackoff: Duration{-time.Second}},
+	}
+	for _, r := range invalid {
+		if err := r.IsValid(); err == nil {
+			t.Errorf("%+v: expected an error", r)
+		}
+	}
+}
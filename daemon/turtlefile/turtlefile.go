@@ -36,9 +36,10 @@ type Turtlefile struct {
 	Name       string
 	Maintainer string
 
-	Env        Env
-	Containers Containers `toml:"Container"`
-	Ports      Ports      `toml:"Port"`
+	Env           Env
+	Containers    Containers `toml:"Container"`
+	Ports         Ports      `toml:"Port"`
+	RestartPolicy RestartPolicy
 }
 
 // IsValid checks if required values are missing or invalid.
@@ -65,6 +66,12 @@ func (t *Turtlefile) IsValid() error {
 		return err
 	}
 
+	// Check if the restart policy is valid.
+	err = t.RestartPolicy.IsValid()
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -81,15 +88,39 @@ func Load(turtlefilePath string) (*Turtlefile, error) {
 		return nil, fmt.Errorf("failed to load turtlefile '%s': %v", turtlefilePath, err)
 	}
 
-	// Sort the containers by their startup level.
-	if err = t.Containers.Sort(); err != nil {
+	if err = t.prepare(); err != nil {
 		return nil, err
 	}
 
-	// Prepare the containers.
-	if err = t.Containers.Prepare(); err != nil {
+	return &t, nil
+}
+
+// Parse the turtlefile content and return a Turtlefile value.
+func Parse(data string) (*Turtlefile, error) {
+	var t Turtlefile
+	_, err := toml.Decode(data, &t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse turtlefile: %v", err)
+	}
+
+	if err = t.prepare(); err != nil {
 		return nil, err
 	}
 
 	return &t, nil
 }
+
+//###############//
+//### Private ###//
+//###############//
+
+// prepare sorts and prepares the loaded containers.
+func (t *Turtlefile) prepare() error {
+	// Sort the containers by their startup level.
+	if err := t.Containers.Sort(); err != nil {
+		return err
+	}
+
+	// Prepare the containers.
+	return t.Containers.Prepare()
+}
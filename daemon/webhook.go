@@ -0,0 +1,151 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/desertbit/turtle/daemon/apps"
+	"github.com/desertbit/turtle/utils"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	webhookPath        = "/webhook/"
+	maxWebhookBodySize = 5 * 1024 * 1024
+)
+
+func init() {
+	// Set the webhook HTTP handler.
+	http.HandleFunc(webhookPath, handleWebhook)
+}
+
+// webhookPayload contains the relevant fields of GitHub, Gitea and GitLab push events.
+type webhookPayload struct {
+	Ref string // The pushed reference, e.g. refs/heads/master.
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// handleWebhook triggers an automatic update of the app named in the URL path.
+// Requests are authenticated with the app webhook secret.
+func handleWebhook(rw http.ResponseWriter, req *http.Request) {
+	// Lock the mutex in read mode.
+	requestRWLock.RLock()
+	defer requestRWLock.RUnlock()
+
+	// Get the remote address from the client.
+	remoteAddr, _ := utils.RemoteAddress(req)
+
+	handleError := func(code int, err error) {
+		log.Warningf("Webhook request from client '%s': %v", remoteAddr, err)
+		http.Error(rw, err.Error(), code)
+	}
+
+	if req.Method != "POST" {
+		handleError(http.StatusMethodNotAllowed, fmt.Errorf("invalid method '%s'", req.Method))
+		return
+	}
+
+	// Obtain the app with the given name.
+	// Don't reveal whenever the app exists.
+	name := strings.TrimPrefix(req.URL.Path, webhookPath)
+	a, err := apps.Get(name)
+	if err != nil || len(a.WebhookSecret()) == 0 {
+		handleError(http.StatusNotFound, fmt.Errorf("no webhook for app '%s'", name))
+		return
+	}
+
+	// Read the request body.
+	body, err := ioutil.ReadAll(http.MaxBytesReader(rw, req.Body, maxWebhookBodySize))
+	if err != nil {
+		handleError(http.StatusBadRequest, fmt.Errorf("failed to read body: %v", err))
+		return
+	}
+
+	// Verify the signature.
+	if !verifyWebhook(req, body, a.WebhookSecret()) {
+		handleError(http.StatusUnauthorized, fmt.Errorf("app '%s': invalid webhook signature", name))
+		return
+	}
+
+	// Respond to ping events.
+	if req.Header.Get("X-GitHub-Event") == "ping" {
+		rw.Write([]byte("pong"))
+		return
+	}
+
+	// Parse the payload.
+	var payload webhookPayload
+	if err = json.Unmarshal(body, &payload); err != nil {
+		handleError(http.StatusBadRequest, fmt.Errorf("app '%s': invalid webhook payload: %v", name, err))
+		return
+	}
+
+	// Ignore pushes to other branches. Pinned apps check their revision on every push.
+	if len(a.Pin()) == 0 && payload.Ref != "refs/heads/"+a.Branch() {
+		rw.Write([]byte("ignored: push to another branch"))
+		return
+	}
+
+	// Trigger the update.
+	log.Infof("Webhook request from client '%s': triggering automatic update of app '%s'", remoteAddr, name)
+	if err = a.TriggerAutoUpdate(); err != nil {
+		handleError(http.StatusConflict, fmt.Errorf("app '%s': %v", name, err))
+		return
+	}
+
+	rw.WriteHeader(http.StatusAccepted)
+	rw.Write([]byte("update triggered"))
+}
+
+// verifyWebhook checks the GitHub or Gitea HMAC signature or the GitLab token of the request.
+func verifyWebhook(req *http.Request, body []byte, secret string) bool {
+	// GitLab sends the plain secret token.
+	if token := req.Header.Get("X-Gitlab-Token"); len(token) > 0 {
+		return subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+	}
+
+	// GitHub prefixes the hex signature with the algorithm.
+	signature := strings.TrimPrefix(req.Header.Get("X-Hub-Signature-256"), "sha256=")
+	if len(signature) == 0 {
+		signature = req.Header.Get("X-Gitea-Signature")
+	}
+
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) == 0 {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hmac.Equal(sig, mac.Sum(nil))
+}
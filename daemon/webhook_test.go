@@ -0,0 +1,68 @@
+/*
+ *  Turtle - Rock Solid Cluster Management
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"testing"
+)
+
+const testWebhookSecret = "secret"
+
+// sign returns the hex HMAC signature of the body.
+func sign(body, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	body := `{"ref":"refs/heads/master"}`
+
+	tests := []struct {
+		name    string
+		body    string
+		headers map[string]string
+		valid   bool
+	}{
+		{"github", body, map[string]string{"X-Hub-Signature-256": "sha256=" + sign(body, testWebhookSecret)}, true},
+		{"gitea", body, map[string]string{"X-Gitea-Signature": sign(body, testWebhookSecret)}, true},
+		{"gitlab", body, map[string]string{"X-Gitlab-Token": testWebhookSecret}, true},
+		{"missing signature", body, nil, false},
+		{"wrong secret", body, map[string]string{"X-Hub-Signature-256": "sha256=" + sign(body, "other")}, false},
+		{"modified body", body + " ", map[string]string{"X-Hub-Signature-256": "sha256=" + sign(body, testWebhookSecret)}, false},
+		{"invalid hex", body, map[string]string{"X-Gitea-Signature": "xyz"}, false},
+		{"empty signature", body, map[string]string{"X-Hub-Signature-256": "sha256="}, false},
+		{"wrong gitlab token", body, map[string]string{"X-Gitlab-Token": "other"}, false},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", webhookPath+"app", nil)
+		for key, value := range test.headers {
+			req.Header.Set(key, value)
+		}
+
+		if valid := verifyWebhook(req, []byte(test.body), testWebhookSecret); valid != test.valid {
+			t.Errorf("%s: got valid %v, want %v", test.name, valid, test.valid)
+		}
+	}
+}
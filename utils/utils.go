@@ -49,3 +49,29 @@ func RunCommandInPath(dir, name string, args ...string) error {
 
 	return nil
 }
+
+// RunCommandOutput runs a command and returns its standard output.
+// The stderr error message is returned on error.
+func RunCommandOutput(name string, args ...string) (string, error) {
+	return RunCommandOutputInPath("", name, args...)
+}
+
+// RunCommandOutputInPath runs a command in the working directory
+// and returns its standard output.
+// The stderr error message is returned on error.
+func RunCommandOutputInPath(dir, name string, args ...string) (string, error) {
+	// Create the command.
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Dir = dir
+
+	// Start the command and wait for it to exit.
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf(strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}